@@ -0,0 +1,108 @@
+package rdap
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+var (
+	// ErrNoAbuseContact is used when the RDAP object and its parents don't
+	// have any entity with the abuse role
+	ErrNoAbuseContact = errors.New("no abuse contact found")
+)
+
+// AbuseContact stores the contact information of the entity responsible for
+// handling abuse reports of a resource
+type AbuseContact struct {
+	Handle string
+	Name   string
+	Emails []string
+	Phones []string
+
+	// Entity is the complete entity with the abuse role
+	Entity protocol.Entity
+
+	// Object is the RDAP object where the abuse entity was found. It could
+	// be a parent network of the queried resource
+	Object interface{}
+}
+
+// AbuseContact will query the RDAP servers for the object (ASN, IP, IP
+// network, domain or entity, as detected by Query) and look for the entity
+// with the abuse role, searching the nested entities recursively. For IP
+// networks without an abuse contact the parent networks are also analyzed.
+// If no abuse contact is found the error ErrNoAbuseContact is returned
+func (c *Client) AbuseContact(object string, header http.Header, queryString url.Values) (*AbuseContact, error) {
+	result, _, err := c.Query(object, header, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	if contact, found := findAbuseContact(result); found {
+		return contact, nil
+	}
+
+	network, ok := result.(*protocol.IPNetwork)
+	if !ok {
+		return nil, ErrNoAbuseContact
+	}
+
+	visited := map[string]bool{ipNetworkKey(network): true}
+
+	for depth := 0; depth < DefaultHierarchyDepth; depth++ {
+		network, err = c.ipNetworkParent(network, header, queryString)
+		if err != nil {
+			return nil, err
+		}
+
+		if network == nil || visited[ipNetworkKey(network)] {
+			break
+		}
+		visited[ipNetworkKey(network)] = true
+
+		if contact, found := findAbuseContact(network); found {
+			return contact, nil
+		}
+	}
+
+	return nil, ErrNoAbuseContact
+}
+
+// findAbuseContact looks for the abuse entity in the object itself (when
+// the object is an entity) and in the nested entities
+func findAbuseContact(object interface{}) (*AbuseContact, bool) {
+	entities := protocol.ObjectEntities(object)
+	if entity, ok := object.(*protocol.Entity); ok {
+		entities = []protocol.Entity{*entity}
+	}
+
+	entity, found := protocol.FindEntity(entities, protocol.RoleAbuse)
+	if !found {
+		return nil, false
+	}
+
+	contact := AbuseContact{
+		Handle: entity.Handle,
+		Entity: entity,
+		Object: object,
+	}
+
+	// a malformed vCard shouldn't hide the abuse entity, the handle is still
+	// useful to find the contact
+	if vcard, err := entity.VCard(); err == nil && vcard != nil {
+		contact.Name = vcard.FN()
+
+		for _, email := range vcard.Emails() {
+			contact.Emails = append(contact.Emails, email.Address)
+		}
+
+		for _, tel := range vcard.Tels() {
+			contact.Phones = append(contact.Phones, tel.Number)
+		}
+	}
+
+	return &contact, true
+}
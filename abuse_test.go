@@ -0,0 +1,159 @@
+package rdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestClientAbuseContact(t *testing.T) {
+	abuse := protocol.Entity{
+		ObjectClassName: "entity",
+		Handle:          "ABUSE-1",
+		Roles:           []string{"abuse"},
+		VCardArray: []interface{}{
+			"vcard",
+			[]interface{}{
+				[]interface{}{"version", map[string]interface{}{}, "text", "4.0"},
+				[]interface{}{"fn", map[string]interface{}{}, "text", "Abuse Team"},
+				[]interface{}{"email", map[string]interface{}{}, "text", "abuse@example.com"},
+				[]interface{}{"tel", map[string]interface{}{"type": "voice"}, "uri", "tel:+55.1155093500"},
+			},
+		},
+	}
+
+	registrar := protocol.Entity{
+		ObjectClassName: "entity",
+		Handle:          "REGISTRAR-1",
+		Roles:           []string{"registrar"},
+		Entities:        []protocol.Entity{abuse},
+	}
+
+	domain := &protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.com",
+		Entities: []protocol.Entity{
+			{ObjectClassName: "entity", Handle: "OWNER-1", Roles: []string{"registrant"}},
+			registrar,
+		},
+	}
+
+	assignment := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-192-0-2-0-24",
+		StartAddress:    "192.0.2.0",
+		EndAddress:      "192.0.2.255",
+		Links: []protocol.Link{
+			{Rel: "up", Href: "https://rdap.rir.example/ip/192.0.2.0/23"},
+		},
+	}
+
+	allocation := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-192-0-2-0",
+		StartAddress:    "192.0.2.0",
+		EndAddress:      "192.0.3.255",
+		Entities:        []protocol.Entity{abuse},
+	}
+
+	as := &protocol.AS{
+		ObjectClassName: "autnum",
+		Handle:          "AS64496",
+	}
+
+	objects := map[string]interface{}{
+		"domain/example.com": domain,
+		"ip/192.0.2.1":       assignment,
+		"ip/192.0.2.0/23":    allocation,
+		"autnum/64496":       as,
+		"entity/REGISTRAR-1": &registrar,
+	}
+
+	fetcher := fetcherFunc(func(uris []string, queryType QueryType, queryValue string, header http.Header, queryString url.Values) (*http.Response, error) {
+		object := objects[fmt.Sprintf("%s/%s", queryType, queryValue)]
+		if object == nil {
+			return nil, ErrNotFound
+		}
+
+		data, err := json.Marshal(object)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var response http.Response
+		response.Body = nopCloser{bytes.NewBuffer(data)}
+		return &response, nil
+	})
+
+	expectedContact := func(object interface{}) *AbuseContact {
+		return &AbuseContact{
+			Handle: "ABUSE-1",
+			Name:   "Abuse Team",
+			Emails: []string{"abuse@example.com"},
+			Phones: []string{"+55.1155093500"},
+			Entity: abuse,
+			Object: object,
+		}
+	}
+
+	data := []struct {
+		description   string
+		object        string
+		expected      *AbuseContact
+		expectedError error
+	}{
+		{
+			description: "it should find the abuse contact in the nested entities of a domain",
+			object:      "example.com",
+			expected:    expectedContact(domain),
+		},
+		{
+			description: "it should find the abuse contact in the parent network",
+			object:      "192.0.2.1",
+			expected:    expectedContact(allocation),
+		},
+		{
+			description: "it should find the abuse contact in an entity",
+			object:      "REGISTRAR-1",
+			expected:    expectedContact(&registrar),
+		},
+		{
+			description:   "it should fail when there's no abuse contact",
+			object:        "64496",
+			expectedError: ErrNoAbuseContact,
+		},
+		{
+			description:   "it should fail when the object is not found",
+			object:        "UNKNOWN-ENTRY",
+			expectedError: ErrNotFound,
+		},
+	}
+
+	for i, item := range data {
+		client := Client{
+			URIs:          []string{"https://rdap.example"},
+			Transport:     fetcher,
+			LinkTransport: fetcher,
+		}
+
+		contact, err := client.AbuseContact(item.object, nil, nil)
+
+		if item.expectedError != nil {
+			if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+				t.Errorf("[%d] %s: expected error “%s”, got “%s”", i, item.description, item.expectedError, err)
+			}
+
+		} else if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+
+		} else if !reflect.DeepEqual(item.expected, contact) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, contact))
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package rdap
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// ErrInvalidCredentials is returned by the authenticators when the client
+// sent credentials that aren't valid. The client is answered with 401
+// Unauthorized
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator identifies the view of the client that sent the request.
+// When the request doesn't have credentials of the authenticator type, an
+// empty view and no error is returned. When the credentials are invalid
+// ErrInvalidCredentials is returned
+type Authenticator interface {
+	Authenticate(r *http.Request) (view string, err error)
+}
+
+// AuthenticatorFunc is a function type that implements the Authenticator
+// interface
+type AuthenticatorFunc func(r *http.Request) (string, error)
+
+// Authenticate calls f(r)
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (string, error) {
+	return f(r)
+}
+
+// UnauthorizedError is returned by the AccessControl filter when the
+// credentials of the client are invalid. The server answers with 401
+// Unauthorized and a WWW-Authenticate header for each challenge (RFC 7235,
+// section 3.1)
+type UnauthorizedError struct {
+	Response   protocol.Error
+	Challenges []string
+}
+
+// Error implements the error interface
+func (e UnauthorizedError) Error() string {
+	return e.Response.Error()
+}
+
+// challenger is implemented by the authenticators that use the HTTP
+// authentication framework, to inform the challenge of the authentication
+// scheme
+type challenger interface {
+	Challenge(realm string) string
+}
+
+// BasicUser is a user of the HTTP Basic authentication
+type BasicUser struct {
+	Password string
+	View     string
+}
+
+// BasicAuthenticator identifies the clients with the HTTP Basic
+// authentication (RFC 7617). The users are indexed by the username
+type BasicAuthenticator map[string]BasicUser
+
+// Authenticate implements the Authenticator interface
+func (a BasicAuthenticator) Authenticate(r *http.Request) (string, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", nil
+	}
+
+	user, ok := a[username]
+	if !ok || subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) != 1 {
+		return "", ErrInvalidCredentials
+	}
+
+	return user.View, nil
+}
+
+// Challenge returns the challenge of the HTTP Basic authentication
+func (a BasicAuthenticator) Challenge(realm string) string {
+	return fmt.Sprintf("Basic realm=%q", realm)
+}
+
+// BearerAuthenticator identifies the clients with a bearer token in the
+// Authorization header (RFC 6750). It maps the tokens to the views
+type BearerAuthenticator map[string]string
+
+// Authenticate implements the Authenticator interface
+func (a BearerAuthenticator) Authenticate(r *http.Request) (string, error) {
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+		return "", nil
+	}
+
+	view, ok := a[strings.TrimSpace(authorization[7:])]
+	if !ok {
+		return "", ErrInvalidCredentials
+	}
+
+	return view, nil
+}
+
+// Challenge returns the challenge of the bearer token authentication
+func (a BearerAuthenticator) Challenge(realm string) string {
+	return fmt.Sprintf("Bearer realm=%q", realm)
+}
+
+// ClientCertificateAuthenticator identifies the clients with a TLS client
+// certificate. It maps the certificate subject common name to the views.
+// Only certificates verified by the TLS server (see tls.Config.ClientCAs)
+// are accepted
+type ClientCertificateAuthenticator map[string]string
+
+// Authenticate implements the Authenticator interface
+func (a ClientCertificateAuthenticator) Authenticate(r *http.Request) (string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", nil
+	}
+
+	view, ok := a[r.TLS.VerifiedChains[0][0].Subject.CommonName]
+	if !ok {
+		return "", ErrInvalidCredentials
+	}
+
+	return view, nil
+}
+
+// AccessControl is a ResponseFilter that redacts the objects according to the
+// view of the client. The view is selected by the first authenticator that
+// finds credentials in the request. The redacted objects receive a remark
+// with the type protocol.RemarkTypeObjectTruncatedAuthorization. The backend
+// objects are not changed, the redaction is applied to copies
+type AccessControl struct {
+	Authenticators []Authenticator
+
+	// Public is the redaction policy of the clients without credentials
+	Public RedactionPolicy
+
+	// Views stores the redaction policy of each view. Views with an empty
+	// policy have full access to the objects, and the views that aren't
+	// listed receive the public policy
+	Views map[string]RedactionPolicy
+
+	// Realm is the protection space informed in the authentication
+	// challenges. When empty DefaultRealm is used
+	Realm string
+}
+
+// DefaultRealm is the realm of the authentication challenges when the
+// AccessControl doesn't define one
+const DefaultRealm = "rdap"
+
+// View returns the view of the client. An empty view identifies the public
+// access
+func (a AccessControl) View(r *http.Request) (string, error) {
+	for _, authenticator := range a.Authenticators {
+		view, err := authenticator.Authenticate(r)
+		if err != nil || view != "" {
+			return view, err
+		}
+	}
+
+	return "", nil
+}
+
+// FilterResponse implements the ResponseFilter interface
+func (a AccessControl) FilterResponse(r *http.Request, object interface{}) (interface{}, error) {
+	view, err := a.View(r)
+	if err == ErrInvalidCredentials {
+		return nil, UnauthorizedError{
+			Response: protocol.Error{
+				ErrorCode:   http.StatusUnauthorized,
+				Title:       "unauthorized",
+				Description: []string{"the credentials are invalid"},
+			},
+			Challenges: a.challenges(),
+		}
+
+	} else if err != nil {
+		return nil, err
+	}
+
+	// views without an entry are handled as public, so a typo in the
+	// configuration doesn't grant full access
+	policy, ok := a.Views[view]
+	if view == "" || !ok {
+		policy = a.Public
+	}
+
+	if len(policy) == 0 {
+		return object, nil
+	}
+
+	switch o := object.(type) {
+	case *protocol.DomainSearchResults:
+		results := *o
+		results.Results = make([]protocol.Domain, len(o.Results))
+		for i, domain := range o.Results {
+			results.Results[i] = *redactObject(policy, domain).(*protocol.Domain)
+		}
+		return &results, nil
+
+	case *protocol.NameserverSearchResults:
+		results := *o
+		results.Results = make([]protocol.Nameserver, len(o.Results))
+		for i, nameserver := range o.Results {
+			results.Results[i] = *redactObject(policy, nameserver).(*protocol.Nameserver)
+		}
+		return &results, nil
+
+	case *protocol.EntitySearchResults:
+		results := *o
+		results.Results = make([]protocol.Entity, len(o.Results))
+		for i, entity := range o.Results {
+			results.Results[i] = *redactObject(policy, entity).(*protocol.Entity)
+		}
+		return &results, nil
+	}
+
+	if redacted := redactObject(policy, object); redacted != nil {
+		return redacted, nil
+	}
+
+	return object, nil
+}
+
+// challenges returns the authentication challenges of the authenticators
+func (a AccessControl) challenges() []string {
+	realm := a.Realm
+	if realm == "" {
+		realm = DefaultRealm
+	}
+
+	var challenges []string
+	for _, authenticator := range a.Authenticators {
+		if c, ok := authenticator.(challenger); ok {
+			challenges = append(challenges, c.Challenge(realm))
+		}
+	}
+
+	return challenges
+}
+
+// redactObject returns a redacted copy of the object. The entities are also
+// copied, as the redaction changes them. If the object type isn't supported
+// nil is returned
+func redactObject(policy RedactionPolicy, object interface{}) interface{} {
+	remark := protocol.Remark{
+		Title:       "Authorization",
+		Type:        string(protocol.RemarkTypeObjectTruncatedAuthorization),
+		Description: []string{"Some data is only available to authorized clients."},
+	}
+
+	remarks := func(r []protocol.Remark, before, after int) []protocol.Remark {
+		if before == after {
+			return r
+		}
+		return append(append([]protocol.Remark{}, r...), remark)
+	}
+
+	switch o := object.(type) {
+	case *protocol.Domain:
+		return redactObject(policy, *o)
+	case protocol.Domain:
+		o.Entities = append([]protocol.Entity(nil), o.Entities...)
+		o.Redacted = append([]protocol.Redacted(nil), o.Redacted...)
+		before := len(o.Redacted)
+		policy.RedactDomain(&o)
+		o.Remarks = remarks(o.Remarks, before, len(o.Redacted))
+		return &o
+
+	case *protocol.Nameserver:
+		return redactObject(policy, *o)
+	case protocol.Nameserver:
+		o.Entities = append([]protocol.Entity(nil), o.Entities...)
+		o.Redacted = append([]protocol.Redacted(nil), o.Redacted...)
+		before := len(o.Redacted)
+		policy.RedactNameserver(&o)
+		o.Remarks = remarks(o.Remarks, before, len(o.Redacted))
+		return &o
+
+	case *protocol.Entity:
+		return redactObject(policy, *o)
+	case protocol.Entity:
+		o.Entities = append([]protocol.Entity(nil), o.Entities...)
+		o.Redacted = append([]protocol.Redacted(nil), o.Redacted...)
+		before := len(o.Redacted)
+		policy.RedactEntity(&o)
+		o.Remarks = remarks(o.Remarks, before, len(o.Redacted))
+		return &o
+
+	case *protocol.IPNetwork:
+		return redactObject(policy, *o)
+	case protocol.IPNetwork:
+		o.Entities = append([]protocol.Entity(nil), o.Entities...)
+		o.Redacted = append([]protocol.Redacted(nil), o.Redacted...)
+		before := len(o.Redacted)
+		policy.RedactIPNetwork(&o)
+		o.Remarks = remarks(o.Remarks, before, len(o.Redacted))
+		return &o
+
+	case *protocol.AS:
+		return redactObject(policy, *o)
+	case protocol.AS:
+		o.Entities = append([]protocol.Entity(nil), o.Entities...)
+		o.Redacted = append([]protocol.Redacted(nil), o.Redacted...)
+		before := len(o.Redacted)
+		policy.RedactAS(&o)
+		o.Remarks = remarks(o.Remarks, before, len(o.Redacted))
+		return &o
+	}
+
+	return nil
+}
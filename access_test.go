@@ -0,0 +1,197 @@
+package rdap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestAccessControlFilterResponse(t *testing.T) {
+	registrant := protocol.Entity{
+		ObjectClassName: "entity",
+		Handle:          "REG-1",
+		Roles:           []string{protocol.RoleRegistrant},
+	}
+	registrant.SetVCard(protocol.NewVCard().
+		AddFN("Joe User").
+		AddEmail(protocol.VCardEmail{Address: "joe.user@example.com"}))
+
+	domain := &protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.com",
+		Entities:        []protocol.Entity{registrant},
+	}
+
+	redactedRegistrant := registrant
+	redactedRegistrant.SetVCard(protocol.NewVCard().AddFN("Joe User"))
+
+	redactedDomain := &protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.com",
+		Entities:        []protocol.Entity{redactedRegistrant},
+		Remarks: []protocol.Remark{
+			{
+				Title:       "Authorization",
+				Type:        "object truncated due to authorization",
+				Description: []string{"Some data is only available to authorized clients."},
+			},
+		},
+		Redacted: []protocol.Redacted{
+			{
+				Name:    protocol.RedactedName{Type: "Registrant Email"},
+				PrePath: "$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='email')][3]",
+			},
+		},
+	}
+
+	emailRule := RedactionRule{
+		Name:   "Registrant Email",
+		Role:   protocol.RoleRegistrant,
+		Member: protocol.VCardPropertyEmail,
+	}
+
+	accessControl := AccessControl{
+		Authenticators: []Authenticator{
+			BasicAuthenticator{
+				"registrar": {Password: "secret", View: "registrar"},
+				"auditor":   {Password: "secret", View: "auditor"},
+			},
+			BearerAuthenticator{"token-1": "law-enforcement"},
+			ClientCertificateAuthenticator{"registrar.example.com": "registrar"},
+		},
+		Public: RedactionPolicy{emailRule},
+		Views: map[string]RedactionPolicy{
+			"registrar":       {emailRule},
+			"law-enforcement": nil,
+		},
+	}
+
+	data := []struct {
+		description   string
+		request       func(r *http.Request)
+		expected      interface{}
+		expectedError error
+	}{
+		{
+			description: "it should redact the object for the public",
+			expected:    redactedDomain,
+		},
+		{
+			description: "it should redact the object for a restricted view",
+			request: func(r *http.Request) {
+				r.SetBasicAuth("registrar", "secret")
+			},
+			expected: redactedDomain,
+		},
+		{
+			description: "it should answer the full object for a view without policy",
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer token-1")
+			},
+			expected: domain,
+		},
+		{
+			description: "it should identify the view of a client certificate",
+			request: func(r *http.Request) {
+				r.TLS = &tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{
+						{{Subject: pkix.Name{CommonName: "registrar.example.com"}}},
+					},
+				}
+			},
+			expected: redactedDomain,
+		},
+		{
+			description: "it should redact the object for a view without entry",
+			request: func(r *http.Request) {
+				r.SetBasicAuth("auditor", "secret")
+			},
+			expected: redactedDomain,
+		},
+		{
+			description: "it should detect invalid credentials",
+			request: func(r *http.Request) {
+				r.SetBasicAuth("registrar", "wrong")
+			},
+			expectedError: UnauthorizedError{
+				Response: protocol.Error{
+					ErrorCode:   http.StatusUnauthorized,
+					Title:       "unauthorized",
+					Description: []string{"the credentials are invalid"},
+				},
+				Challenges: []string{`Basic realm="rdap"`, `Bearer realm="rdap"`},
+			},
+		},
+		{
+			description: "it should detect an unknown token",
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer token-2")
+			},
+			expectedError: UnauthorizedError{
+				Response: protocol.Error{
+					ErrorCode:   http.StatusUnauthorized,
+					Title:       "unauthorized",
+					Description: []string{"the credentials are invalid"},
+				},
+				Challenges: []string{`Basic realm="rdap"`, `Bearer realm="rdap"`},
+			},
+		},
+	}
+
+	for i, item := range data {
+		r := httptest.NewRequest("GET", "/domain/example.com", nil)
+		if item.request != nil {
+			item.request(r)
+		}
+
+		object, err := accessControl.FilterResponse(r, domain)
+		if !reflect.DeepEqual(item.expectedError, err) {
+			t.Errorf("[%d] %s: expected error “%v”, got “%v”", i, item.description, item.expectedError, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(item.expected, object) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, object))
+		}
+	}
+
+	if len(domain.Redacted) > 0 || len(domain.Remarks) > 0 || !reflect.DeepEqual(registrant, domain.Entities[0]) {
+		t.Error("The backend object was changed by the redaction")
+	}
+}
+
+func TestAccessControlUnauthorized(t *testing.T) {
+	server := NewServer()
+	server.Handle(QueryTypeDomain, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		return &protocol.Domain{ObjectClassName: "domain", LDHName: queryValue}, nil
+	}))
+
+	server.Filters = append(server.Filters, AccessControl{
+		Authenticators: []Authenticator{BearerAuthenticator{"token-1": "registrar"}},
+		Realm:          "example",
+	})
+
+	r := httptest.NewRequest("GET", "/domain/example.com", nil)
+	r.Header.Set("Authorization", "Bearer token-2")
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected HTTP status “401” and got “%d”", w.Code)
+	}
+
+	if challenge := w.Header().Get("WWW-Authenticate"); challenge != `Bearer realm="example"` {
+		t.Errorf("Unexpected WWW-Authenticate header “%s”", challenge)
+	}
+}
@@ -0,0 +1,335 @@
+package rdap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+// List of the files of the RDAP Bootstrap Service Registries, as published
+// by IANA
+const (
+	// BootstrapFileDNS is the registry of domain names (RFC 9224, section 4)
+	BootstrapFileDNS = "dns.json"
+
+	// BootstrapFileASN is the registry of AS numbers (RFC 9224, section 5.3)
+	BootstrapFileASN = "asn.json"
+
+	// BootstrapFileIPv4 is the registry of IPv4 networks (RFC 9224, section
+	// 5.1)
+	BootstrapFileIPv4 = "ipv4.json"
+
+	// BootstrapFileIPv6 is the registry of IPv6 networks (RFC 9224, section
+	// 5.2)
+	BootstrapFileIPv6 = "ipv6.json"
+
+	// BootstrapFileObjectTags is the registry of entity handle tags (RFC
+	// 8521, section 3)
+	BootstrapFileObjectTags = "object-tags.json"
+)
+
+// BootstrapBuilder builds the RDAP Bootstrap Service Registries files. The
+// entries are validated when added, and an entry can only be in one service.
+// The files are compatible with the transport returned by
+// NewBootstrapFetcher
+type BootstrapBuilder struct {
+	// Description is the optional description of the registries
+	Description string
+
+	dns        []service
+	asn        []service
+	ipv4       []service
+	ipv6       []service
+	objectTags [][3][]string
+	entries    map[string]bool
+	asnRanges  [][2]uint64
+}
+
+// NewBootstrapBuilder returns a builder without services
+func NewBootstrapBuilder() *BootstrapBuilder {
+	return &BootstrapBuilder{
+		entries: make(map[string]bool),
+	}
+}
+
+// AddDNS adds a service with the domain names (e.g. "br", "com.br") answered
+// by the RDAP servers
+func (b *BootstrapBuilder) AddDNS(entries []string, uris ...string) error {
+	var normalized []string
+
+	for _, entry := range entries {
+		fqdn, err := idna.ToASCII(strings.TrimSuffix(strings.ToLower(entry), "."))
+		if err != nil || fqdn == "" || !fqdnRX.MatchString(fqdn) {
+			return fmt.Errorf("invalid DNS entry %q", entry)
+		}
+		normalized = append(normalized, fqdn)
+	}
+
+	return b.add(&b.dns, "dns", normalized, uris)
+}
+
+// AddASN adds a service with the AS numbers answered by the RDAP servers. The
+// entries can be a single AS number or a range (e.g. "64512-65534"), and they
+// can't overlap the AS numbers of other entries
+func (b *BootstrapBuilder) AddASN(entries []string, uris ...string) error {
+	ranges := append([][2]uint64{}, b.asnRanges...)
+
+	for _, entry := range entries {
+		asRange := strings.Split(entry, "-")
+		if len(asRange) > 2 {
+			return fmt.Errorf("invalid ASN entry %q", entry)
+		}
+
+		begin, err := strconv.ParseUint(asRange[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid ASN entry %q", entry)
+		}
+
+		end := begin
+		if len(asRange) == 2 {
+			end, err = strconv.ParseUint(asRange[1], 10, 32)
+			if err != nil || end < begin {
+				return fmt.Errorf("invalid ASN entry %q", entry)
+			}
+		}
+
+		for _, r := range ranges {
+			if begin <= r[1] && end >= r[0] {
+				return fmt.Errorf("overlapping asn entry %q", entry)
+			}
+		}
+
+		ranges = append(ranges, [2]uint64{begin, end})
+	}
+
+	if err := b.add(&b.asn, "asn", entries, uris); err != nil {
+		return err
+	}
+
+	b.asnRanges = ranges
+	return nil
+}
+
+// AddIP adds a service with the IP networks (in CIDR notation) answered by the
+// RDAP servers. The IPv4 and IPv6 networks are added to the corresponding
+// registries
+func (b *BootstrapBuilder) AddIP(entries []string, uris ...string) error {
+	var ipv4, ipv6 []string
+
+	for _, entry := range entries {
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("invalid IP entry %q", entry)
+		}
+
+		if network.IP.To4() != nil {
+			ipv4 = append(ipv4, network.String())
+		} else {
+			ipv6 = append(ipv6, network.String())
+		}
+	}
+
+	if len(ipv4) > 0 {
+		if err := b.add(&b.ipv4, "ipv4", ipv4, uris); err != nil {
+			return err
+		}
+	}
+
+	if len(ipv6) > 0 {
+		return b.add(&b.ipv6, "ipv6", ipv6, uris)
+	}
+
+	return nil
+}
+
+// AddObjectTag adds a service for the entity handles with the tag (e.g.
+// "XXXX-ARIN" is tagged with "ARIN"), as described in RFC 8521. The contacts
+// are the email addresses of the service operator
+func (b *BootstrapBuilder) AddObjectTag(tag string, contacts []string, uris ...string) error {
+	if tag == "" || strings.ContainsAny(tag, "- ") {
+		return fmt.Errorf("invalid object tag %q", tag)
+	}
+
+	normalizedURIs, err := b.check("object-tags", []string{strings.ToUpper(tag)}, uris)
+	if err != nil {
+		return err
+	}
+
+	b.objectTags = append(b.objectTags, [3][]string{append([]string{}, contacts...), {tag}, normalizedURIs})
+	return nil
+}
+
+// add includes the service in the registry, checking the URIs and the
+// duplicated entries
+func (b *BootstrapBuilder) add(registry *[]service, name string, entries, uris []string) error {
+	if len(entries) == 0 {
+		return fmt.Errorf("no entries in the %s service", name)
+	}
+
+	normalizedURIs, err := b.check(name, entries, uris)
+	if err != nil {
+		return err
+	}
+
+	*registry = append(*registry, service{entries, normalizedURIs})
+	return nil
+}
+
+func (b *BootstrapBuilder) check(name string, entries, uris []string) ([]string, error) {
+	if len(uris) == 0 {
+		return nil, fmt.Errorf("no URIs in the %s service", name)
+	}
+
+	if b.entries == nil {
+		b.entries = make(map[string]bool)
+	}
+
+	for _, entry := range entries {
+		if b.entries[name+" "+entry] {
+			return nil, fmt.Errorf("duplicated %s entry %q", name, entry)
+		}
+	}
+
+	var normalizedURIs []string
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid bootstrap URI %q", uri)
+		}
+
+		// RFC 9224, section 3: the base URLs end with a "/"
+		if !strings.HasSuffix(uri, "/") {
+			uri += "/"
+		}
+		normalizedURIs = append(normalizedURIs, uri)
+	}
+
+	for _, entry := range entries {
+		b.entries[name+" "+entry] = true
+	}
+
+	return normalizedURIs, nil
+}
+
+// Build returns the content of the registry files, indexed by the file name
+// (e.g. BootstrapFileDNS). All files are returned, even without services
+func (b *BootstrapBuilder) Build(publication time.Time) (map[string][]byte, error) {
+	publication = publication.UTC().Truncate(time.Second)
+
+	registries := map[string]interface{}{
+		BootstrapFileDNS:  b.registry(publication, b.dns),
+		BootstrapFileASN:  b.registry(publication, b.asn),
+		BootstrapFileIPv4: b.registry(publication, b.ipv4),
+		BootstrapFileIPv6: b.registry(publication, b.ipv6),
+		BootstrapFileObjectTags: struct {
+			Version     string        `json:"version"`
+			Publication time.Time     `json:"publication"`
+			Description string        `json:"description,omitempty"`
+			Services    [][3][]string `json:"services"`
+		}{
+			Version:     version,
+			Publication: publication,
+			Description: b.Description,
+			Services:    append([][3][]string{}, b.objectTags...),
+		},
+	}
+
+	files := make(map[string][]byte)
+	for name, registry := range registries {
+		data, err := json.MarshalIndent(registry, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+
+	return files, nil
+}
+
+func (b *BootstrapBuilder) registry(publication time.Time, services []service) serviceRegistry {
+	return serviceRegistry{
+		Version:     version,
+		Publication: publication,
+		Description: b.Description,
+		Services:    append([]service{}, services...),
+	}
+}
+
+// BootstrapHandler is a HTTP handler that serves the RDAP Bootstrap Service
+// Registries files. The file is identified by the last path segment (e.g.
+// "/rdap/dns.json"). The responses have the caching headers (Cache-Control,
+// Last-Modified and ETag) and the conditional requests are answered with 304
+// Not Modified
+type BootstrapHandler struct {
+	// MaxAge is the time that clients and proxies can cache the files
+	MaxAge time.Duration
+
+	mu          sync.RWMutex
+	files       map[string][]byte
+	publication time.Time
+}
+
+// NewBootstrapHandler returns a handler that serves the files of the builder,
+// published now
+func NewBootstrapHandler(builder *BootstrapBuilder, maxAge time.Duration) (*BootstrapHandler, error) {
+	h := &BootstrapHandler{MaxAge: maxAge}
+	if err := h.Publish(builder, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Publish replaces the files served by the ones of the builder
+func (h *BootstrapHandler) Publish(builder *BootstrapBuilder, publication time.Time) error {
+	files, err := builder.Build(publication)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.files = files
+	h.publication = publication.UTC().Truncate(time.Second)
+	h.mu.Unlock()
+	return nil
+}
+
+// ServeHTTP implements the http.Handler interface
+func (h *BootstrapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.mu.RLock()
+	data, ok := h.files[path.Base(r.URL.Path)]
+	publication := h.publication
+	h.mu.RUnlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	hash := sha256.Sum256(data)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.MaxAge/time.Second)))
+	w.Header().Set("ETag", `"`+hex.EncodeToString(hash[:16])+`"`)
+
+	http.ServeContent(w, r, "", publication, bytes.NewReader(data))
+}
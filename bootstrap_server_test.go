@@ -0,0 +1,225 @@
+package rdap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBootstrapBuilder(t *testing.T) {
+	data := []struct {
+		description   string
+		add           func(b *BootstrapBuilder) error
+		expectedError error
+	}{
+		{
+			description: "it should add a DNS service",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddDNS([]string{"BR.", "café.br"}, "https://rdap.registro.br")
+			},
+		},
+		{
+			description: "it should add an IP service in both registries",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddIP([]string{"200.160.0.0/20", "2001:12ff::/32"}, "https://rdap.registro.br/")
+			},
+		},
+		{
+			description: "it should detect an invalid DNS entry",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddDNS([]string{"-invalid-"}, "https://rdap.example.com/")
+			},
+			expectedError: fmt.Errorf(`invalid DNS entry "-invalid-"`),
+		},
+		{
+			description: "it should detect a duplicated entry",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddDNS([]string{"br"}, "https://rdap.example.com/")
+			},
+			expectedError: fmt.Errorf(`duplicated dns entry "br"`),
+		},
+		{
+			description: "it should detect an invalid AS range",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddASN([]string{"65534-64512"}, "https://rdap.example.com/")
+			},
+			expectedError: fmt.Errorf(`invalid ASN entry "65534-64512"`),
+		},
+		{
+			description: "it should add an ASN service",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddASN([]string{"1-100", "200"}, "https://rdap.example.com/")
+			},
+		},
+		{
+			description: "it should detect an overlapping AS range",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddASN([]string{"50-60"}, "https://rdap.example.com/")
+			},
+			expectedError: fmt.Errorf(`overlapping asn entry "50-60"`),
+		},
+		{
+			description: "it should detect an AS range overlapping an AS number",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddASN([]string{"150-250"}, "https://rdap.example.com/")
+			},
+			expectedError: fmt.Errorf(`overlapping asn entry "150-250"`),
+		},
+		{
+			description: "it should detect overlapping AS ranges in the same service",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddASN([]string{"300-400", "400-500"}, "https://rdap.example.com/")
+			},
+			expectedError: fmt.Errorf(`overlapping asn entry "400-500"`),
+		},
+		{
+			description: "it should detect an invalid IP entry",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddIP([]string{"200.160.0.0"}, "https://rdap.example.com/")
+			},
+			expectedError: fmt.Errorf(`invalid IP entry "200.160.0.0"`),
+		},
+		{
+			description: "it should detect an invalid URI",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddASN([]string{"64512"}, "rdap.example.com")
+			},
+			expectedError: fmt.Errorf(`invalid bootstrap URI "rdap.example.com"`),
+		},
+		{
+			description: "it should detect a service without URIs",
+			add: func(b *BootstrapBuilder) error {
+				return b.AddObjectTag("EXAMPLE", nil)
+			},
+			expectedError: fmt.Errorf("no URIs in the object-tags service"),
+		},
+	}
+
+	builder := NewBootstrapBuilder()
+	for i, item := range data {
+		err := item.add(builder)
+		if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+			t.Errorf("[%d] %s: expected error “%v”, got “%v”", i, item.description, item.expectedError, err)
+		}
+	}
+
+	files, err := builder.Build(time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var registry serviceRegistry
+	if err := json.Unmarshal(files[BootstrapFileDNS], &registry); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := serviceRegistry{
+		Version:     "1.0",
+		Publication: time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC),
+		Services: []service{
+			{{"br", "xn--caf-dma.br"}, {"https://rdap.registro.br/"}},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, registry) {
+		t.Errorf("Unexpected DNS registry.\n%v", diff(expected, registry))
+	}
+
+	if len(files) != 5 {
+		t.Errorf("Unexpected number of files. Expected “5” and got “%d”", len(files))
+	}
+}
+
+func TestBootstrapHandler(t *testing.T) {
+	builder := NewBootstrapBuilder()
+	builder.AddDNS([]string{"br"}, "https://rdap.registro.br/")
+	builder.AddIP([]string{"200.160.0.0/20"}, "https://rdap.registro.br/")
+
+	handler, err := NewBootstrapHandler(builder, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// the registries must be readable by the bootstrap transport
+	registry, _, err := bootstrapFetch(http.DefaultClient, server.URL+"/rdap/ipv4.json", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uris, err := registry.matchIP(net.ParseIP("200.160.2.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"https://rdap.registro.br"}; !reflect.DeepEqual(expected, uris) {
+		t.Errorf("Unexpected URIs.\n%v", diff(expected, uris))
+	}
+
+	data := []struct {
+		description    string
+		method         string
+		path           string
+		header         http.Header
+		expectedStatus int
+	}{
+		{
+			description:    "it should serve a registry file",
+			method:         "GET",
+			path:           "/dns.json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "it should answer a conditional request",
+			method:         "GET",
+			path:           "/dns.json",
+			header:         http.Header{"If-Modified-Since": []string{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			description:    "it should detect an unknown file",
+			method:         "GET",
+			path:           "/unknown.json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			description:    "it should detect an invalid method",
+			method:         "POST",
+			path:           "/dns.json",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for i, item := range data {
+		r := httptest.NewRequest(item.method, item.path, nil)
+		for key, values := range item.header {
+			r.Header[key] = values
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != item.expectedStatus {
+			t.Errorf("[%d] %s: expected HTTP status “%d” and got “%d”", i, item.description, item.expectedStatus, w.Code)
+		}
+
+		if w.Code != http.StatusOK {
+			continue
+		}
+
+		if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "public, max-age=3600" {
+			t.Errorf("[%d] %s: unexpected Cache-Control “%s”", i, item.description, cacheControl)
+		}
+
+		if w.Header().Get("ETag") == "" || w.Header().Get("Last-Modified") == "" {
+			t.Errorf("[%d] %s: missing validators in the response headers", i, item.description)
+		}
+	}
+}
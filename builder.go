@@ -0,0 +1,391 @@
+package rdap
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/registrobr/rdap/protocol"
+	"golang.org/x/net/idna"
+)
+
+// ObjectBuilder completes the RDAP objects returned by the backends. It fills
+// the objectClassName and the self links of the object and of the nested
+// objects (nameservers, entities and networks), and checks the members
+// required by RFC 9083. The top-level objects also receive the notices and
+// the port43 of the server. The objects given are not changed, the builder
+// returns copies
+type ObjectBuilder struct {
+	// Notices are added to the top-level objects (e.g. terms of service),
+	// unless the object already has a notice with the same title
+	Notices []protocol.Notice
+
+	// Port43 is the WHOIS server of the objects. It is only filled when the
+	// object doesn't have one
+	Port43 string
+
+	base *url.URL
+}
+
+// NewObjectBuilder returns a builder that creates the links relative to the
+// base URL of the RDAP server (e.g. "https://rdap.example.com/rdap/")
+func NewObjectBuilder(baseURL string) (*ObjectBuilder, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+
+	// the query paths are resolved relative to the base URL
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	base.RawQuery = ""
+	base.Fragment = ""
+
+	return &ObjectBuilder{base: base}, nil
+}
+
+// Link returns a link to the object of the query in the RDAP server, with the
+// relation (e.g. protocol.LinkRelRelated)
+func (b *ObjectBuilder) Link(rel string, queryType QueryType, queryValue string) protocol.Link {
+	href := b.base.ResolveReference(&url.URL{Path: string(queryType) + "/" + queryValue}).String()
+
+	return protocol.Link{
+		Value: href,
+		Rel:   rel,
+		Href:  href,
+		Type:  ContentTypeRDAP,
+	}
+}
+
+// Domain completes the domain. The ldhName is required and is converted to
+// the canonical format (lowercase A-labels). When the ldhName has U-labels
+// and the unicodeName is empty, the unicodeName is filled
+func (b *ObjectBuilder) Domain(domain protocol.Domain) (*protocol.Domain, error) {
+	if err := b.domain(&domain); err != nil {
+		return nil, err
+	}
+
+	domain.Notices = b.notices(domain.Notices)
+	if domain.Port43.Port43 == "" {
+		domain.SetPort43(b.Port43)
+	}
+
+	return &domain, nil
+}
+
+// Nameserver completes the nameserver. The ldhName is required and is
+// converted to the canonical format, filling the unicodeName as in Domain
+func (b *ObjectBuilder) Nameserver(nameserver protocol.Nameserver) (*protocol.Nameserver, error) {
+	if err := b.nameserver(&nameserver); err != nil {
+		return nil, err
+	}
+
+	nameserver.Notices = b.notices(nameserver.Notices)
+	if nameserver.Port43 == "" {
+		nameserver.Port43 = b.Port43
+	}
+
+	return &nameserver, nil
+}
+
+// Entity completes the entity. The handle is required, as it identifies the
+// entity in the self link. In nested entities the handle is optional
+func (b *ObjectBuilder) Entity(entity protocol.Entity) (*protocol.Entity, error) {
+	if entity.Handle == "" {
+		return nil, fmt.Errorf("missing handle in the entity")
+	}
+
+	if err := b.entity(&entity); err != nil {
+		return nil, err
+	}
+
+	entity.Notices = b.notices(entity.Notices)
+	if entity.Port43.Port43 == "" {
+		entity.SetPort43(b.Port43)
+	}
+
+	return &entity, nil
+}
+
+// IPNetwork completes the IP network. The start and end addresses are
+// required, and the IP version is filled from them. Ranges that aren't CIDR
+// blocks can't be queried by the range, so they must already have a self
+// link
+func (b *ObjectBuilder) IPNetwork(ipNetwork protocol.IPNetwork) (*protocol.IPNetwork, error) {
+	if err := b.ipNetwork(&ipNetwork); err != nil {
+		return nil, err
+	}
+
+	ipNetwork.Notices = b.notices(ipNetwork.Notices)
+	if ipNetwork.Port43.Port43 == "" {
+		ipNetwork.SetPort43(b.Port43)
+	}
+
+	return &ipNetwork, nil
+}
+
+// AS completes the AS. When the end of the range is zero the AS has a single
+// number, and the end is filled with the start
+func (b *ObjectBuilder) AS(as protocol.AS) (*protocol.AS, error) {
+	if err := b.as(&as); err != nil {
+		return nil, err
+	}
+
+	as.Notices = b.notices(as.Notices)
+	if as.Port43.Port43 == "" {
+		as.SetPort43(b.Port43)
+	}
+
+	return &as, nil
+}
+
+func (b *ObjectBuilder) domain(domain *protocol.Domain) error {
+	if domain.LDHName == "" {
+		return fmt.Errorf("missing ldhName in the domain")
+	}
+
+	fqdn, ok := normalizeQueryValue(QueryTypeDomain, domain.LDHName)
+	if !ok {
+		return fmt.Errorf("invalid domain ldhName %q", domain.LDHName)
+	}
+
+	domain.ObjectClassName = protocol.ObjectClassDomain
+	if domain.UnicodeName == "" {
+		domain.UnicodeName = unicodeName(domain.LDHName, fqdn)
+	}
+	domain.LDHName = fqdn
+	domain.Links = b.selfLink(domain.Links, QueryTypeDomain, fqdn)
+
+	if err := checkEvents(domain.Events, "domain"); err != nil {
+		return err
+	}
+
+	domain.Nameservers = append([]protocol.Nameserver(nil), domain.Nameservers...)
+	for i := range domain.Nameservers {
+		if err := b.nameserver(&domain.Nameservers[i]); err != nil {
+			return err
+		}
+	}
+
+	if domain.Network != nil {
+		network := *domain.Network
+		if err := b.ipNetwork(&network); err != nil {
+			return err
+		}
+		domain.Network = &network
+	}
+
+	var err error
+	domain.Entities, err = b.entities(domain.Entities)
+	return err
+}
+
+func (b *ObjectBuilder) nameserver(nameserver *protocol.Nameserver) error {
+	if nameserver.LDHName == "" {
+		return fmt.Errorf("missing ldhName in the nameserver")
+	}
+
+	fqdn, ok := normalizeQueryValue(QueryTypeNameserver, nameserver.LDHName)
+	if !ok {
+		return fmt.Errorf("invalid nameserver ldhName %q", nameserver.LDHName)
+	}
+
+	nameserver.ObjectClassName = protocol.ObjectClassNameserver
+	if nameserver.UnicodeName == "" {
+		nameserver.UnicodeName = unicodeName(nameserver.LDHName, fqdn)
+	}
+	nameserver.LDHName = fqdn
+	nameserver.Links = b.selfLink(nameserver.Links, QueryTypeNameserver, fqdn)
+
+	if nameserver.IPAddresses != nil {
+		for _, address := range append(append([]string{}, nameserver.IPAddresses.V4...), nameserver.IPAddresses.V6...) {
+			if net.ParseIP(address) == nil {
+				return fmt.Errorf("invalid IP address %q in the nameserver", address)
+			}
+		}
+	}
+
+	if err := checkEvents(nameserver.Events, "nameserver"); err != nil {
+		return err
+	}
+
+	var err error
+	nameserver.Entities, err = b.entities(nameserver.Entities)
+	return err
+}
+
+func (b *ObjectBuilder) entity(entity *protocol.Entity) error {
+	entity.ObjectClassName = protocol.ObjectClassEntity
+	if entity.Handle != "" {
+		entity.Links = b.selfLink(entity.Links, QueryTypeEntity, entity.Handle)
+	}
+
+	if err := checkEvents(entity.Events, "entity"); err != nil {
+		return err
+	}
+
+	entity.Networks = append([]protocol.IPNetwork(nil), entity.Networks...)
+	for i := range entity.Networks {
+		if err := b.ipNetwork(&entity.Networks[i]); err != nil {
+			return err
+		}
+	}
+
+	entity.Autnums = append([]protocol.AS(nil), entity.Autnums...)
+	for i := range entity.Autnums {
+		if err := b.as(&entity.Autnums[i]); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	entity.Entities, err = b.entities(entity.Entities)
+	return err
+}
+
+// entities completes a copy of the nested entities
+func (b *ObjectBuilder) entities(entities []protocol.Entity) ([]protocol.Entity, error) {
+	entities = append([]protocol.Entity(nil), entities...)
+	for i := range entities {
+		if err := b.entity(&entities[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return entities, nil
+}
+
+func (b *ObjectBuilder) ipNetwork(ipNetwork *protocol.IPNetwork) error {
+	if ipNetwork.StartAddress == "" || ipNetwork.EndAddress == "" {
+		return fmt.Errorf("missing startAddress or endAddress in the IP network")
+	}
+
+	start, end := net.ParseIP(ipNetwork.StartAddress), net.ParseIP(ipNetwork.EndAddress)
+	if start == nil || end == nil || (start.To4() == nil) != (end.To4() == nil) || bytes.Compare(start, end) > 0 {
+		return fmt.Errorf("invalid IP network range %q - %q", ipNetwork.StartAddress, ipNetwork.EndAddress)
+	}
+
+	version := "v6"
+	if start.To4() != nil {
+		version = "v4"
+	}
+
+	if ipNetwork.IPVersion != "" && ipNetwork.IPVersion != version {
+		return fmt.Errorf("invalid ipVersion %q in the IP network", ipNetwork.IPVersion)
+	}
+
+	ipNetwork.ObjectClassName = protocol.ObjectClassIPNetwork
+	ipNetwork.IPVersion = version
+
+	// an IP query of the first address could answer a more specific network,
+	// so the self link of other ranges is given by the backend
+	if network, ok := rangeCIDR(start, end); ok {
+		ipNetwork.Links = b.selfLink(ipNetwork.Links, QueryTypeIP, network.String())
+	} else if !hasSelfLink(ipNetwork.Links) {
+		return fmt.Errorf("missing self link in the IP network range %q - %q, that isn't a CIDR block",
+			ipNetwork.StartAddress, ipNetwork.EndAddress)
+	}
+
+	if err := checkEvents(ipNetwork.Events, "IP network"); err != nil {
+		return err
+	}
+
+	var err error
+	ipNetwork.Entities, err = b.entities(ipNetwork.Entities)
+	return err
+}
+
+func (b *ObjectBuilder) as(as *protocol.AS) error {
+	if as.EndAutnum == 0 {
+		as.EndAutnum = as.StartAutnum
+	}
+
+	if as.StartAutnum == 0 || as.EndAutnum < as.StartAutnum {
+		return fmt.Errorf("invalid AS range %d - %d", as.StartAutnum, as.EndAutnum)
+	}
+
+	as.ObjectClassName = protocol.ObjectClassAutnum
+	as.Links = b.selfLink(as.Links, QueryTypeAutnum, strconv.FormatUint(uint64(as.StartAutnum), 10))
+
+	if err := checkEvents(as.Events, "AS"); err != nil {
+		return err
+	}
+
+	var err error
+	as.Entities, err = b.entities(as.Entities)
+	return err
+}
+
+// selfLink returns a copy of the links with the self link of the object,
+// replacing the existing one
+func (b *ObjectBuilder) selfLink(links []protocol.Link, queryType QueryType, queryValue string) []protocol.Link {
+	self := b.Link(protocol.LinkRelSelf, queryType, queryValue)
+
+	links = append([]protocol.Link(nil), links...)
+	for i := range links {
+		if links[i].Rel == protocol.LinkRelSelf {
+			links[i] = self
+			return links
+		}
+	}
+
+	return append(links, self)
+}
+
+func hasSelfLink(links []protocol.Link) bool {
+	for _, link := range links {
+		if link.Rel == protocol.LinkRelSelf {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unicodeName returns the name with U-labels of the canonical fqdn, when the
+// original name wasn't in the ASCII format
+func unicodeName(name, fqdn string) string {
+	if strings.IndexFunc(name, func(r rune) bool { return r > unicode.MaxASCII }) == -1 {
+		return ""
+	}
+
+	name, err := idna.ToUnicode(fqdn)
+	if err != nil {
+		return ""
+	}
+
+	return name
+}
+
+// notices returns a copy of the notices with the builder notices that are
+// missing
+func (b *ObjectBuilder) notices(notices []protocol.Notice) []protocol.Notice {
+	for _, notice := range b.Notices {
+		if !hasNotice(notices, notice.Title) {
+			notices = append(append([]protocol.Notice(nil), notices...), notice)
+		}
+	}
+
+	return notices
+}
+
+// checkEvents verifies the required members of the events (RFC 9083, section
+// 4.5)
+func checkEvents(events []protocol.Event, objectClass string) error {
+	for _, event := range events {
+		if event.Action == "" {
+			return fmt.Errorf("missing eventAction in the %s events", objectClass)
+		}
+
+		if event.Date.IsZero() {
+			return fmt.Errorf("missing eventDate of the %q event in the %s", event.Action, objectClass)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package rdap
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestObjectBuilder(t *testing.T) {
+	builder, err := NewObjectBuilder("https://rdap.example.com/rdap")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder.Port43 = "whois.example.com"
+	builder.Notices = []protocol.Notice{
+		{Title: "Terms of Use", Description: []string{"Service subject to terms of use."}},
+	}
+
+	self := func(queryType QueryType, queryValue string) []protocol.Link {
+		return []protocol.Link{builder.Link(protocol.LinkRelSelf, queryType, queryValue)}
+	}
+
+	date := protocol.NewEventDate(time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC))
+
+	data := []struct {
+		description   string
+		build         func() (interface{}, error)
+		expected      interface{}
+		expectedError error
+	}{
+		{
+			description: "it should build a domain with nested objects",
+			build: func() (interface{}, error) {
+				return builder.Domain(protocol.Domain{
+					LDHName:     "Café.BR.",
+					Nameservers: []protocol.Nameserver{{LDHName: "a.dns.br"}},
+					Entities:    []protocol.Entity{{Handle: "ABC12", Roles: []string{protocol.RoleRegistrant}}},
+					Events:      []protocol.Event{{Action: protocol.EventActionRegistration, Date: date}},
+					Links: []protocol.Link{
+						{Value: "http://old.example.com", Rel: "self", Href: "http://old.example.com"},
+						{Rel: "related", Href: "https://rdap.registrar.example.com/domain/xn--caf-dma.br"},
+					},
+				})
+			},
+			expected: &protocol.Domain{
+				ObjectClassName: protocol.ObjectClassDomain,
+				LDHName:         "xn--caf-dma.br",
+				UnicodeName:     "café.br",
+				Nameservers: []protocol.Nameserver{
+					{
+						ObjectClassName: protocol.ObjectClassNameserver,
+						LDHName:         "a.dns.br",
+						Links:           self(QueryTypeNameserver, "a.dns.br"),
+					},
+				},
+				Entities: []protocol.Entity{
+					{
+						ObjectClassName: protocol.ObjectClassEntity,
+						Handle:          "ABC12",
+						Roles:           []string{protocol.RoleRegistrant},
+						Links:           self(QueryTypeEntity, "ABC12"),
+					},
+				},
+				Events: []protocol.Event{{Action: protocol.EventActionRegistration, Date: date}},
+				Links: []protocol.Link{
+					{
+						Value: "https://rdap.example.com/rdap/domain/xn--caf-dma.br",
+						Rel:   "self",
+						Href:  "https://rdap.example.com/rdap/domain/xn--caf-dma.br",
+						Type:  "application/rdap+json",
+					},
+					{Rel: "related", Href: "https://rdap.registrar.example.com/domain/xn--caf-dma.br"},
+				},
+				Notices: builder.Notices,
+				Port43:  protocol.Port43{Port43: "whois.example.com"},
+			},
+		},
+		{
+			description: "it should build an IP network",
+			build: func() (interface{}, error) {
+				return builder.IPNetwork(protocol.IPNetwork{
+					StartAddress: "192.0.2.0",
+					EndAddress:   "192.0.2.255",
+					Notices:      []protocol.Notice{{Title: "Terms of Use"}},
+					Port43:       protocol.Port43{Port43: "whois.registry.example.com"},
+				})
+			},
+			expected: &protocol.IPNetwork{
+				ObjectClassName: protocol.ObjectClassIPNetwork,
+				StartAddress:    "192.0.2.0",
+				EndAddress:      "192.0.2.255",
+				IPVersion:       "v4",
+				Links:           self(QueryTypeIP, "192.0.2.0/24"),
+				Notices:         []protocol.Notice{{Title: "Terms of Use"}},
+				Port43:          protocol.Port43{Port43: "whois.registry.example.com"},
+			},
+		},
+		{
+			description: "it should keep the self link of an IP network that isn't a CIDR block",
+			build: func() (interface{}, error) {
+				return builder.IPNetwork(protocol.IPNetwork{
+					StartAddress: "192.0.2.0",
+					EndAddress:   "192.0.2.99",
+					Links:        self(QueryTypeIP, "192.0.2.0/25"),
+				})
+			},
+			expected: &protocol.IPNetwork{
+				ObjectClassName: protocol.ObjectClassIPNetwork,
+				StartAddress:    "192.0.2.0",
+				EndAddress:      "192.0.2.99",
+				IPVersion:       "v4",
+				Links:           self(QueryTypeIP, "192.0.2.0/25"),
+				Notices:         builder.Notices,
+				Port43:          protocol.Port43{Port43: "whois.example.com"},
+			},
+		},
+		{
+			description: "it should build a nameserver without unicodeName from A-labels",
+			build: func() (interface{}, error) {
+				return builder.Nameserver(protocol.Nameserver{LDHName: "ns.xn--caf-dma.br"})
+			},
+			expected: &protocol.Nameserver{
+				ObjectClassName: protocol.ObjectClassNameserver,
+				LDHName:         "ns.xn--caf-dma.br",
+				Links:           self(QueryTypeNameserver, "ns.xn--caf-dma.br"),
+				Notices:         builder.Notices,
+				Port43:          "whois.example.com",
+			},
+		},
+		{
+			description: "it should build an AS",
+			build: func() (interface{}, error) {
+				return builder.AS(protocol.AS{StartAutnum: 64512})
+			},
+			expected: &protocol.AS{
+				ObjectClassName: protocol.ObjectClassAutnum,
+				StartAutnum:     64512,
+				EndAutnum:       64512,
+				Links:           self(QueryTypeAutnum, "64512"),
+				Notices:         builder.Notices,
+				Port43:          protocol.Port43{Port43: "whois.example.com"},
+			},
+		},
+		{
+			description: "it should build an entity with a nested entity without handle",
+			build: func() (interface{}, error) {
+				return builder.Entity(protocol.Entity{
+					Handle:   "ABC12",
+					Entities: []protocol.Entity{{Roles: []string{protocol.RoleAbuse}}},
+				})
+			},
+			expected: &protocol.Entity{
+				ObjectClassName: protocol.ObjectClassEntity,
+				Handle:          "ABC12",
+				Entities: []protocol.Entity{
+					{ObjectClassName: protocol.ObjectClassEntity, Roles: []string{protocol.RoleAbuse}},
+				},
+				Links:   self(QueryTypeEntity, "ABC12"),
+				Notices: builder.Notices,
+				Port43:  protocol.Port43{Port43: "whois.example.com"},
+			},
+		},
+		{
+			description: "it should detect a domain without ldhName",
+			build: func() (interface{}, error) {
+				return builder.Domain(protocol.Domain{Handle: "EXAMPLE"})
+			},
+			expectedError: fmt.Errorf("missing ldhName in the domain"),
+		},
+		{
+			description: "it should detect an invalid nested nameserver",
+			build: func() (interface{}, error) {
+				return builder.Domain(protocol.Domain{
+					LDHName:     "example.br",
+					Nameservers: []protocol.Nameserver{{LDHName: "-invalid-"}},
+				})
+			},
+			expectedError: fmt.Errorf(`invalid nameserver ldhName "-invalid-"`),
+		},
+		{
+			description: "it should detect an event without date",
+			build: func() (interface{}, error) {
+				return builder.Nameserver(protocol.Nameserver{
+					LDHName: "a.dns.br",
+					Events:  []protocol.Event{{Action: protocol.EventActionLastChanged}},
+				})
+			},
+			expectedError: fmt.Errorf(`missing eventDate of the "last changed" event in the nameserver`),
+		},
+		{
+			description: "it should detect an entity without handle",
+			build: func() (interface{}, error) {
+				return builder.Entity(protocol.Entity{})
+			},
+			expectedError: fmt.Errorf("missing handle in the entity"),
+		},
+		{
+			description: "it should detect an inverted IP network range",
+			build: func() (interface{}, error) {
+				return builder.IPNetwork(protocol.IPNetwork{StartAddress: "192.0.2.255", EndAddress: "192.0.2.0"})
+			},
+			expectedError: fmt.Errorf(`invalid IP network range "192.0.2.255" - "192.0.2.0"`),
+		},
+		{
+			description: "it should detect an IP network range that isn't a CIDR block without self link",
+			build: func() (interface{}, error) {
+				return builder.IPNetwork(protocol.IPNetwork{StartAddress: "192.0.2.0", EndAddress: "192.0.2.99"})
+			},
+			expectedError: fmt.Errorf(`missing self link in the IP network range "192.0.2.0" - "192.0.2.99", that isn't a CIDR block`),
+		},
+		{
+			description: "it should detect a wrong IP version",
+			build: func() (interface{}, error) {
+				return builder.IPNetwork(protocol.IPNetwork{StartAddress: "2001:db8::", EndAddress: "2001:db8::ff", IPVersion: "v4"})
+			},
+			expectedError: fmt.Errorf(`invalid ipVersion "v4" in the IP network`),
+		},
+		{
+			description: "it should detect an invalid AS range",
+			build: func() (interface{}, error) {
+				return builder.AS(protocol.AS{StartAutnum: 64512, EndAutnum: 100})
+			},
+			expectedError: fmt.Errorf("invalid AS range 64512 - 100"),
+		},
+	}
+
+	for i, item := range data {
+		object, err := item.build()
+		if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+			t.Errorf("[%d] %s: expected error “%v”, got “%v”", i, item.description, item.expectedError, err)
+			continue
+		}
+
+		if item.expectedError == nil && !reflect.DeepEqual(item.expected, object) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, object))
+		}
+	}
+
+	if _, err := NewObjectBuilder("rdap.example.com"); err == nil {
+		t.Error("Expected an error for a base URL without scheme")
+	}
+}
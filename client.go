@@ -30,6 +30,17 @@ type Client struct {
 	// directly. Remember that if you use a bootstrap transport layer this
 	// information might not be used
 	URIs []string
+
+	// LinkTransport is the network layer used to follow links between RDAP
+	// objects. As the links already contain the RDAP server address, it should
+	// send the requests directly, without any bootstrap strategy. If not
+	// defined a direct transport layer with the default HTTP client is used
+	LinkTransport Fetcher
+
+	// WHOIS is the client used by QueryWithFallback when the RDAP query
+	// fails. If not defined a WHOIS client that starts the queries in the
+	// IANA server is used
+	WHOIS *WHOISClient
 }
 
 // NewClient is an easy way to create a client with bootstrap support or not,
@@ -40,6 +51,7 @@ func NewClient(URIs []string) *Client {
 	}
 
 	var httpClient http.Client
+	client.LinkTransport = NewDefaultFetcher(&httpClient)
 
 	if len(URIs) == 0 {
 		client.Transport = NewBootstrapFetcher(&httpClient, IANABootstrap, nil)
@@ -81,6 +93,10 @@ func (c *Client) Domain(fqdn string, header http.Header, queryString url.Values)
 		return nil, resp.Header, err
 	}
 
+	if !domain.Has(protocol.JSContactConformance) {
+		ignoreJSContact(domain)
+	}
+
 	return domain, resp.Header, nil
 }
 
@@ -110,6 +126,10 @@ func (c *Client) Ticket(ticketNumber int, header http.Header, queryString url.Va
 		return nil, resp.Header, err
 	}
 
+	if !domain.Has(protocol.JSContactConformance) {
+		ignoreJSContact(domain)
+	}
+
 	return domain, resp.Header, nil
 }
 
@@ -141,6 +161,10 @@ func (c *Client) ASN(asn uint32, header http.Header, queryString url.Values) (*p
 		return nil, resp.Header, err
 	}
 
+	if !as.Has(protocol.JSContactConformance) {
+		ignoreJSContact(as)
+	}
+
 	return as, resp.Header, nil
 }
 
@@ -170,6 +194,10 @@ func (c *Client) Entity(identifier string, header http.Header, queryString url.V
 		return nil, resp.Header, err
 	}
 
+	if !entity.Has(protocol.JSContactConformance) {
+		ignoreJSContact(entity)
+	}
+
 	return entity, resp.Header, nil
 }
 
@@ -203,6 +231,10 @@ func (c *Client) IPNetwork(ipnet *net.IPNet, header http.Header, queryString url
 		return nil, resp.Header, err
 	}
 
+	if !ipNetwork.Has(protocol.JSContactConformance) {
+		ignoreJSContact(ipNetwork)
+	}
+
 	return ipNetwork, resp.Header, nil
 }
 
@@ -236,6 +268,10 @@ func (c *Client) IP(ip net.IP, header http.Header, queryString url.Values) (*pro
 		return nil, resp.Header, err
 	}
 
+	if !ipNetwork.Has(protocol.JSContactConformance) {
+		ignoreJSContact(ipNetwork)
+	}
+
 	return ipNetwork, resp.Header, nil
 }
 
@@ -263,3 +299,36 @@ func (c *Client) Query(object string, header http.Header, queryString url.Values
 
 	return c.Entity(object, header, queryString)
 }
+
+// ignoreJSContact removes the JSContact cards of the object entities. It's
+// used when the server didn't declare the jscontact extension in the
+// rdapConformance, as the member is only defined by the extension (RFC 9083,
+// section 4.1)
+func ignoreJSContact(object interface{}) {
+	switch o := object.(type) {
+	case *protocol.Domain:
+		for i := range o.Nameservers {
+			ignoreJSContact(&o.Nameservers[i])
+		}
+
+		if o.Network != nil {
+			ignoreJSContact(o.Network)
+		}
+
+	case *protocol.Entity:
+		o.JSContactCard = nil
+
+		for i := range o.Networks {
+			ignoreJSContact(&o.Networks[i])
+		}
+
+		for i := range o.Autnums {
+			ignoreJSContact(&o.Autnums[i])
+		}
+	}
+
+	entities := protocol.ObjectEntities(object)
+	for i := range entities {
+		ignoreJSContact(&entities[i])
+	}
+}
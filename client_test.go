@@ -487,6 +487,63 @@ func TestClientEntity(t *testing.T) {
 				"Random-Header": []string{"value"},
 			},
 		},
+		{
+			description: "it should ignore the JSContact card without the conformance",
+			entity:      "h_005506560000136-NICBR",
+			client: func() (*http.Response, error) {
+				var response http.Response
+				response.Body = nopCloser{bytes.NewBufferString(`{
+  "objectClassName": "entity",
+  "handle": "XXXX",
+  "jscontact_card": {"@type": "Card", "version": "1.0", "name": {"full": "Joe User"}},
+  "networks": [
+    {
+      "objectClassName": "ip network",
+      "handle": "NET-1",
+      "entities": [{"objectClassName": "entity", "handle": "YYYY", "jscontact_card": {"@type": "Card", "version": "1.0"}}]
+    }
+  ],
+  "rdapConformance": ["rdap_level_0"]
+}`)}
+				return &response, nil
+			},
+			expected: &protocol.Entity{
+				ObjectClassName: "entity",
+				Handle:          "XXXX",
+				Networks: []protocol.IPNetwork{
+					{
+						ObjectClassName: "ip network",
+						Handle:          "NET-1",
+						Entities:        []protocol.Entity{{ObjectClassName: "entity", Handle: "YYYY"}},
+					},
+				},
+				Conformance: protocol.Conformance{Levels: []string{"rdap_level_0"}},
+			},
+		},
+		{
+			description: "it should keep the JSContact card with the conformance",
+			entity:      "h_005506560000136-NICBR",
+			client: func() (*http.Response, error) {
+				var response http.Response
+				response.Body = nopCloser{bytes.NewBufferString(`{
+  "objectClassName": "entity",
+  "handle": "XXXX",
+  "jscontact_card": {"@type": "Card", "version": "1.0", "name": {"full": "Joe User"}},
+  "rdapConformance": ["rdap_level_0", "jscontact"]
+}`)}
+				return &response, nil
+			},
+			expected: &protocol.Entity{
+				ObjectClassName: "entity",
+				Handle:          "XXXX",
+				JSContactCard: &protocol.JSContactCard{
+					Type:    "Card",
+					Version: "1.0",
+					Name:    &protocol.JSContactName{Full: "Joe User"},
+				},
+				Conformance: protocol.Conformance{Levels: []string{"rdap_level_0", "jscontact"}},
+			},
+		},
 		{
 			description: "it should fail to query an entity",
 			entity:      "h_005506560000136-NICBR",
@@ -0,0 +1,217 @@
+package rdap
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+const (
+	// DefaultConditionalCacheSize is the maximum number of responses kept by
+	// the conditional fetcher when no size is given
+	DefaultConditionalCacheSize = 1000
+)
+
+// notModified sets the validators of the response, as described in RFC
+// 7232: the ETag is the hash of the JSON encoding and the Last-Modified is
+// the most recent "last changed" or "last update" event of the object. When
+// the client already has the object the response is 304 Not Modified and
+// true is returned
+func notModified(w http.ResponseWriter, r *http.Request, data []byte, object interface{}) bool {
+	hash := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+	w.Header().Set("ETag", etag)
+
+	lastModified := objectLastModified(object)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	// RFC 7232, section 6: If-Modified-Since is ignored when If-None-Match
+	// is present
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if !etagMatch(ifNoneMatch, etag) {
+			return false
+		}
+
+	} else {
+		ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || lastModified.IsZero() || lastModified.Truncate(time.Second).After(ifModifiedSince) {
+			return false
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// objectLastModified returns the date of the most recent change of the
+// object, or a zero time when the object has no change events
+func objectLastModified(object interface{}) time.Time {
+	var lastModified time.Time
+
+	for _, event := range protocol.ObjectEvents(object) {
+		if event.Action != protocol.EventActionLastChanged && event.Action != protocol.EventActionLastUpdate {
+			continue
+		}
+
+		if event.Date.After(lastModified) {
+			lastModified = event.Date.Time
+		}
+	}
+
+	return lastModified
+}
+
+// etagMatch checks if the entity tag is in the If-None-Match list, using the
+// weak comparison (RFC 7232, section 2.3.2)
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// conditionalResponse stores a response with validators, that is used when
+// the server answers 304 Not Modified
+type conditionalResponse struct {
+	key          string
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+}
+
+// NewConditionalFetcher returns a transport layer that keeps the responses
+// with validators (ETag or Last-Modified), and sends conditional requests
+// for them. When the RDAP server answers with 304 Not Modified the kept
+// response is returned, so the caller doesn't notice the difference. At most
+// size responses are kept (DefaultConditionalCacheSize if zero), and the
+// least recently used response is removed to release space
+func NewConditionalFetcher(fetcher Fetcher, size int) Fetcher {
+	if size <= 0 {
+		size = DefaultConditionalCacheSize
+	}
+
+	return decorate(fetcher, conditional(size))
+}
+
+func conditional(size int) decorator {
+	var mu sync.Mutex
+	cache := make(map[string]*list.Element)
+	usage := list.New()
+
+	return func(f Fetcher) Fetcher {
+		return fetcherFunc(func(uris []string, queryType QueryType, queryValue string, header http.Header, queryString url.Values) (*http.Response, error) {
+			// the credentials are part of the key, as each client can have a
+			// different view of the object
+			key := strings.Join([]string{
+				strings.Join(uris, " "),
+				string(queryType),
+				queryValue,
+				queryString.Encode(),
+				header.Get("Authorization"),
+			}, "\n")
+
+			var cached conditionalResponse
+			mu.Lock()
+			element, found := cache[key]
+			if found {
+				usage.MoveToFront(element)
+				cached = element.Value.(conditionalResponse)
+			}
+			mu.Unlock()
+
+			// the header is copied, so the caller's header isn't changed
+			conditionalHeader := make(http.Header)
+			for name, values := range header {
+				conditionalHeader[name] = values
+			}
+
+			if found {
+				if cached.etag != "" {
+					conditionalHeader.Set("If-None-Match", cached.etag)
+				}
+				if cached.lastModified != "" {
+					conditionalHeader.Set("If-Modified-Since", cached.lastModified)
+				}
+			}
+
+			resp, err := f.Fetch(uris, queryType, queryValue, conditionalHeader, queryString)
+			if err == ErrNotModified && found {
+				if resp.Body != nil {
+					resp.Body.Close()
+				}
+
+				responseHeader := make(http.Header)
+				for name, values := range cached.header {
+					responseHeader[name] = values
+				}
+
+				return &http.Response{
+					Status:     "200 OK",
+					StatusCode: http.StatusOK,
+					Proto:      resp.Proto,
+					ProtoMajor: resp.ProtoMajor,
+					ProtoMinor: resp.ProtoMinor,
+					Header:     responseHeader,
+					Body:       ioutil.NopCloser(bytes.NewReader(cached.body)),
+					Request:    resp.Request,
+				}, nil
+
+			} else if err != nil {
+				return resp, err
+			}
+
+			etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+			if etag == "" && lastModified == "" {
+				return resp, nil
+			}
+
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			response := conditionalResponse{
+				key:          key,
+				etag:         etag,
+				lastModified: lastModified,
+				header:       resp.Header,
+				body:         body,
+			}
+
+			mu.Lock()
+			if element, found := cache[key]; found {
+				element.Value = response
+				usage.MoveToFront(element)
+
+			} else {
+				if usage.Len() >= size {
+					oldest := usage.Back()
+					usage.Remove(oldest)
+					delete(cache, oldest.Value.(conditionalResponse).key)
+				}
+				cache[key] = usage.PushFront(response)
+			}
+			mu.Unlock()
+
+			return resp, nil
+		})
+	}
+}
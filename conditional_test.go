@@ -0,0 +1,240 @@
+package rdap
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestServerConditionalRequests(t *testing.T) {
+	lastChanged := time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	server := NewServer()
+	server.Handle(QueryTypeDomain, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		return &protocol.Domain{
+			ObjectClassName: "domain",
+			LDHName:         queryValue,
+			Events: []protocol.Event{
+				{Action: protocol.EventActionRegistration, Date: protocol.NewEventDate(lastChanged.Add(-time.Hour))},
+				{Action: protocol.EventActionLastChanged, Date: protocol.NewEventDate(lastChanged)},
+			},
+		}, nil
+	}))
+
+	r := httptest.NewRequest("GET", "/domain/example.br", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Missing ETag in the response")
+	}
+
+	if lastModified := w.Header().Get("Last-Modified"); lastModified != "Thu, 20 Jul 2017 12:00:00 GMT" {
+		t.Errorf("Unexpected Last-Modified “%s”", lastModified)
+	}
+
+	data := []struct {
+		description    string
+		path           string
+		header         http.Header
+		expectedStatus int
+	}{
+		{
+			description:    "it should answer a matching entity tag",
+			path:           "/domain/example.br",
+			header:         http.Header{"If-None-Match": []string{`"other", ` + etag}},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			description:    "it should answer a weak entity tag",
+			path:           "/domain/example.br",
+			header:         http.Header{"If-None-Match": []string{"W/" + etag}},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			description:    "it should detect a changed object by the entity tag",
+			path:           "/domain/example2.br",
+			header:         http.Header{"If-None-Match": []string{etag}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "it should answer an object that wasn't modified since the date",
+			path:           "/domain/example.br",
+			header:         http.Header{"If-Modified-Since": []string{"Thu, 20 Jul 2017 12:00:00 GMT"}},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			description:    "it should detect an object modified since the date",
+			path:           "/domain/example.br",
+			header:         http.Header{"If-Modified-Since": []string{"Thu, 20 Jul 2017 11:59:59 GMT"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description: "it should prefer the entity tag to the date",
+			path:        "/domain/example.br",
+			header: http.Header{
+				"If-None-Match":     []string{`"other"`},
+				"If-Modified-Since": []string{"Thu, 20 Jul 2017 12:00:00 GMT"},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "it should not answer errors conditionally",
+			path:           "/autnum/AS65536",
+			header:         http.Header{"If-None-Match": []string{"*"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for i, item := range data {
+		r := httptest.NewRequest("GET", item.path, nil)
+		for key, values := range item.header {
+			r.Header[key] = values
+		}
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+
+		if w.Code != item.expectedStatus {
+			t.Errorf("[%d] %s: expected HTTP status “%d” and got “%d”", i, item.description, item.expectedStatus, w.Code)
+		}
+
+		if w.Code == http.StatusNotModified && w.Body.Len() > 0 {
+			t.Errorf("[%d] %s: unexpected body “%s”", i, item.description, w.Body.String())
+		}
+	}
+}
+
+func TestConditionalFetcher(t *testing.T) {
+	server := NewServer()
+	server.Handle(QueryTypeDomain, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		return &protocol.Domain{ObjectClassName: "domain", LDHName: queryValue}, nil
+	}))
+
+	var statuses []int
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, r)
+		statuses = append(statuses, recorder.Code)
+
+		for key, values := range recorder.Header() {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(recorder.Code)
+		w.Write(recorder.Body.Bytes())
+	}))
+	defer httpServer.Close()
+
+	fetcher := NewConditionalFetcher(NewDefaultFetcher(http.DefaultClient), 0)
+
+	var bodies []string
+	for i := 0; i < 2; i++ {
+		header := http.Header{"X-Forwarded-For": []string{"192.0.2.1"}}
+
+		resp, err := fetcher.Fetch([]string{httpServer.URL}, QueryTypeDomain, "example.br", header, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("[%d] Unexpected status. Expected “200” and got “%d”", i, resp.StatusCode)
+		}
+
+		if len(header) != 1 {
+			t.Errorf("[%d] The caller header was changed: “%v”", i, header)
+		}
+
+		bodies = append(bodies, string(body))
+	}
+
+	if len(statuses) != 2 || statuses[0] != http.StatusOK || statuses[1] != http.StatusNotModified {
+		t.Errorf("Unexpected server statuses. Expected “[200 304]” and got “%v”", statuses)
+	}
+
+	if bodies[0] == "" || bodies[0] != bodies[1] {
+		t.Errorf("Unexpected cached response.\n%v", diff(bodies[0], bodies[1]))
+	}
+}
+
+func TestConditionalFetcherEviction(t *testing.T) {
+	server := NewServer()
+	server.Handle(QueryTypeDomain, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		return &protocol.Domain{ObjectClassName: "domain", LDHName: queryValue}, nil
+	}))
+
+	var statuses []int
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, r)
+		statuses = append(statuses, recorder.Code)
+
+		for key, values := range recorder.Header() {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(recorder.Code)
+		w.Write(recorder.Body.Bytes())
+	}))
+	defer httpServer.Close()
+
+	fetcher := NewConditionalFetcher(NewDefaultFetcher(http.DefaultClient), 2)
+
+	// the least recently used response (b.br) is removed when c.br is kept
+	for _, domain := range []string{"a.br", "b.br", "a.br", "c.br", "a.br", "b.br"} {
+		resp, err := fetcher.Fetch([]string{httpServer.URL}, QueryTypeDomain, domain, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	expected := []int{
+		http.StatusOK, http.StatusOK, http.StatusNotModified,
+		http.StatusOK, http.StatusNotModified, http.StatusOK,
+	}
+
+	if !reflect.DeepEqual(expected, statuses) {
+		t.Errorf("Unexpected server statuses. Expected “%v” and got “%v”", expected, statuses)
+	}
+}
+
+func TestDefaultFetcherNotModified(t *testing.T) {
+	var requests int
+	otherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer otherServer.Close()
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer httpServer.Close()
+
+	fetcher := NewDefaultFetcher(http.DefaultClient)
+
+	resp, err := fetcher.Fetch([]string{httpServer.URL, otherServer.URL}, QueryTypeDomain, "example.br", nil, nil)
+	if err != ErrNotModified {
+		t.Fatalf("Expected error “%v” and got “%v”", ErrNotModified, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Unexpected status. Expected “304” and got “%d”", resp.StatusCode)
+	}
+
+	if requests > 0 {
+		t.Errorf("Unexpected %d requests to the other server", requests)
+	}
+}
@@ -0,0 +1,205 @@
+package rdap
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+const (
+	// DefaultHierarchyDepth is a reasonable limit of levels to walk in an IP
+	// network hierarchy. Usually there are only a few levels between the
+	// assignment and the RIR allocation
+	DefaultHierarchyDepth = 10
+)
+
+// IPHierarchy will retrieve the IP network of the given IP and walk the
+// allocation chain up to the RIR allocation, following the "up" links (or
+// querying the less specific network when only the parentHandle is
+// available). When children is true the "down" links are also followed to
+// retrieve the more specific networks. The maxDepth parameter limits the
+// number of levels walked in each direction. The networks are returned in
+// order, from the least specific to the most specific, with the children
+// of the same level grouped together. A network that was already visited is
+// not retrieved again. On error the networks retrieved so far are also
+// returned
+func (c *Client) IPHierarchy(ip net.IP, maxDepth int, children bool, header http.Header, queryString url.Values) ([]*protocol.IPNetwork, error) {
+	network, _, err := c.IP(ip, header, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	visited := make(map[string]bool)
+	visited[ipNetworkKey(network)] = true
+
+	hierarchy := []*protocol.IPNetwork{network}
+
+	current := network
+	for depth := 0; depth < maxDepth; depth++ {
+		parent, err := c.ipNetworkParent(current, header, queryString)
+		if err != nil {
+			return hierarchy, err
+		}
+
+		if parent == nil || visited[ipNetworkKey(parent)] {
+			break
+		}
+		visited[ipNetworkKey(parent)] = true
+
+		hierarchy = append([]*protocol.IPNetwork{parent}, hierarchy...)
+		current = parent
+	}
+
+	if !children {
+		return hierarchy, nil
+	}
+
+	level := []*protocol.IPNetwork{network}
+	for depth := 0; depth < maxDepth && len(level) > 0; depth++ {
+		var nextLevel []*protocol.IPNetwork
+
+		for _, parent := range level {
+			for _, link := range parent.Links {
+				if link.Rel != protocol.LinkRelDown || link.Href == "" {
+					continue
+				}
+
+				child, err := c.followIPNetwork(link.Href, header, queryString)
+				if err != nil {
+					return hierarchy, err
+				}
+
+				if visited[ipNetworkKey(child)] {
+					continue
+				}
+				visited[ipNetworkKey(child)] = true
+
+				hierarchy = append(hierarchy, child)
+				nextLevel = append(nextLevel, child)
+			}
+		}
+
+		level = nextLevel
+	}
+
+	return hierarchy, nil
+}
+
+// ipNetworkParent retrieves the less specific network. If the network
+// doesn't have a parent nil is returned
+func (c *Client) ipNetworkParent(network *protocol.IPNetwork, header http.Header, queryString url.Values) (*protocol.IPNetwork, error) {
+	if link, found := protocol.FindLink(network.Links, protocol.LinkRelUp); found && link.Href != "" {
+		return c.followIPNetwork(link.Href, header, queryString)
+	}
+
+	if network.ParentHandle == "" {
+		return nil, nil
+	}
+
+	// without the link we query the network that contains the current network
+	// with one bit less in the prefix, that should be the parent network
+	ipnet := ipNetworkRange(network)
+	if ipnet == nil {
+		return nil, fmt.Errorf("invalid address range in IP network %s", network.Handle)
+	}
+
+	size, bits := ipnet.Mask.Size()
+	if size == 0 {
+		return nil, nil
+	}
+
+	ipnet.Mask = net.CIDRMask(size-1, bits)
+	ipnet.IP = ipnet.IP.Mask(ipnet.Mask)
+
+	parent, _, err := c.IPNetwork(ipnet, header, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	// the server returns the most specific network that contains the query,
+	// that isn't always the parent network, so we check the result
+	if parent.Handle != network.ParentHandle {
+		return nil, fmt.Errorf("IP network %s returned as the parent of %s doesn't match the parent handle %s",
+			parent.Handle, network.Handle, network.ParentHandle)
+	}
+
+	if !ipNetworkCovers(parent, network) {
+		return nil, fmt.Errorf("IP network %s returned as the parent of %s doesn't cover its address range",
+			parent.Handle, network.Handle)
+	}
+
+	return parent, nil
+}
+
+func (c *Client) followIPNetwork(href string, header http.Header, queryString url.Values) (*protocol.IPNetwork, error) {
+	object, _, err := c.Follow(href, header, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	network, ok := object.(*protocol.IPNetwork)
+	if !ok {
+		return nil, fmt.Errorf("link %s does not reference an IP network", href)
+	}
+
+	return network, nil
+}
+
+// ipNetworkKey identifies an IP network to detect cycles in the hierarchy
+func ipNetworkKey(network *protocol.IPNetwork) string {
+	if network.Handle != "" {
+		return network.Handle
+	}
+
+	return network.StartAddress + "-" + network.EndAddress
+}
+
+// ipNetworkCovers checks if the address range of the parent IP network
+// contains the address range of the child IP network
+func ipNetworkCovers(parent, child *protocol.IPNetwork) bool {
+	parentStart, parentEnd := net.ParseIP(parent.StartAddress), net.ParseIP(parent.EndAddress)
+	childStart, childEnd := net.ParseIP(child.StartAddress), net.ParseIP(child.EndAddress)
+	if parentStart == nil || parentEnd == nil || childStart == nil || childEnd == nil {
+		return false
+	}
+
+	return bytes.Compare(parentStart.To16(), childStart.To16()) <= 0 &&
+		bytes.Compare(parentEnd.To16(), childEnd.To16()) >= 0
+}
+
+// ipNetworkRange returns the smallest CIDR that contains the address range of
+// the IP network
+func ipNetworkRange(network *protocol.IPNetwork) *net.IPNet {
+	start := net.ParseIP(network.StartAddress)
+	end := net.ParseIP(network.EndAddress)
+	if start == nil || end == nil {
+		return nil
+	}
+
+	bits := 8 * net.IPv6len
+	if start4, end4 := start.To4(), end.To4(); start4 != nil && end4 != nil {
+		start, end = start4, end4
+		bits = 8 * net.IPv4len
+	} else {
+		start, end = start.To16(), end.To16()
+	}
+
+	size := 0
+	for size < bits {
+		bit := byte(0x80 >> uint(size%8))
+		if start[size/8]&bit != end[size/8]&bit {
+			break
+		}
+		size++
+	}
+
+	mask := net.CIDRMask(size, bits)
+	return &net.IPNet{
+		IP:   start.Mask(mask),
+		Mask: mask,
+	}
+}
@@ -0,0 +1,242 @@
+package rdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestClientIPHierarchy(t *testing.T) {
+	root := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-192-0-0-0",
+		StartAddress:    "192.0.0.0",
+		EndAddress:      "192.0.3.255",
+	}
+
+	allocation := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-192-0-2-0",
+		StartAddress:    "192.0.2.0",
+		EndAddress:      "192.0.3.255",
+		ParentHandle:    "NET-192-0-0-0",
+	}
+
+	assignment := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-192-0-2-0-24",
+		StartAddress:    "192.0.2.0",
+		EndAddress:      "192.0.2.255",
+		ParentHandle:    "NET-192-0-2-0",
+		Links: []protocol.Link{
+			{Rel: "up", Href: "https://rdap.rir.example/ip/192.0.2.0/23"},
+			{Rel: "down", Href: "https://rdap.rir.example/ip/192.0.2.0/25"},
+			{Rel: "down", Href: "https://rdap.rir.example/ip/192.0.2.128/25"},
+		},
+	}
+
+	child1 := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-192-0-2-0-25",
+		StartAddress:    "192.0.2.0",
+		EndAddress:      "192.0.2.127",
+		Links: []protocol.Link{
+			{Rel: "up", Href: "https://rdap.rir.example/ip/192.0.2.0/24"},
+		},
+	}
+
+	child2 := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-192-0-2-128-25",
+		StartAddress:    "192.0.2.128",
+		EndAddress:      "192.0.2.255",
+		Links: []protocol.Link{
+			// cycle that should be ignored
+			{Rel: "down", Href: "https://rdap.rir.example/ip/192.0.2.0/24"},
+		},
+	}
+
+	// without the "up" link the less specific network returned by the server
+	// isn't the parent network
+	orphan := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-198-51-100-0",
+		StartAddress:    "198.51.100.0",
+		EndAddress:      "198.51.100.255",
+		ParentHandle:    "NET-198-51-100-0-23",
+	}
+
+	orphanCovering := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-198-51-0-0",
+		StartAddress:    "198.51.0.0",
+		EndAddress:      "198.51.255.255",
+	}
+
+	// the less specific network returned by the server has the parent handle,
+	// but doesn't cover the network
+	misplaced := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-203-0-113-0",
+		StartAddress:    "203.0.113.0",
+		EndAddress:      "203.0.113.255",
+		ParentHandle:    "NET-203-0-112-0",
+	}
+
+	misplacedParent := &protocol.IPNetwork{
+		ObjectClassName: "ip network",
+		Handle:          "NET-203-0-112-0",
+		StartAddress:    "203.0.112.0",
+		EndAddress:      "203.0.112.255",
+	}
+
+	networks := map[string]*protocol.IPNetwork{
+		"192.0.2.10":      assignment,
+		"192.0.2.0/24":    assignment,
+		"192.0.2.0/23":    allocation,
+		"192.0.0.0/22":    root,
+		"192.0.2.0/25":    child1,
+		"192.0.2.128/25":  child2,
+		"198.51.100.10":   orphan,
+		"198.51.100.0/23": orphanCovering,
+		"203.0.113.10":    misplaced,
+		"203.0.112.0/23":  misplacedParent,
+	}
+
+	fetcher := fetcherFunc(func(uris []string, queryType QueryType, queryValue string, header http.Header, queryString url.Values) (*http.Response, error) {
+		if queryType != QueryTypeIP {
+			return nil, fmt.Errorf("expected query type “%s” and got “%s”", QueryTypeIP, queryType)
+		}
+
+		network, ok := networks[queryValue]
+		if !ok {
+			return nil, ErrNotFound
+		}
+
+		data, err := json.Marshal(network)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var response http.Response
+		response.Body = nopCloser{bytes.NewBuffer(data)}
+		return &response, nil
+	})
+
+	data := []struct {
+		description   string
+		ip            net.IP
+		maxDepth      int
+		children      bool
+		expected      []*protocol.IPNetwork
+		expectedError error
+	}{
+		{
+			description: "it should walk up to the RIR allocation",
+			ip:          net.ParseIP("192.0.2.10"),
+			maxDepth:    DefaultHierarchyDepth,
+			expected:    []*protocol.IPNetwork{root, allocation, assignment},
+		},
+		{
+			description: "it should walk up and down the hierarchy",
+			ip:          net.ParseIP("192.0.2.10"),
+			maxDepth:    DefaultHierarchyDepth,
+			children:    true,
+			expected:    []*protocol.IPNetwork{root, allocation, assignment, child1, child2},
+		},
+		{
+			description: "it should respect the depth limit",
+			ip:          net.ParseIP("192.0.2.10"),
+			maxDepth:    1,
+			expected:    []*protocol.IPNetwork{allocation, assignment},
+		},
+		{
+			description:   "it should fail when the less specific network doesn't match the parent handle",
+			ip:            net.ParseIP("198.51.100.10"),
+			maxDepth:      DefaultHierarchyDepth,
+			expectedError: fmt.Errorf("IP network NET-198-51-0-0 returned as the parent of NET-198-51-100-0 doesn't match the parent handle NET-198-51-100-0-23"),
+		},
+		{
+			description:   "it should fail when the less specific network doesn't cover the network",
+			ip:            net.ParseIP("203.0.113.10"),
+			maxDepth:      DefaultHierarchyDepth,
+			expectedError: fmt.Errorf("IP network NET-203-0-112-0 returned as the parent of NET-203-0-113-0 doesn't cover its address range"),
+		},
+		{
+			description:   "it should fail when the IP is not found",
+			ip:            net.ParseIP("198.51.100.1"),
+			maxDepth:      DefaultHierarchyDepth,
+			expectedError: ErrNotFound,
+		},
+	}
+
+	for i, item := range data {
+		client := Client{
+			URIs:          []string{"https://rdap.rir.example"},
+			Transport:     fetcher,
+			LinkTransport: fetcher,
+		}
+
+		hierarchy, err := client.IPHierarchy(item.ip, item.maxDepth, item.children, nil, nil)
+
+		if item.expectedError != nil {
+			if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+				t.Errorf("[%d] %s: expected error “%s”, got “%s”", i, item.description, item.expectedError, err)
+			}
+
+		} else if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+
+		} else if !reflect.DeepEqual(item.expected, hierarchy) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, hierarchy))
+		}
+	}
+}
+
+func TestIPNetworkRange(t *testing.T) {
+	data := []struct {
+		description string
+		network     *protocol.IPNetwork
+		expected    string
+	}{
+		{
+			description: "it should detect an IPv4 prefix",
+			network:     &protocol.IPNetwork{StartAddress: "200.160.0.0", EndAddress: "200.160.15.255"},
+			expected:    "200.160.0.0/20",
+		},
+		{
+			description: "it should detect the smallest prefix of a range",
+			network:     &protocol.IPNetwork{StartAddress: "192.0.2.10", EndAddress: "192.0.2.20"},
+			expected:    "192.0.2.0/27",
+		},
+		{
+			description: "it should detect an IPv6 prefix",
+			network:     &protocol.IPNetwork{StartAddress: "2001:db8::", EndAddress: "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"},
+			expected:    "2001:db8::/32",
+		},
+		{
+			description: "it should ignore invalid addresses",
+			network:     &protocol.IPNetwork{StartAddress: "abc", EndAddress: "192.0.2.20"},
+		},
+	}
+
+	for i, item := range data {
+		ipnet := ipNetworkRange(item.network)
+
+		var result string
+		if ipnet != nil {
+			result = ipnet.String()
+		}
+
+		if item.expected != result {
+			t.Errorf("[%d] %s: expected “%s” and got “%s”", i, item.description, item.expected, result)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package rdap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+const (
+	// DefaultReferralDepth is a reasonable limit of referrals to follow. In
+	// the common case there's only one referral, from the registry to the
+	// registrar RDAP server
+	DefaultReferralDepth = 3
+)
+
+// linkQueryTypes stores the path segments that can be used to identify the
+// resource type of a link
+var linkQueryTypes = []QueryType{
+	QueryTypeDomain,
+	QueryTypeNameserver,
+	QueryTypeAutnum,
+	QueryTypeIP,
+	QueryTypeEntity,
+	QueryTypeTicket,
+}
+
+// Follow will retrieve the RDAP object referenced by the href, that is usually
+// found in the links of another RDAP object. The object class is detected
+// from the objectClassName member of the response, and a pointer to a
+// protocol Domain, Nameserver, Entity, IPNetwork or AS object is returned.
+// You can optionally define the HTTP headers parameters to send to the RDAP
+// server. The HTTP header of the RDAP response is also returned to analyze any
+// specific flag
+func (c *Client) Follow(href string, header http.Header, queryString url.Values) (interface{}, http.Header, error) {
+	uri, queryType, queryValue, linkQueryString, err := splitLink(href)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for key, values := range queryString {
+		for _, value := range values {
+			linkQueryString.Add(key, value)
+		}
+	}
+
+	transport := c.LinkTransport
+	if transport == nil {
+		transport = NewDefaultFetcher(http.DefaultClient)
+	}
+
+	resp, err := transport.Fetch([]string{uri}, queryType, queryValue, header, linkQueryString)
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+	}()
+
+	if err != nil {
+		if resp != nil {
+			return nil, resp.Header, err
+		}
+		return nil, nil, err
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.Header, err
+	}
+
+	object, err := protocol.DecodeObject(data)
+	if err != nil {
+		return nil, resp.Header, err
+	}
+
+	if conformance, ok := object.(interface {
+		Has(level string) bool
+	}); ok && !conformance.Has(protocol.JSContactConformance) {
+		ignoreJSContact(object)
+	}
+
+	return object, resp.Header, nil
+}
+
+// Referrals will follow the RDAP links of type "related" starting from the
+// given object, like the link from a registry domain response to the
+// registrar domain response. At most maxDepth referrals are followed, and the
+// objects retrieved are returned in the order that they were found. A link
+// that was already visited is not followed again. On error the referrals
+// retrieved so far are also returned
+func (c *Client) Referrals(object interface{}, maxDepth int, header http.Header, queryString url.Values) ([]interface{}, error) {
+	visited := make(map[string]bool)
+	if self, found := protocol.FindLink(protocol.ObjectLinks(object), protocol.LinkRelSelf); found {
+		visited[self.Href] = true
+	}
+
+	var referrals []interface{}
+
+	for depth := 0; depth < maxDepth; depth++ {
+		link, found := referralLink(protocol.ObjectLinks(object), visited)
+		if !found {
+			break
+		}
+		visited[link.Href] = true
+
+		referral, _, err := c.Follow(link.Href, header, queryString)
+		if err != nil {
+			return referrals, err
+		}
+
+		if self, found := protocol.FindLink(protocol.ObjectLinks(referral), protocol.LinkRelSelf); found {
+			visited[self.Href] = true
+		}
+
+		referrals = append(referrals, referral)
+		object = referral
+	}
+
+	return referrals, nil
+}
+
+// referralLink looks for the first related link that points to another RDAP
+// server and that wasn't visited yet
+func referralLink(links []protocol.Link, visited map[string]bool) (protocol.Link, bool) {
+	for _, link := range links {
+		if link.Rel != protocol.LinkRelRelated || link.Href == "" || visited[link.Href] {
+			continue
+		}
+
+		contentType := strings.TrimSpace(strings.Split(link.Type, ";")[0])
+		if contentType == "application/rdap+json" {
+			return link, true
+		}
+	}
+
+	return protocol.Link{}, false
+}
+
+// splitLink breaks a RDAP URL into the RDAP server address, the resource type,
+// the resource identifier and the query string. The resource type is the
+// path segment right before the identifier (or before the prefix length for
+// IP networks)
+func splitLink(href string) (uri string, queryType QueryType, queryValue string, queryString url.Values, err error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", "", "", nil, err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return "", "", "", nil, fmt.Errorf("link %q is not an absolute URL", href)
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	// the identifier uses one segment, except for IP networks that have the
+	// prefix length in an extra segment
+	for size := 1; size <= 2; size++ {
+		i := len(segments) - size - 1
+		if i < 0 {
+			break
+		}
+
+		candidate := QueryType(segments[i])
+		if size == 2 && candidate != QueryTypeIP {
+			continue
+		}
+
+		for _, linkQueryType := range linkQueryTypes {
+			if candidate != linkQueryType {
+				continue
+			}
+
+			uri = u.Scheme + "://" + u.Host
+			if i > 0 {
+				uri += "/" + strings.Join(segments[:i], "/")
+			}
+
+			return uri, candidate, strings.Join(segments[i+1:], "/"), u.Query(), nil
+		}
+	}
+
+	return "", "", "", nil, fmt.Errorf("link %q does not reference a known RDAP resource", href)
+}
@@ -0,0 +1,328 @@
+package rdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestClientFollow(t *testing.T) {
+	data := []struct {
+		description        string
+		href               string
+		queryString        url.Values
+		expectedURIs       []string
+		expectedQueryType  QueryType
+		expectedQueryValue string
+		expectedQuery      url.Values
+		response           interface{}
+		responseError      error
+		expected           interface{}
+		expectedError      error
+	}{
+		{
+			description:        "it should follow a domain link",
+			href:               "https://rdap.registrar.example/rdap/domain/example.com",
+			expectedURIs:       []string{"https://rdap.registrar.example/rdap"},
+			expectedQueryType:  QueryTypeDomain,
+			expectedQueryValue: "example.com",
+			expectedQuery:      url.Values{},
+			response: protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "example.com",
+			},
+			expected: &protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "example.com",
+			},
+		},
+		{
+			description:        "it should follow an IP network link",
+			href:               "https://rdap.rir.example/ip/192.0.2.0/24?full",
+			queryString:        url.Values{"ticket": []string{"1234"}},
+			expectedURIs:       []string{"https://rdap.rir.example"},
+			expectedQueryType:  QueryTypeIP,
+			expectedQueryValue: "192.0.2.0/24",
+			expectedQuery:      url.Values{"full": []string{""}, "ticket": []string{"1234"}},
+			response: protocol.IPNetwork{
+				ObjectClassName: "ip network",
+				Handle:          "NET-192-0-2-0",
+			},
+			expected: &protocol.IPNetwork{
+				ObjectClassName: "ip network",
+				Handle:          "NET-192-0-2-0",
+			},
+		},
+		{
+			description:        "it should follow an entity link",
+			href:               "https://rdap.example/entity/ABC123",
+			expectedURIs:       []string{"https://rdap.example"},
+			expectedQueryType:  QueryTypeEntity,
+			expectedQueryValue: "ABC123",
+			expectedQuery:      url.Values{},
+			response: protocol.Entity{
+				ObjectClassName: "entity",
+				Handle:          "ABC123",
+			},
+			expected: &protocol.Entity{
+				ObjectClassName: "entity",
+				Handle:          "ABC123",
+			},
+		},
+		{
+			description:        "it should ignore the JSContact card without the conformance",
+			href:               "https://rdap.registrar.example/rdap/domain/example.com",
+			expectedURIs:       []string{"https://rdap.registrar.example/rdap"},
+			expectedQueryType:  QueryTypeDomain,
+			expectedQueryValue: "example.com",
+			expectedQuery:      url.Values{},
+			response: protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "example.com",
+				Entities: []protocol.Entity{
+					{
+						ObjectClassName: "entity",
+						Handle:          "XXXX",
+						JSContactCard: &protocol.JSContactCard{
+							Type:    "Card",
+							Version: "1.0",
+							Name:    &protocol.JSContactName{Full: "Joe User"},
+						},
+					},
+				},
+				Conformance: protocol.Conformance{Levels: []string{"rdap_level_0"}},
+			},
+			expected: &protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "example.com",
+				Entities: []protocol.Entity{
+					{
+						ObjectClassName: "entity",
+						Handle:          "XXXX",
+					},
+				},
+				Conformance: protocol.Conformance{Levels: []string{"rdap_level_0"}},
+			},
+		},
+		{
+			description:        "it should keep the JSContact card with the conformance",
+			href:               "https://rdap.example/entity/XXXX",
+			expectedURIs:       []string{"https://rdap.example"},
+			expectedQueryType:  QueryTypeEntity,
+			expectedQueryValue: "XXXX",
+			expectedQuery:      url.Values{},
+			response: protocol.Entity{
+				ObjectClassName: "entity",
+				Handle:          "XXXX",
+				JSContactCard: &protocol.JSContactCard{
+					Type:    "Card",
+					Version: "1.0",
+					Name:    &protocol.JSContactName{Full: "Joe User"},
+				},
+				Conformance: protocol.Conformance{Levels: []string{"rdap_level_0", "jscontact"}},
+			},
+			expected: &protocol.Entity{
+				ObjectClassName: "entity",
+				Handle:          "XXXX",
+				JSContactCard: &protocol.JSContactCard{
+					Type:    "Card",
+					Version: "1.0",
+					Name:    &protocol.JSContactName{Full: "Joe User"},
+				},
+				Conformance: protocol.Conformance{Levels: []string{"rdap_level_0", "jscontact"}},
+			},
+		},
+		{
+			description:   "it should fail for a relative link",
+			href:          "/domain/example.com",
+			expectedError: fmt.Errorf(`link "/domain/example.com" is not an absolute URL`),
+		},
+		{
+			description:   "it should fail for an unknown resource",
+			href:          "https://rdap.example/domains?name=exa*",
+			expectedError: fmt.Errorf(`link "https://rdap.example/domains?name=exa*" does not reference a known RDAP resource`),
+		},
+		{
+			description:        "it should fail for an unknown object class",
+			href:               "https://rdap.example/domain/example.com",
+			expectedURIs:       []string{"https://rdap.example"},
+			expectedQueryType:  QueryTypeDomain,
+			expectedQueryValue: "example.com",
+			expectedQuery:      url.Values{},
+			response:           map[string]string{"objectClassName": "unknown"},
+			expectedError:      fmt.Errorf(`unknown object class "unknown"`),
+		},
+		{
+			description:        "it should fail to fetch the link",
+			href:               "https://rdap.example/domain/example.com",
+			expectedURIs:       []string{"https://rdap.example"},
+			expectedQueryType:  QueryTypeDomain,
+			expectedQueryValue: "example.com",
+			expectedQuery:      url.Values{},
+			responseError:      fmt.Errorf("I'm a crazy error!"),
+			expectedError:      fmt.Errorf("I'm a crazy error!"),
+		},
+	}
+
+	for i, item := range data {
+		client := Client{
+			LinkTransport: fetcherFunc(func(uris []string, queryType QueryType, queryValue string, header http.Header, queryString url.Values) (*http.Response, error) {
+				if !reflect.DeepEqual(item.expectedURIs, uris) {
+					return nil, fmt.Errorf("expected uris “%#v” and got “%#v”", item.expectedURIs, uris)
+				}
+
+				if queryType != item.expectedQueryType {
+					return nil, fmt.Errorf("expected query type “%s” and got “%s”", item.expectedQueryType, queryType)
+				}
+
+				if queryValue != item.expectedQueryValue {
+					return nil, fmt.Errorf("expected query value “%s” and got “%s”", item.expectedQueryValue, queryValue)
+				}
+
+				if !reflect.DeepEqual(item.expectedQuery, queryString) {
+					return nil, fmt.Errorf("expected query string “%#v” and got “%#v”", item.expectedQuery, queryString)
+				}
+
+				if item.responseError != nil {
+					return nil, item.responseError
+				}
+
+				data, err := json.Marshal(item.response)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var response http.Response
+				response.Body = nopCloser{bytes.NewBuffer(data)}
+				return &response, nil
+			}),
+		}
+
+		object, _, err := client.Follow(item.href, nil, item.queryString)
+
+		if item.expectedError != nil {
+			if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+				t.Errorf("[%d] %s: expected error “%s”, got “%s”", i, item.description, item.expectedError, err)
+			}
+
+		} else if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+
+		} else if !reflect.DeepEqual(item.expected, object) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, object))
+		}
+	}
+}
+
+func TestClientReferrals(t *testing.T) {
+	registry := &protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.com",
+		Links: []protocol.Link{
+			{Rel: "self", Href: "https://rdap.registry.example/domain/example.com", Type: "application/rdap+json"},
+			{Rel: "related", Href: "https://rdap.registrar.example/domain/example.com", Type: "application/rdap+json"},
+		},
+	}
+
+	registrar := protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.com",
+		Links: []protocol.Link{
+			{Rel: "self", Href: "https://rdap.registrar.example/domain/example.com", Type: "application/rdap+json"},
+			{Rel: "related", Href: "https://rdap.reseller.example/domain/example.com", Type: "application/rdap+json"},
+		},
+	}
+
+	reseller := protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.com",
+		Links: []protocol.Link{
+			{Rel: "self", Href: "https://rdap.reseller.example/domain/example.com", Type: "application/rdap+json"},
+			{Rel: "related", Href: "https://rdap.registry.example/domain/example.com", Type: "application/rdap+json"},
+			{Rel: "related", Href: "https://www.reseller.example", Type: "text/html"},
+			{Rel: "related", Href: "https://rdap.privacy.example/domain/example.com", Type: "application/rdap+json"},
+		},
+	}
+
+	privacy := protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.com",
+		Links: []protocol.Link{
+			{Rel: "self", Href: "https://rdap.privacy.example/domain/example.com", Type: "application/rdap+json"},
+			{Rel: "related", Href: "https://rdap.registrar.example/domain/example.com", Type: "application/rdap+json"},
+		},
+	}
+
+	servers := map[string]protocol.Domain{
+		"https://rdap.registrar.example": registrar,
+		"https://rdap.reseller.example":  reseller,
+		"https://rdap.privacy.example":   privacy,
+	}
+
+	data := []struct {
+		description   string
+		maxDepth      int
+		expected      []interface{}
+		expectedError error
+	}{
+		{
+			description: "it should follow all referrals, skipping the visited links",
+			maxDepth:    DefaultReferralDepth,
+			expected:    []interface{}{&registrar, &reseller, &privacy},
+		},
+		{
+			description: "it should stop when all referrals were visited",
+			maxDepth:    DefaultReferralDepth + 1,
+			expected:    []interface{}{&registrar, &reseller, &privacy},
+		},
+		{
+			description: "it should respect the depth limit",
+			maxDepth:    1,
+			expected:    []interface{}{&registrar},
+		},
+		{
+			description: "it should not follow any referral",
+			maxDepth:    0,
+		},
+	}
+
+	for i, item := range data {
+		client := Client{
+			LinkTransport: fetcherFunc(func(uris []string, queryType QueryType, queryValue string, header http.Header, queryString url.Values) (*http.Response, error) {
+				domain, ok := servers[uris[0]]
+				if !ok {
+					return nil, fmt.Errorf("unexpected server “%s”", uris[0])
+				}
+
+				data, err := json.Marshal(domain)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var response http.Response
+				response.Body = nopCloser{bytes.NewBuffer(data)}
+				return &response, nil
+			}),
+		}
+
+		referrals, err := client.Referrals(registry, item.maxDepth, nil, nil)
+
+		if item.expectedError != nil {
+			if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+				t.Errorf("[%d] %s: expected error “%s”, got “%s”", i, item.description, item.expectedError, err)
+			}
+
+		} else if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+
+		} else if !reflect.DeepEqual(item.expected, referrals) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, referrals))
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package rdap
+
+import (
+	"strings"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// List of sources of a merged member
+const (
+	// MergeOriginRegistry the member was copied from the registry response
+	MergeOriginRegistry MergeOrigin = "registry"
+
+	// MergeOriginRegistrar the member was copied from the registrar response
+	MergeOriginRegistrar MergeOrigin = "registrar"
+)
+
+// MergeOrigin identifies the response that provided a merged member
+type MergeOrigin string
+
+// MergePolicy defines the precedence rules used when merging a thin registry
+// response with a thick registrar response. Unless listed in the policy, the
+// registry response prevails, and the registrar response is only used to fill
+// the members that are missing in the registry response
+type MergePolicy struct {
+	// RegistrarRoles lists the entity roles where the registrar response
+	// prevails. All entities with the role are taken from the same response
+	RegistrarRoles []protocol.Role
+
+	// RegistrarEvents lists the event actions where the registrar response
+	// prevails
+	RegistrarEvents []protocol.EventAction
+}
+
+// DefaultMergePolicy trusts the registrar for the contacts of the domain, as
+// in thin registries the registrar is the only one that stores them
+var DefaultMergePolicy = MergePolicy{
+	RegistrarRoles: []protocol.Role{
+		protocol.RoleRegistrant,
+		protocol.RoleAdministrative,
+		protocol.RoleTechnical,
+		protocol.RoleBilling,
+		protocol.RoleAbuse,
+		protocol.RoleReseller,
+	},
+}
+
+// MergedDomain is the result of merging a registry and a registrar domain
+// response. Origins maps each merged member to the response that provided it.
+// Simple members are identified by their JSON name (e.g. "ldhName"), and
+// members of lists are identified by the list name and the key used to merge
+// them, like "entities[registrant]", "events[expiration]" or
+// "notices[Terms of Use]"
+type MergedDomain struct {
+	Domain  protocol.Domain
+	Origins map[string]MergeOrigin
+}
+
+// MergeDomains combines the thin registry response with the thick registrar
+// response of the same domain, usually retrieved following the referral
+// link, according to the precedence rules of the policy. Entities are merged
+// by role, events by action, and notices, remarks and links are deduplicated
+func MergeDomains(registry, registrar *protocol.Domain, policy MergePolicy) *MergedDomain {
+	if registry == nil {
+		registry = &protocol.Domain{}
+	}
+
+	if registrar == nil {
+		registrar = &protocol.Domain{}
+	}
+
+	merged := &MergedDomain{
+		Domain:  *registry,
+		Origins: make(map[string]MergeOrigin),
+	}
+
+	d := &merged.Domain
+
+	d.ObjectClassName = mergeString(merged, "objectClassName", registry.ObjectClassName, registrar.ObjectClassName)
+	d.Handle = mergeString(merged, "handle", registry.Handle, registrar.Handle)
+	d.LDHName = mergeString(merged, "ldhName", registry.LDHName, registrar.LDHName)
+	d.UnicodeName = mergeString(merged, "unicodeName", registry.UnicodeName, registrar.UnicodeName)
+	d.Port43.Port43 = mergeString(merged, "port43", registry.Port43.Port43, registrar.Port43.Port43)
+
+	if mergeSource(merged, "nameservers", len(registry.Nameservers) > 0, len(registrar.Nameservers) > 0) == MergeOriginRegistrar {
+		d.Nameservers = registrar.Nameservers
+	}
+
+	if mergeSource(merged, "secureDNS", registry.SecureDNS != nil, registrar.SecureDNS != nil) == MergeOriginRegistrar {
+		d.SecureDNS = registrar.SecureDNS
+	}
+
+	if mergeSource(merged, "status", len(registry.Status) > 0, len(registrar.Status) > 0) == MergeOriginRegistrar {
+		d.Status = registrar.Status
+	}
+
+	if mergeSource(merged, "publicIds", len(registry.PublicIDs) > 0, len(registrar.PublicIDs) > 0) == MergeOriginRegistrar {
+		d.PublicIDs = registrar.PublicIDs
+	}
+
+	if mergeSource(merged, "network", registry.Network != nil, registrar.Network != nil) == MergeOriginRegistrar {
+		d.Network = registrar.Network
+	}
+
+	d.Entities = mergeEntities(merged, registry.Entities, registrar.Entities, policy.RegistrarRoles)
+	d.Events = mergeEvents(merged, registry.Events, registrar.Events, policy.RegistrarEvents)
+	d.Notices = mergeNotices(merged, registry.Notices, registrar.Notices)
+	d.Remarks = mergeRemarks(merged, registry.Remarks, registrar.Remarks)
+	d.Links = mergeLinks(merged, registry.Links, registrar.Links)
+	d.Conformance.Levels = mergeConformance(registry.Conformance.Levels, registrar.Conformance.Levels)
+
+	return merged
+}
+
+// mergeSource detects which response should provide a member, giving
+// precedence to the registry response. The origin is recorded only when the
+// member exists in one of the responses
+func mergeSource(merged *MergedDomain, name string, inRegistry, inRegistrar bool) MergeOrigin {
+	switch {
+	case inRegistry:
+		merged.Origins[name] = MergeOriginRegistry
+		return MergeOriginRegistry
+	case inRegistrar:
+		merged.Origins[name] = MergeOriginRegistrar
+		return MergeOriginRegistrar
+	}
+
+	return ""
+}
+
+func mergeString(merged *MergedDomain, name, registry, registrar string) string {
+	if mergeSource(merged, name, registry != "", registrar != "") == MergeOriginRegistrar {
+		return registrar
+	}
+
+	return registry
+}
+
+func mergeEntities(merged *MergedDomain, registry, registrar []protocol.Entity, registrarRoles []protocol.Role) []protocol.Entity {
+	var roles []protocol.Role
+	rolesFound := make(map[protocol.Role]bool)
+
+	for _, entities := range [][]protocol.Entity{registry, registrar} {
+		for _, entity := range entities {
+			for _, name := range entity.Roles {
+				if role := protocol.Role(name); !rolesFound[role] {
+					rolesFound[role] = true
+					roles = append(roles, role)
+				}
+			}
+		}
+	}
+
+	preferRegistrar := make(map[protocol.Role]bool)
+	for _, role := range registrarRoles {
+		preferRegistrar[role] = true
+	}
+
+	var entities []protocol.Entity
+	selected := make(map[MergeOrigin]map[int]bool)
+	selected[MergeOriginRegistry] = make(map[int]bool)
+	selected[MergeOriginRegistrar] = make(map[int]bool)
+
+	for _, role := range roles {
+		first, firstOrigin := registry, MergeOriginRegistry
+		second, secondOrigin := registrar, MergeOriginRegistrar
+		if preferRegistrar[role] {
+			first, firstOrigin, second, secondOrigin = second, secondOrigin, first, firstOrigin
+		}
+
+		source, origin := first, firstOrigin
+		if !hasRole(first, role) {
+			source, origin = second, secondOrigin
+		}
+
+		merged.Origins["entities["+string(role)+"]"] = origin
+
+		for i, entity := range source {
+			if selected[origin][i] || !entity.HasRole(role) {
+				continue
+			}
+
+			selected[origin][i] = true
+			entities = append(entities, entity)
+		}
+	}
+
+	// entities without roles are kept from the registry response
+	for _, entity := range registry {
+		if len(entity.Roles) == 0 {
+			entities = append(entities, entity)
+		}
+	}
+
+	return entities
+}
+
+func hasRole(entities []protocol.Entity, role protocol.Role) bool {
+	for _, entity := range entities {
+		if entity.HasRole(role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func mergeEvents(merged *MergedDomain, registry, registrar []protocol.Event, registrarActions []protocol.EventAction) []protocol.Event {
+	preferRegistrar := make(map[protocol.EventAction]bool)
+	for _, action := range registrarActions {
+		preferRegistrar[action] = true
+	}
+
+	registrarEvents := make(map[protocol.EventAction]protocol.Event)
+	for _, event := range registrar {
+		if _, found := registrarEvents[event.Action]; !found {
+			registrarEvents[event.Action] = event
+		}
+	}
+
+	var events []protocol.Event
+	added := make(map[protocol.EventAction]bool)
+
+	for _, event := range registry {
+		if added[event.Action] {
+			continue
+		}
+		added[event.Action] = true
+
+		origin := MergeOriginRegistry
+		if registrarEvent, found := registrarEvents[event.Action]; found && preferRegistrar[event.Action] {
+			event, origin = registrarEvent, MergeOriginRegistrar
+		}
+
+		merged.Origins["events["+string(event.Action)+"]"] = origin
+		events = append(events, event)
+	}
+
+	for _, event := range registrar {
+		if added[event.Action] {
+			continue
+		}
+		added[event.Action] = true
+
+		merged.Origins["events["+string(event.Action)+"]"] = MergeOriginRegistrar
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func mergeNotices(merged *MergedDomain, registry, registrar []protocol.Notice) []protocol.Notice {
+	var notices []protocol.Notice
+	added := make(map[string]bool)
+
+	add := func(origin MergeOrigin, list []protocol.Notice) {
+		for _, notice := range list {
+			key := notice.Title + "\n" + strings.Join(notice.Description, "\n")
+			if added[key] {
+				continue
+			}
+			added[key] = true
+
+			if _, found := merged.Origins["notices["+notice.Title+"]"]; !found {
+				merged.Origins["notices["+notice.Title+"]"] = origin
+			}
+			notices = append(notices, notice)
+		}
+	}
+
+	add(MergeOriginRegistry, registry)
+	add(MergeOriginRegistrar, registrar)
+	return notices
+}
+
+func mergeRemarks(merged *MergedDomain, registry, registrar []protocol.Remark) []protocol.Remark {
+	var remarks []protocol.Remark
+	added := make(map[string]bool)
+
+	add := func(origin MergeOrigin, list []protocol.Remark) {
+		for _, remark := range list {
+			key := remark.Title + "\n" + remark.Type + "\n" + strings.Join(remark.Description, "\n")
+			if added[key] {
+				continue
+			}
+			added[key] = true
+
+			if _, found := merged.Origins["remarks["+remark.Title+"]"]; !found {
+				merged.Origins["remarks["+remark.Title+"]"] = origin
+			}
+			remarks = append(remarks, remark)
+		}
+	}
+
+	add(MergeOriginRegistry, registry)
+	add(MergeOriginRegistrar, registrar)
+	return remarks
+}
+
+func mergeLinks(merged *MergedDomain, registry, registrar []protocol.Link) []protocol.Link {
+	var links []protocol.Link
+	added := make(map[string]bool)
+
+	for _, link := range registry {
+		added[link.Href] = true
+		merged.Origins["links["+link.Href+"]"] = MergeOriginRegistry
+		links = append(links, link)
+	}
+
+	// the self link of the consolidated record is the one from the registry,
+	// so the registrar self link becomes a related link. Links back to the
+	// registry response are ignored
+	for _, link := range registrar {
+		if link.Rel == protocol.LinkRelSelf {
+			link.Rel = protocol.LinkRelRelated
+		}
+
+		if added[link.Href] {
+			continue
+		}
+		added[link.Href] = true
+
+		merged.Origins["links["+link.Href+"]"] = MergeOriginRegistrar
+		links = append(links, link)
+	}
+
+	return links
+}
+
+func mergeConformance(registry, registrar []string) []string {
+	var levels []string
+	added := make(map[string]bool)
+
+	for _, list := range [][]string{registry, registrar} {
+		for _, level := range list {
+			if !added[level] {
+				added[level] = true
+				levels = append(levels, level)
+			}
+		}
+	}
+
+	return levels
+}
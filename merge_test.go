@@ -0,0 +1,176 @@
+package rdap
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestMergeDomains(t *testing.T) {
+	registrationDate := protocol.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	expirationDate := protocol.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	registrarExpirationDate := protocol.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastChangedDate := protocol.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	termsOfUse := protocol.Notice{
+		Title:       "Terms of Use",
+		Description: []string{"Service subject to terms of use."},
+	}
+
+	registrarNotice := protocol.Notice{
+		Title:       "Status Codes",
+		Description: []string{"For more information on domain status codes, please visit https://icann.org/epp"},
+	}
+
+	registry := &protocol.Domain{
+		ObjectClassName: "domain",
+		Handle:          "2336799_DOMAIN_COM-VRSN",
+		LDHName:         "EXAMPLE.COM",
+		Status:          protocol.StatusSet{protocol.StatusActive},
+		Entities: []protocol.Entity{
+			{ObjectClassName: "entity", Handle: "292", Roles: []string{"registrar"}},
+			{ObjectClassName: "entity", Handle: "THIN-TECH", Roles: []string{"technical"}},
+		},
+		Events: []protocol.Event{
+			{Action: protocol.EventActionRegistration, Date: registrationDate},
+			{Action: protocol.EventActionExpiration, Date: expirationDate},
+		},
+		Notices: []protocol.Notice{termsOfUse},
+		Links: []protocol.Link{
+			{Rel: "self", Href: "https://rdap.registry.example/domain/EXAMPLE.COM"},
+			{Rel: "related", Href: "https://rdap.registrar.example/domain/EXAMPLE.COM"},
+		},
+		Conformance: protocol.Conformance{Levels: []string{"rdap_level_0"}},
+	}
+
+	registrar := &protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.com",
+		Entities: []protocol.Entity{
+			{ObjectClassName: "entity", Handle: "REG-1", Roles: []string{"registrant"}},
+			{ObjectClassName: "entity", Handle: "TECH-1", Roles: []string{"technical", "administrative"}},
+			{ObjectClassName: "entity", Handle: "REGISTRAR", Roles: []string{"registrar"}},
+		},
+		Events: []protocol.Event{
+			{Action: protocol.EventActionExpiration, Date: registrarExpirationDate},
+			{Action: protocol.EventActionLastChanged, Date: lastChangedDate},
+		},
+		Notices: []protocol.Notice{termsOfUse, registrarNotice},
+		Links: []protocol.Link{
+			{Rel: "self", Href: "https://rdap.registrar.example/domain/EXAMPLE.COM"},
+			{Rel: "about", Href: "https://registrar.example"},
+		},
+		Port43:      protocol.Port43{Port43: "whois.registrar.example"},
+		Conformance: protocol.Conformance{Levels: []string{"rdap_level_0", "icann_rdap_response_profile_0"}},
+	}
+
+	data := []struct {
+		description string
+		registry    *protocol.Domain
+		registrar   *protocol.Domain
+		policy      MergePolicy
+		expected    *MergedDomain
+	}{
+		{
+			description: "it should merge using the default policy",
+			registry:    registry,
+			registrar:   registrar,
+			policy:      DefaultMergePolicy,
+			expected: &MergedDomain{
+				Domain: protocol.Domain{
+					ObjectClassName: "domain",
+					Handle:          "2336799_DOMAIN_COM-VRSN",
+					LDHName:         "EXAMPLE.COM",
+					Status:          protocol.StatusSet{protocol.StatusActive},
+					Entities: []protocol.Entity{
+						{ObjectClassName: "entity", Handle: "292", Roles: []string{"registrar"}},
+						{ObjectClassName: "entity", Handle: "TECH-1", Roles: []string{"technical", "administrative"}},
+						{ObjectClassName: "entity", Handle: "REG-1", Roles: []string{"registrant"}},
+					},
+					Events: []protocol.Event{
+						{Action: protocol.EventActionRegistration, Date: registrationDate},
+						{Action: protocol.EventActionExpiration, Date: expirationDate},
+						{Action: protocol.EventActionLastChanged, Date: lastChangedDate},
+					},
+					Notices: []protocol.Notice{termsOfUse, registrarNotice},
+					Links: []protocol.Link{
+						{Rel: "self", Href: "https://rdap.registry.example/domain/EXAMPLE.COM"},
+						{Rel: "related", Href: "https://rdap.registrar.example/domain/EXAMPLE.COM"},
+						{Rel: "about", Href: "https://registrar.example"},
+					},
+					Port43:      protocol.Port43{Port43: "whois.registrar.example"},
+					Conformance: protocol.Conformance{Levels: []string{"rdap_level_0", "icann_rdap_response_profile_0"}},
+				},
+				Origins: map[string]MergeOrigin{
+					"objectClassName":          MergeOriginRegistry,
+					"handle":                   MergeOriginRegistry,
+					"ldhName":                  MergeOriginRegistry,
+					"port43":                   MergeOriginRegistrar,
+					"status":                   MergeOriginRegistry,
+					"entities[registrar]":      MergeOriginRegistry,
+					"entities[technical]":      MergeOriginRegistrar,
+					"entities[administrative]": MergeOriginRegistrar,
+					"entities[registrant]":     MergeOriginRegistrar,
+					"events[registration]":     MergeOriginRegistry,
+					"events[expiration]":       MergeOriginRegistry,
+					"events[last changed]":     MergeOriginRegistrar,
+					"notices[Terms of Use]":    MergeOriginRegistry,
+					"notices[Status Codes]":    MergeOriginRegistrar,
+					"links[https://rdap.registry.example/domain/EXAMPLE.COM]":  MergeOriginRegistry,
+					"links[https://rdap.registrar.example/domain/EXAMPLE.COM]": MergeOriginRegistry,
+					"links[https://registrar.example]":                         MergeOriginRegistrar,
+				},
+			},
+		},
+		{
+			description: "it should give precedence to the registrar events in the policy",
+			registry: &protocol.Domain{
+				Events: []protocol.Event{
+					{Action: protocol.EventActionExpiration, Date: expirationDate},
+				},
+			},
+			registrar: &protocol.Domain{
+				Events: []protocol.Event{
+					{Action: protocol.EventActionExpiration, Date: registrarExpirationDate},
+				},
+			},
+			policy: MergePolicy{
+				RegistrarEvents: []protocol.EventAction{protocol.EventActionExpiration},
+			},
+			expected: &MergedDomain{
+				Domain: protocol.Domain{
+					Events: []protocol.Event{
+						{Action: protocol.EventActionExpiration, Date: registrarExpirationDate},
+					},
+				},
+				Origins: map[string]MergeOrigin{
+					"events[expiration]": MergeOriginRegistrar,
+				},
+			},
+		},
+		{
+			description: "it should merge without a registrar response",
+			registry: &protocol.Domain{
+				LDHName: "example.com",
+			},
+			expected: &MergedDomain{
+				Domain: protocol.Domain{
+					LDHName: "example.com",
+				},
+				Origins: map[string]MergeOrigin{
+					"ldhName": MergeOriginRegistry,
+				},
+			},
+		},
+	}
+
+	for i, item := range data {
+		merged := MergeDomains(item.registry, item.registrar, item.policy)
+
+		if !reflect.DeepEqual(item.expected, merged) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, merged))
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package protocol
 
+import "encoding/json"
+
 // AS describes the Autonomous System Number Entity Object Class as it is in
-// RFC 7483, section 5.5
+// RFC 9083, section 5.5
 type AS struct {
 	ObjectClassName string          `json:"objectClassName"`
 	Handle          string          `json:"handle"`
@@ -10,14 +12,35 @@ type AS struct {
 	Name            string          `json:"name,omitempty"`
 	Type            string          `json:"type"`
 	Country         string          `json:"country"`
+	Status          StatusSet       `json:"status,omitempty"`
 	Links           []Link          `json:"links,omitempty"`
 	Entities        []Entity        `json:"entities,omitempty"`
 	RoutingPolicy   []RoutingPolicy `json:"nicbr_routingPolicy,omitempty"`
 	Events          []Event         `json:"events,omitempty"`
 	Notices         []Notice        `json:"notices,omitempty"`
 	Remarks         []Remark        `json:"remarks,omitempty"`
+	Redacted        []Redacted      `json:"redacted,omitempty"`
+	Lang            string          `json:"lang,omitempty"`
 	Conformance
 	Port43
+
+	// HandleRemoved informs that the handle was removed by a redaction (RFC
+	// 9537, section 3.1), so the handle member is omitted from the JSON
+	HandleRemoved bool `json:"-"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, omitting the handle
+// member when it was removed by a redaction
+func (a AS) MarshalJSON() ([]byte, error) {
+	type as AS
+	if !a.HandleRemoved {
+		return json.Marshal(as(a))
+	}
+
+	return json.Marshal(struct {
+		as
+		Handle *string `json:"handle,omitempty"`
+	}{as: as(a)})
 }
 
 // RoutingPolicy is a NIC.br extension that stores the information of network
@@ -1,6 +1,16 @@
 package protocol
 
-// Conformance describes the RDAP conformance as it is in RFC 7483, section
+const (
+	// ConformanceLevel0 is the conformance level of the RDAP specification,
+	// as registered in the IANA RDAP Extensions registry
+	ConformanceLevel0 = "rdap_level_0"
+
+	// NICBRConformance is the RDAP extension identifier used by servers that
+	// answer the NIC.br members (prefixed with "nicbr_")
+	NICBRConformance = "nicbr_level_0"
+)
+
+// Conformance describes the RDAP conformance as it is in RFC 9083, section
 // 4.1. The conformance is usually inserted in all responses to identify the
 // extensions that the response includes
 type Conformance struct {
@@ -18,3 +28,14 @@ type ConformanceSetter interface {
 func (l *Conformance) SetConformance(levels []string) {
 	l.Levels = levels
 }
+
+// Has checks if the response declares the conformance level or extension
+func (l Conformance) Has(level string) bool {
+	for _, item := range l.Levels {
+		if item == level {
+			return true
+		}
+	}
+
+	return false
+}
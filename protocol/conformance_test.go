@@ -15,3 +15,15 @@ func TestConformanceSetConformance(t *testing.T) {
 		t.Errorf("Unexpected conformance levels. Expected “%#v” and got “%#v”", expected, c.Levels)
 	}
 }
+
+func TestConformanceHas(t *testing.T) {
+	c := Conformance{Levels: []string{"rdap_level_0", "jscontact"}}
+
+	if !c.Has("jscontact") {
+		t.Error("Expected the jscontact conformance")
+	}
+
+	if c.Has("redacted") {
+		t.Error("Unexpected redacted conformance")
+	}
+}
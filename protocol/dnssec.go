@@ -1,21 +1,73 @@
 package protocol
 
-// DS describes the dsData as it is in RFC 7483, section 5.3
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// DS describes the dsData as it is in RFC 9083, section 5.3
 type DS struct {
 	KeyTag     int     `json:"keyTag"`
 	Algorithm  int     `json:"algorithm"`
 	Digest     string  `json:"digest"`
 	DigestType int     `json:"digestType"`
 	Events     []Event `json:"events,omitempty"`
+	Links      []Link  `json:"links,omitempty"`
+}
+
+// KeyData describes the keyData as it is in RFC 9083, section 5.3. It
+// stores the DNSKEY record of a delegation
+type KeyData struct {
+	Flags     int     `json:"flags"`
+	Protocol  int     `json:"protocol"`
+	PublicKey string  `json:"publicKey"`
+	Algorithm int     `json:"algorithm"`
+	Events    []Event `json:"events,omitempty"`
+	Links     []Link  `json:"links,omitempty"`
+}
+
+// NewKeyData builds the keyData from a DNSKEY record. Spaces are removed from
+// the public key, as they are allowed in the zone file presentation format
+func NewKeyData(dnskey *dns.DNSKEY) KeyData {
+	return KeyData{
+		Flags:     int(dnskey.Flags),
+		Protocol:  int(dnskey.Protocol),
+		PublicKey: strings.Replace(dnskey.PublicKey, " ", "", -1),
+		Algorithm: int(dnskey.Algorithm),
+	}
+}
+
+// DNSKEY converts the keyData to a DNSKEY record with the given owner name
+func (k KeyData) DNSKEY(owner string) *dns.DNSKEY {
+	return &dns.DNSKEY{
+		Hdr: dns.RR_Header{
+			Name:   dns.Fqdn(owner),
+			Rrtype: dns.TypeDNSKEY,
+			Class:  dns.ClassINET,
+		},
+		Flags:     uint16(k.Flags),
+		Protocol:  uint8(k.Protocol),
+		Algorithm: uint8(k.Algorithm),
+		PublicKey: strings.Replace(k.PublicKey, " ", "", -1),
+	}
+}
+
+// KeyTag calculates the key tag of the DNSKEY as described in RFC 4034,
+// appendix B
+func (k KeyData) KeyTag() int {
+	return int(k.DNSKEY(".").KeyTag())
 }
 
-// SecureDNS describes the secureDNS as it is in RFC 7483, section 5.3
+// SecureDNS describes the secureDNS as it is in RFC 9083, section 5.3
 type SecureDNS struct {
 	// ZoneSigned does not make too much sense for us to use
 	// it, so we need to use a pointer to hide it with omitempty. Maybe the
 	// real use for it is for TLDs that publish the DS records
-	// without signing it, but its not clear in RFC 7483, section 5.3
-	ZoneSigned       *bool `json:"zoneSigned,omitempty"`
-	DelegationSigned bool  `json:"delegationSigned"`
-	DSData           []DS  `json:"dsData,omitempty"`
+	// without signing it, but its not clear in RFC 9083, section 5.3
+	ZoneSigned       *bool     `json:"zoneSigned,omitempty"`
+	DelegationSigned bool      `json:"delegationSigned"`
+	MaxSigLife       int       `json:"maxSigLife,omitempty"`
+	DSData           []DS      `json:"dsData,omitempty"`
+	KeyData          []KeyData `json:"keyData,omitempty"`
 }
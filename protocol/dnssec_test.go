@@ -0,0 +1,48 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// DNSKEY example from RFC 4034, section 5.4
+const dnskeyExample = "dskey.example.com. 86400 IN DNSKEY 256 3 5 " +
+	"AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZ " +
+	"DRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9Xzc " +
+	"nOf+EPbtG9DMBmADjFDc2w/rljwvFw=="
+
+func TestKeyData(t *testing.T) {
+	rr, err := dns.NewRR(dnskeyExample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dnskey := rr.(*dns.DNSKEY)
+	keyData := NewKeyData(dnskey)
+
+	expected := KeyData{
+		Flags:     256,
+		Protocol:  3,
+		Algorithm: 5,
+		PublicKey: "AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZ" +
+			"DRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9Xzc" +
+			"nOf+EPbtG9DMBmADjFDc2w/rljwvFw==",
+	}
+
+	if !reflect.DeepEqual(expected, keyData) {
+		t.Errorf("Unexpected key data. Expected “%#v” and got “%#v”", expected, keyData)
+	}
+
+	if keyTag := keyData.KeyTag(); keyTag != 60485 {
+		t.Errorf("Unexpected key tag. Expected “60485” and got “%d”", keyTag)
+	}
+
+	converted := keyData.DNSKEY("dskey.example.com")
+	converted.Hdr.Ttl = 86400
+
+	if converted.String() != dnskey.String() {
+		t.Errorf("Unexpected DNSKEY. Expected “%s” and got “%s”", dnskey, converted)
+	}
+}
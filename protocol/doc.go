@@ -1,3 +1,3 @@
-// Package protocol contains RDAP protocol types defined in RFC 7483 and in
+// Package protocol contains RDAP protocol types defined in RFC 9083 and in
 // NIC.br extension document.
 package protocol
@@ -1,23 +1,102 @@
 package protocol
 
-// Domain describes Domain Object Class as it is in RFC 7483, section 5.3
+// Domain describes Domain Object Class as it is in RFC 9083, section 5.3
 type Domain struct {
 	ObjectClassName string       `json:"objectClassName"`
 	Handle          string       `json:"handle,omitempty"`
 	LDHName         string       `json:"ldhName,omitempty"`
 	UnicodeName     string       `json:"unicodeName,omitempty"`
+	Variants        []Variant    `json:"variants,omitempty"`
 	Nameservers     []Nameserver `json:"nameservers,omitempty"`
 	SecureDNS       *SecureDNS   `json:"secureDNS,omitempty"`
 	Arbitration     bool         `json:"nicbr_arbitration,omitempty"`
 	Links           []Link       `json:"links,omitempty"`
 	Entities        []Entity     `json:"entities,omitempty"`
 	Events          []Event      `json:"events,omitempty"`
-	Status          []Status     `json:"status,omitempty"`
+	Status          StatusSet    `json:"status,omitempty"`
 	PublicIDs       []PublicID   `json:"publicIds,omitempty"`
 	Remarks         []Remark     `json:"remarks,omitempty"`
 	Notices         []Notice     `json:"notices,omitempty"`
 	Network         *IPNetwork   `json:"network,omitempty"`
+	Redacted        []Redacted   `json:"redacted,omitempty"`
+	Lang            string       `json:"lang,omitempty"`
 	Unavailability  string       `json:"-"`
 	Conformance
 	Port43
 }
+
+// List of variant relations registered in the IANA RDAP JSON Values registry
+const (
+	// VariantRelationRegistered the variant names are registered in the
+	// registry
+	VariantRelationRegistered VariantRelation = "registered"
+
+	// VariantRelationUnregistered the variant names are not found in the
+	// registry
+	VariantRelationUnregistered VariantRelation = "unregistered"
+
+	// VariantRelationRegistrationRestricted registration of the variant names
+	// is restricted to certain parties or within certain rules
+	VariantRelationRegistrationRestricted VariantRelation = "registration restricted"
+
+	// VariantRelationOpenRegistration registration of the variant names is
+	// available to generally qualified registrants
+	VariantRelationOpenRegistration VariantRelation = "open registration"
+
+	// VariantRelationConjoined registration of the variant names occurs
+	// automatically with the registration of the containing domain
+	VariantRelationConjoined VariantRelation = "conjoined"
+)
+
+// VariantRelation stores the relation between a domain and its IDN variants
+type VariantRelation string
+
+// Variant describes an IDN variant of the domain as it is in RFC 9083,
+// section 5.3
+type Variant struct {
+	Relation     []VariantRelation `json:"relation,omitempty"`
+	IDNTable     string            `json:"idnTable,omitempty"`
+	VariantNames []VariantName     `json:"variantNames,omitempty"`
+}
+
+// HasRelation checks if the variant has the given relation with the domain
+func (v Variant) HasRelation(relation VariantRelation) bool {
+	for _, r := range v.Relation {
+		if r == relation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// VariantName stores the names of an IDN variant
+type VariantName struct {
+	LDHName     string `json:"ldhName,omitempty"`
+	UnicodeName string `json:"unicodeName,omitempty"`
+}
+
+// VariantNames returns all the variant names of the domain, in the order
+// that they appear in the variants
+func (d Domain) VariantNames() []VariantName {
+	var names []VariantName
+	for _, variant := range d.Variants {
+		names = append(names, variant.VariantNames...)
+	}
+
+	return names
+}
+
+// VariantNamesByRelation returns the variant names of the domain that have
+// the given relation, like the blocked names of an IDN registration
+// (VariantRelationUnregistered or VariantRelationRegistrationRestricted)
+func (d Domain) VariantNamesByRelation(relation VariantRelation) []VariantName {
+	var names []VariantName
+	for _, variant := range d.Variants {
+		if variant.HasRelation(relation) {
+			names = append(names, variant.VariantNames...)
+		}
+	}
+
+	return names
+}
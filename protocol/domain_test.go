@@ -0,0 +1,209 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDomainUnmarshalJSON(t *testing.T) {
+	// domain example based on RFC 9083, section 5.3
+	data := `{
+  "objectClassName" : "domain",
+  "ldhName" : "xn--fo-5ja.example",
+  "lang" : "en",
+  "variants" : [
+    {
+      "relation" : [ "registered", "conjoined" ],
+      "variantNames" : [
+        { "ldhName" : "xn--fo-cka.example", "unicodeName" : "fõo.example" }
+      ]
+    },
+    {
+      "relation" : [ "unregistered", "registration restricted" ],
+      "idnTable": ".EXAMPLE Swedish",
+      "variantNames" : [
+        { "ldhName": "xn--fo-8ja.example", "unicodeName" : "fôo.example" }
+      ]
+    }
+  ],
+  "secureDNS" : {
+    "delegationSigned" : true,
+    "maxSigLife" : 604800,
+    "keyData" : [
+      {
+        "flags" : 257,
+        "protocol" : 3,
+        "algorithm" : 8,
+        "publicKey" : "AwEAAa6eDzronzjEDbT...Jg1M5N rBSPkuXpdFE="
+      }
+    ]
+  },
+  "links" : [
+    {
+      "rel" : "self",
+      "href" : "https://example.net/domain/xn--fo-5ja.example",
+      "hreflang" : [ "en", "ch" ],
+      "title" : "Domain Information",
+      "type" : "application/rdap+json"
+    },
+    {
+      "rel" : "related",
+      "href" : "https://example.com/domain/xn--fo-5ja.example",
+      "hreflang" : "en",
+      "media" : "screen",
+      "type" : "application/rdap+json"
+    }
+  ],
+  "entities" : [
+    {
+      "objectClassName" : "entity",
+      "handle" : "XXXX",
+      "roles" : [ "registrar" ],
+      "status" : [ "validated" ],
+      "asEventActor" : [
+        { "eventAction" : "last changed", "eventDate" : "1990-12-31T23:59:59Z" }
+      ]
+    }
+  ]
+}`
+
+	var domain Domain
+	if err := json.Unmarshal([]byte(data), &domain); err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	expectedVariants := []Variant{
+		{
+			Relation: []VariantRelation{"registered", "conjoined"},
+			VariantNames: []VariantName{
+				{LDHName: "xn--fo-cka.example", UnicodeName: "fõo.example"},
+			},
+		},
+		{
+			Relation: []VariantRelation{"unregistered", "registration restricted"},
+			IDNTable: ".EXAMPLE Swedish",
+			VariantNames: []VariantName{
+				{LDHName: "xn--fo-8ja.example", UnicodeName: "fôo.example"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedVariants, domain.Variants) {
+		t.Errorf("Unexpected variants. Expected “%#v” and got “%#v”", expectedVariants, domain.Variants)
+	}
+
+	expectedSecureDNS := &SecureDNS{
+		DelegationSigned: true,
+		MaxSigLife:       604800,
+		KeyData: []KeyData{
+			{
+				Flags:     257,
+				Protocol:  3,
+				Algorithm: 8,
+				PublicKey: "AwEAAa6eDzronzjEDbT...Jg1M5N rBSPkuXpdFE=",
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedSecureDNS, domain.SecureDNS) {
+		t.Errorf("Unexpected secure DNS. Expected “%#v” and got “%#v”", expectedSecureDNS, domain.SecureDNS)
+	}
+
+	expectedLinks := []Link{
+		{
+			Rel:      "self",
+			Href:     "https://example.net/domain/xn--fo-5ja.example",
+			HrefLang: HrefLang{"en", "ch"},
+			Title:    "Domain Information",
+			Type:     "application/rdap+json",
+		},
+		{
+			Rel:      "related",
+			Href:     "https://example.com/domain/xn--fo-5ja.example",
+			HrefLang: HrefLang{"en"},
+			Media:    "screen",
+			Type:     "application/rdap+json",
+		},
+	}
+
+	if !reflect.DeepEqual(expectedLinks, domain.Links) {
+		t.Errorf("Unexpected links. Expected “%#v” and got “%#v”", expectedLinks, domain.Links)
+	}
+
+	if domain.Lang != "en" {
+		t.Errorf("Unexpected lang. Expected “en” and got “%s”", domain.Lang)
+	}
+
+	if len(domain.Entities) != 1 {
+		t.Fatalf("Unexpected number of entities. Expected 1 and got %d", len(domain.Entities))
+	}
+
+	entity := domain.Entities[0]
+	if !reflect.DeepEqual(StatusSet{StatusValidated}, entity.Status) {
+		t.Errorf("Unexpected entity status. Expected “validated” and got “%v”", entity.Status)
+	}
+
+	if len(entity.AsEventActor) != 1 || entity.AsEventActor[0].Action != EventActionLastChanged {
+		t.Errorf("Unexpected entity asEventActor “%#v”", entity.AsEventActor)
+	}
+}
+
+func TestDomainVariantNames(t *testing.T) {
+	domain := Domain{
+		Variants: []Variant{
+			{
+				Relation: []VariantRelation{VariantRelationRegistered, VariantRelationConjoined},
+				VariantNames: []VariantName{
+					{LDHName: "xn--fo-cka.example", UnicodeName: "fõo.example"},
+				},
+			},
+			{
+				Relation: []VariantRelation{VariantRelationUnregistered, VariantRelationRegistrationRestricted},
+				IDNTable: ".EXAMPLE Swedish",
+				VariantNames: []VariantName{
+					{LDHName: "xn--fo-8ja.example", UnicodeName: "fôo.example"},
+					{LDHName: "xn--fo-fka.example", UnicodeName: "föo.example"},
+				},
+			},
+		},
+	}
+
+	data := []struct {
+		description string
+		relation    VariantRelation
+		expected    []VariantName
+	}{
+		{
+			description: "it should list the registered variant names",
+			relation:    VariantRelationRegistered,
+			expected: []VariantName{
+				{LDHName: "xn--fo-cka.example", UnicodeName: "fõo.example"},
+			},
+		},
+		{
+			description: "it should list the restricted variant names",
+			relation:    VariantRelationRegistrationRestricted,
+			expected: []VariantName{
+				{LDHName: "xn--fo-8ja.example", UnicodeName: "fôo.example"},
+				{LDHName: "xn--fo-fka.example", UnicodeName: "föo.example"},
+			},
+		},
+		{
+			description: "it should list no variant names",
+			relation:    VariantRelationOpenRegistration,
+		},
+	}
+
+	for i, item := range data {
+		names := domain.VariantNamesByRelation(item.relation)
+
+		if !reflect.DeepEqual(item.expected, names) {
+			t.Errorf("[%d] %s: expected “%#v” and got “%#v”", i, item.description, item.expected, names)
+		}
+	}
+
+	if names := domain.VariantNames(); len(names) != 3 {
+		t.Errorf("Unexpected number of variant names. Expected 3 and got %d", len(names))
+	}
+}
@@ -1,6 +1,8 @@
 package protocol
 
-// PublicID describes Public IDs as it is in RFC 7483, section 4.8
+import "encoding/json"
+
+// PublicID describes Public IDs as it is in RFC 9083, section 4.8
 type PublicID struct {
 	Type       string `json:"type"`
 	Identifier string `json:"identifier"`
@@ -14,30 +16,107 @@ type CustomerSupportService struct {
 	Phone   string `json:"nicbr_phone,omitempty"`
 }
 
-// Entity describes the Entity Object Class as it is in RFC 7483, section 5.1
+// List of entity roles registered in the IANA RDAP JSON Values registry. The
+// constants are untyped, so they can be used in the entity roles and as Role
+// values
+const (
+	// RoleRegistrant the entity object instance is the registrant of the
+	// registration
+	RoleRegistrant = "registrant"
+
+	// RoleTechnical the entity object instance is a technical contact for
+	// the registration
+	RoleTechnical = "technical"
+
+	// RoleAdministrative the entity object instance is an administrative
+	// contact for the registration
+	RoleAdministrative = "administrative"
+
+	// RoleAbuse the entity object instance handles network abuse issues on
+	// behalf of the registrant of the registration
+	RoleAbuse = "abuse"
+
+	// RoleBilling the entity object instance handles payment and billing
+	// issues on behalf of the registrant of the registration
+	RoleBilling = "billing"
+
+	// RoleRegistrar the entity object instance represents the authority
+	// responsible for the registration in the registry
+	RoleRegistrar = "registrar"
+
+	// RoleReseller the entity object instance represents a third party
+	// through which the registration was conducted (i.e., not the registry or
+	// registrar)
+	RoleReseller = "reseller"
+
+	// RoleSponsor the entity object instance represents a domain policy
+	// sponsor, such as an ICANN-approved sponsor
+	RoleSponsor = "sponsor"
+
+	// RoleProxy the entity object instance represents a proxy for another
+	// entity object, such as a registrant
+	RoleProxy = "proxy"
+
+	// RoleNotifications an entity object instance designated to receive
+	// notifications about association object instances
+	RoleNotifications = "notifications"
+
+	// RoleNOC the entity object instance handles communications related to a
+	// network operations center (NOC)
+	RoleNOC = "noc"
+)
+
+// Role stores the relationship of an entity with the object that contains
+// it, as described in RFC 9083, section 10.2.4
+type Role string
+
+// Entity describes the Entity Object Class as it is in RFC 9083, section 5.1
 type Entity struct {
 	ObjectClassName        string                  `json:"objectClassName"`
 	Handle                 string                  `json:"handle"`
 	VCardArray             []interface{}           `json:"vcardArray,omitempty"`
+	JSContactCard          *JSContactCard          `json:"jscontact_card,omitempty"`
 	Roles                  []string                `json:"roles,omitempty"`
+	Status                 StatusSet               `json:"status,omitempty"`
 	PublicIds              []PublicID              `json:"publicIds,omitempty"`
 	Networks               []IPNetwork             `json:"networks,omitempty"`
 	Autnums                []AS                    `json:"autnums,omitempty"`
 	CustomerSupportService *CustomerSupportService `json:"nicbr_customerSupportService,omitempty"`
 	Entities               []Entity                `json:"entities,omitempty"`
 	Events                 []Event                 `json:"events,omitempty"`
+	AsEventActor           []Event                 `json:"asEventActor,omitempty"`
 	Links                  []Link                  `json:"links,omitempty"`
 	Remarks                []Remark                `json:"remarks,omitempty"`
 	Notices                []Notice                `json:"notices,omitempty"`
 	DomainCount            int                     `json:"nicbr_domainCount,omitempty"`
 	InetCount              int                     `json:"nicbr_inetCount,omitempty"`
 	AutnumCount            int                     `json:"nicbr_autnumCount,omitempty"`
+	Redacted               []Redacted              `json:"redacted,omitempty"`
+	Lang                   string                  `json:"lang,omitempty"`
 	Conformance
 	Port43
 
 	// LegalRepresentative was proposed by NIC.br to store the name of the
 	// persons that is responsible for this entity
 	LegalRepresentative string `json:"legalRepresentative,omitempty"`
+
+	// HandleRemoved informs that the handle was removed by a redaction (RFC
+	// 9537, section 3.1), so the handle member is omitted from the JSON
+	HandleRemoved bool `json:"-"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, omitting the handle
+// member when it was removed by a redaction
+func (e Entity) MarshalJSON() ([]byte, error) {
+	type entity Entity
+	if !e.HandleRemoved {
+		return json.Marshal(entity(e))
+	}
+
+	return json.Marshal(struct {
+		entity
+		Handle *string `json:"handle,omitempty"`
+	}{entity: entity(e)})
 }
 
 // GetEntity is an easy way to find an entity with a given role. If more than
@@ -55,3 +134,51 @@ func (e *Entity) GetEntity(role string) (entity Entity, found bool) {
 
 	return
 }
+
+// HasRole checks if the entity has the given role
+func (e Entity) HasRole(role Role) bool {
+	for _, r := range e.Roles {
+		if r == string(role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindEntity walks the entities recursively looking for the first entity with
+// the role. The entities of the same level are analyzed before the nested
+// entities, so the closest entity to the object is returned
+func FindEntity(entities []Entity, role Role) (Entity, bool) {
+	for _, entity := range entities {
+		if entity.HasRole(role) {
+			return entity, true
+		}
+	}
+
+	for _, entity := range entities {
+		if nested, found := FindEntity(entity.Entities, role); found {
+			return nested, true
+		}
+	}
+
+	return Entity{}, false
+}
+
+// FindEntities walks the entities recursively returning all entities with the
+// role. The entities of the same level are returned before the nested
+// entities
+func FindEntities(entities []Entity, role Role) []Entity {
+	var found []Entity
+	for _, entity := range entities {
+		if entity.HasRole(role) {
+			found = append(found, entity)
+		}
+	}
+
+	for _, entity := range entities {
+		found = append(found, FindEntities(entity.Entities, role)...)
+	}
+
+	return found
+}
@@ -58,3 +58,56 @@ func TestEntityGetEntity(t *testing.T) {
 	}
 
 }
+
+func TestFindEntity(t *testing.T) {
+	abuse := Entity{Handle: "ABUSE", Roles: []string{RoleAbuse}}
+	nestedAbuse := Entity{Handle: "NESTED-ABUSE", Roles: []string{RoleAbuse, RoleTechnical}}
+	registrar := Entity{Handle: "REGISTRAR", Roles: []string{RoleRegistrar}, Entities: []Entity{nestedAbuse}}
+
+	data := []struct {
+		description      string
+		entities         []Entity
+		role             Role
+		expectedEntity   Entity
+		expectedFound    bool
+		expectedEntities []Entity
+	}{
+		{
+			description:      "it should prefer the entities of the same level",
+			entities:         []Entity{registrar, abuse},
+			role:             RoleAbuse,
+			expectedEntity:   abuse,
+			expectedFound:    true,
+			expectedEntities: []Entity{abuse, nestedAbuse},
+		},
+		{
+			description:      "it should find a nested entity",
+			entities:         []Entity{registrar},
+			role:             RoleTechnical,
+			expectedEntity:   nestedAbuse,
+			expectedFound:    true,
+			expectedEntities: []Entity{nestedAbuse},
+		},
+		{
+			description: "it should not find an entity",
+			entities:    []Entity{registrar, abuse},
+			role:        RoleNOC,
+		},
+	}
+
+	for i, item := range data {
+		entity, found := FindEntity(item.entities, item.role)
+
+		if found != item.expectedFound {
+			t.Errorf("[%d] %s: expected found “%t”", i, item.description, item.expectedFound)
+		}
+
+		if !reflect.DeepEqual(item.expectedEntity, entity) {
+			t.Errorf("[%d] %s: unexpected entity returned. Expected “%s” and got “%s”", i, item.description, item.expectedEntity.Handle, entity.Handle)
+		}
+
+		if entities := FindEntities(item.entities, item.role); !reflect.DeepEqual(item.expectedEntities, entities) {
+			t.Errorf("[%d] %s: unexpected entities returned. Expected “%#v” and got “%#v”", i, item.description, item.expectedEntities, entities)
+		}
+	}
+}
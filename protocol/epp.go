@@ -0,0 +1,449 @@
+package protocol
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// eppResponse is the EPP response envelope (RFC 5730) of an info command. Only
+// the members used to build RDAP objects are decoded
+type eppResponse struct {
+	XMLName xml.Name    `xml:"urn:ietf:params:xml:ns:epp-1.0 epp"`
+	Results []eppResult `xml:"response>result"`
+	ResData struct {
+		Domain  *eppDomainInfData  `xml:"urn:ietf:params:xml:ns:domain-1.0 infData"`
+		Host    *eppHostInfData    `xml:"urn:ietf:params:xml:ns:host-1.0 infData"`
+		Contact *eppContactInfData `xml:"urn:ietf:params:xml:ns:contact-1.0 infData"`
+	} `xml:"response>resData"`
+	Extension struct {
+		SecDNS *eppSecDNSInfData `xml:"urn:ietf:params:xml:ns:secDNS-1.1 infData"`
+	} `xml:"response>extension"`
+}
+
+type eppResult struct {
+	Code int    `xml:"code,attr"`
+	Msg  string `xml:"msg"`
+}
+
+type eppStatus struct {
+	S string `xml:"s,attr"`
+}
+
+// eppObject stores the members shared by all EPP objects
+type eppObject struct {
+	ROID     string      `xml:"roid"`
+	Statuses []eppStatus `xml:"status"`
+	ClID     string      `xml:"clID"`
+	CrID     string      `xml:"crID"`
+	CrDate   EventDate   `xml:"crDate"`
+	UpID     string      `xml:"upID"`
+	UpDate   EventDate   `xml:"upDate"`
+	TrDate   EventDate   `xml:"trDate"`
+}
+
+// eppDomainInfData is the <domain:infData> element of RFC 5731
+type eppDomainInfData struct {
+	eppObject
+	Name       string `xml:"name"`
+	Registrant string `xml:"registrant"`
+	Contacts   []struct {
+		Type string `xml:"type,attr"`
+		ID   string `xml:",chardata"`
+	} `xml:"contact"`
+	HostObjs  []string `xml:"ns>hostObj"`
+	HostAttrs []struct {
+		HostName  string `xml:"hostName"`
+		HostAddrs []struct {
+			IP      string `xml:"ip,attr"`
+			Address string `xml:",chardata"`
+		} `xml:"hostAddr"`
+	} `xml:"ns>hostAttr"`
+	ExDate EventDate `xml:"exDate"`
+}
+
+// eppHostInfData is the <host:infData> element of RFC 5732
+type eppHostInfData struct {
+	eppObject
+	Name  string `xml:"name"`
+	Addrs []struct {
+		IP      string `xml:"ip,attr"`
+		Address string `xml:",chardata"`
+	} `xml:"addr"`
+}
+
+// eppContactInfData is the <contact:infData> element of RFC 5733
+type eppContactInfData struct {
+	eppObject
+	ID         string `xml:"id"`
+	PostalInfo []struct {
+		Type   string   `xml:"type,attr"`
+		Name   string   `xml:"name"`
+		Org    string   `xml:"org"`
+		Street []string `xml:"addr>street"`
+		City   string   `xml:"addr>city"`
+		SP     string   `xml:"addr>sp"`
+		PC     string   `xml:"addr>pc"`
+		CC     string   `xml:"addr>cc"`
+	} `xml:"postalInfo"`
+	Voice eppPhone `xml:"voice"`
+	Fax   eppPhone `xml:"fax"`
+	Email string   `xml:"email"`
+}
+
+type eppPhone struct {
+	Number    string `xml:",chardata"`
+	Extension string `xml:"x,attr"`
+}
+
+func (p eppPhone) tel(types ...string) VCardTel {
+	tel := VCardTel{Number: p.Number, Types: types}
+	if p.Extension != "" {
+		tel.Number += ";ext=" + p.Extension
+	}
+	return tel
+}
+
+// eppSecDNSInfData is the <secDNS:infData> extension of RFC 5910
+type eppSecDNSInfData struct {
+	MaxSigLife int `xml:"maxSigLife"`
+	DSData     []struct {
+		KeyTag     int    `xml:"keyTag"`
+		Alg        int    `xml:"alg"`
+		DigestType int    `xml:"digestType"`
+		Digest     string `xml:"digest"`
+	} `xml:"dsData"`
+	KeyData []eppKeyData `xml:"keyData"`
+}
+
+type eppKeyData struct {
+	Flags    int    `xml:"flags"`
+	Protocol int    `xml:"protocol"`
+	Alg      int    `xml:"alg"`
+	PubKey   string `xml:"pubKey"`
+}
+
+// eppContactRoles maps the EPP contact types to the RDAP roles
+var eppContactRoles = map[string]Role{
+	"admin":   RoleAdministrative,
+	"tech":    RoleTechnical,
+	"billing": RoleBilling,
+}
+
+// DomainFromEPP converts an EPP <domain:info> response (RFC 5731), with the
+// optional DNSSEC extension (RFC 5910), into a RDAP domain. The contacts are
+// returned as entities that only contain the handle and the role, and the
+// sponsoring client is returned as the registrar entity
+func DomainFromEPP(data []byte) (*Domain, error) {
+	response, err := decodeEPP(data)
+	if err != nil {
+		return nil, err
+	}
+
+	info := response.ResData.Domain
+	if info == nil {
+		return nil, fmt.Errorf("EPP response without domain info data")
+	}
+
+	domain := &Domain{
+		ObjectClassName: ObjectClassDomain,
+		Handle:          info.ROID,
+	}
+
+	if domain.LDHName, domain.UnicodeName, err = eppName(info.Name); err != nil {
+		return nil, err
+	}
+
+	if domain.Status, err = info.status(); err != nil {
+		return nil, err
+	}
+
+	if info.Registrant != "" {
+		domain.Entities = append(domain.Entities, Entity{
+			ObjectClassName: ObjectClassEntity,
+			Handle:          info.Registrant,
+			Roles:           []string{RoleRegistrant},
+		})
+	}
+
+	for _, contact := range info.Contacts {
+		role, found := eppContactRoles[contact.Type]
+		if !found {
+			return nil, fmt.Errorf("unknown EPP contact type %q", contact.Type)
+		}
+
+		domain.Entities = append(domain.Entities, Entity{
+			ObjectClassName: ObjectClassEntity,
+			Handle:          strings.TrimSpace(contact.ID),
+			Roles:           []string{string(role)},
+		})
+	}
+
+	if registrar := info.registrar(); registrar != nil {
+		domain.Entities = append(domain.Entities, *registrar)
+	}
+
+	for _, hostObj := range info.HostObjs {
+		nameserver := Nameserver{ObjectClassName: ObjectClassNameserver}
+		if nameserver.LDHName, nameserver.UnicodeName, err = eppName(hostObj); err != nil {
+			return nil, err
+		}
+		domain.Nameservers = append(domain.Nameservers, nameserver)
+	}
+
+	for _, hostAttr := range info.HostAttrs {
+		nameserver := Nameserver{ObjectClassName: ObjectClassNameserver}
+		if nameserver.LDHName, nameserver.UnicodeName, err = eppName(hostAttr.HostName); err != nil {
+			return nil, err
+		}
+
+		for _, addr := range hostAttr.HostAddrs {
+			nameserver.IPAddresses = eppIPAddresses(nameserver.IPAddresses, addr.IP, addr.Address)
+		}
+
+		domain.Nameservers = append(domain.Nameservers, nameserver)
+	}
+
+	domain.Events = info.events()
+	if !info.ExDate.IsZero() {
+		domain.Events = append(domain.Events, Event{
+			Action: EventActionExpiration,
+			Date:   info.ExDate,
+		})
+	}
+
+	if secDNS := response.Extension.SecDNS; secDNS != nil {
+		domain.SecureDNS = &SecureDNS{
+			DelegationSigned: len(secDNS.DSData) > 0 || len(secDNS.KeyData) > 0,
+			MaxSigLife:       secDNS.MaxSigLife,
+		}
+
+		for _, ds := range secDNS.DSData {
+			domain.SecureDNS.DSData = append(domain.SecureDNS.DSData, DS{
+				KeyTag:     ds.KeyTag,
+				Algorithm:  ds.Alg,
+				DigestType: ds.DigestType,
+				Digest:     ds.Digest,
+			})
+		}
+
+		for _, keyData := range secDNS.KeyData {
+			domain.SecureDNS.KeyData = append(domain.SecureDNS.KeyData, KeyData{
+				Flags:     keyData.Flags,
+				Protocol:  keyData.Protocol,
+				Algorithm: keyData.Alg,
+				PublicKey: keyData.PubKey,
+			})
+		}
+	}
+
+	return domain, nil
+}
+
+// NameserverFromEPP converts an EPP <host:info> response (RFC 5732) into a
+// RDAP nameserver
+func NameserverFromEPP(data []byte) (*Nameserver, error) {
+	response, err := decodeEPP(data)
+	if err != nil {
+		return nil, err
+	}
+
+	info := response.ResData.Host
+	if info == nil {
+		return nil, fmt.Errorf("EPP response without host info data")
+	}
+
+	nameserver := &Nameserver{
+		ObjectClassName: ObjectClassNameserver,
+		Handle:          info.ROID,
+		Events:          info.events(),
+	}
+
+	if nameserver.LDHName, nameserver.UnicodeName, err = eppName(info.Name); err != nil {
+		return nil, err
+	}
+
+	if nameserver.Status, err = info.status(); err != nil {
+		return nil, err
+	}
+
+	for _, addr := range info.Addrs {
+		nameserver.IPAddresses = eppIPAddresses(nameserver.IPAddresses, addr.IP, addr.Address)
+	}
+
+	if registrar := info.registrar(); registrar != nil {
+		nameserver.Entities = append(nameserver.Entities, *registrar)
+	}
+
+	return nameserver, nil
+}
+
+// EntityFromEPP converts an EPP <contact:info> response (RFC 5733) into a RDAP
+// entity with a jCard. When the contact has both postal info types the
+// localized one ("loc") is used, as it is the closest to the original data
+func EntityFromEPP(data []byte) (*Entity, error) {
+	response, err := decodeEPP(data)
+	if err != nil {
+		return nil, err
+	}
+
+	info := response.ResData.Contact
+	if info == nil {
+		return nil, fmt.Errorf("EPP response without contact info data")
+	}
+
+	entity := &Entity{
+		ObjectClassName: ObjectClassEntity,
+		Handle:          info.ID,
+		Events:          info.events(),
+	}
+
+	if entity.Status, err = info.status(); err != nil {
+		return nil, err
+	}
+
+	vcard := NewVCard()
+
+	if len(info.PostalInfo) > 0 {
+		postalInfo := info.PostalInfo[0]
+		for _, p := range info.PostalInfo {
+			if p.Type == "loc" {
+				postalInfo = p
+				break
+			}
+		}
+
+		if postalInfo.Org != "" && postalInfo.Name == "" {
+			vcard.AddKind("org")
+			vcard.AddFN(postalInfo.Org)
+		} else {
+			vcard.AddKind("individual")
+			vcard.AddFN(postalInfo.Name)
+		}
+
+		if postalInfo.Org != "" {
+			vcard.AddOrg(postalInfo.Org)
+		}
+
+		vcard.AddAddress(VCardAddress{
+			Street:     postalInfo.Street,
+			Locality:   postalInfo.City,
+			Region:     postalInfo.SP,
+			PostalCode: postalInfo.PC,
+			CC:         postalInfo.CC,
+		})
+	}
+
+	if info.Voice.Number != "" {
+		vcard.AddTel(info.Voice.tel("voice"))
+	}
+
+	if info.Fax.Number != "" {
+		vcard.AddTel(info.Fax.tel("fax"))
+	}
+
+	if info.Email != "" {
+		vcard.AddEmail(VCardEmail{Address: info.Email})
+	}
+
+	entity.SetVCard(vcard)
+
+	if registrar := info.registrar(); registrar != nil {
+		entity.Entities = append(entity.Entities, *registrar)
+	}
+
+	return entity, nil
+}
+
+func decodeEPP(data []byte) (*eppResponse, error) {
+	var response eppResponse
+	if err := xml.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	// EPP result codes starting with 2 are errors (RFC 5730, section 3)
+	for _, result := range response.Results {
+		if result.Code >= 2000 {
+			return nil, fmt.Errorf("EPP error %d: %s", result.Code, strings.TrimSpace(result.Msg))
+		}
+	}
+
+	return &response, nil
+}
+
+func (o eppObject) status() (StatusSet, error) {
+	codes := make([]string, len(o.Statuses))
+	for i, status := range o.Statuses {
+		codes[i] = status.S
+	}
+
+	return NewStatusSetFromEPP(codes)
+}
+
+func (o eppObject) events() []Event {
+	var events []Event
+
+	if !o.CrDate.IsZero() {
+		events = append(events, Event{Action: EventActionRegistration, Actor: o.CrID, Date: o.CrDate})
+	}
+
+	if !o.UpDate.IsZero() {
+		events = append(events, Event{Action: EventActionLastChanged, Actor: o.UpID, Date: o.UpDate})
+	}
+
+	if !o.TrDate.IsZero() {
+		events = append(events, Event{Action: EventActionTransfer, Date: o.TrDate})
+	}
+
+	return events
+}
+
+func (o eppObject) registrar() *Entity {
+	if o.ClID == "" {
+		return nil
+	}
+
+	return &Entity{
+		ObjectClassName: ObjectClassEntity,
+		Handle:          o.ClID,
+		Roles:           []string{RoleRegistrar},
+	}
+}
+
+// eppName returns the LDH and unicode names of an EPP domain or host name.
+// The unicode name is only returned for IDNs
+func eppName(name string) (ldhName, unicodeName string, err error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	if ldhName, err = idna.ToASCII(name); err != nil {
+		return "", "", err
+	}
+
+	if unicodeName, err = idna.ToUnicode(ldhName); err != nil {
+		return "", "", err
+	}
+
+	if unicodeName == ldhName {
+		unicodeName = ""
+	}
+
+	return
+}
+
+func eppIPAddresses(addresses *IPAddresses, version, address string) *IPAddresses {
+	if addresses == nil {
+		addresses = &IPAddresses{}
+	}
+
+	address = strings.TrimSpace(address)
+	if version == "v6" {
+		addresses.V6 = append(addresses.V6, address)
+	} else {
+		// the EPP default IP version is v4
+		addresses.V4 = append(addresses.V4, address)
+	}
+
+	return addresses
+}
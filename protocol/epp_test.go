@@ -0,0 +1,311 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// domain info response based on RFC 5731, section 3.1.2 and RFC 5910,
+// section 5.1.2
+const eppDomainInfo = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <response>
+    <result code="1000">
+      <msg>Command completed successfully</msg>
+    </result>
+    <resData>
+      <domain:infData xmlns:domain="urn:ietf:params:xml:ns:domain-1.0">
+        <domain:name>Example.com</domain:name>
+        <domain:roid>EXAMPLE1-REP</domain:roid>
+        <domain:status s="ok"/>
+        <domain:registrant>jd1234</domain:registrant>
+        <domain:contact type="admin">sh8013</domain:contact>
+        <domain:contact type="tech">sh8013</domain:contact>
+        <domain:ns>
+          <domain:hostObj>ns1.example.com</domain:hostObj>
+          <domain:hostObj>ns1.xn--exmple-cua.net</domain:hostObj>
+        </domain:ns>
+        <domain:host>ns1.example.com</domain:host>
+        <domain:clID>ClientX</domain:clID>
+        <domain:crID>ClientY</domain:crID>
+        <domain:crDate>1999-04-03T22:00:00.0Z</domain:crDate>
+        <domain:upID>ClientX</domain:upID>
+        <domain:upDate>1999-12-03T09:00:00.0Z</domain:upDate>
+        <domain:exDate>2005-04-03T22:00:00.0Z</domain:exDate>
+        <domain:trDate>2000-04-08T09:00:00.0Z</domain:trDate>
+        <domain:authInfo>
+          <domain:pw>2fooBAR</domain:pw>
+        </domain:authInfo>
+      </domain:infData>
+    </resData>
+    <extension>
+      <secDNS:infData xmlns:secDNS="urn:ietf:params:xml:ns:secDNS-1.1">
+        <secDNS:maxSigLife>604800</secDNS:maxSigLife>
+        <secDNS:dsData>
+          <secDNS:keyTag>12345</secDNS:keyTag>
+          <secDNS:alg>3</secDNS:alg>
+          <secDNS:digestType>1</secDNS:digestType>
+          <secDNS:digest>49FD46E6C4B45C55D4AC</secDNS:digest>
+        </secDNS:dsData>
+      </secDNS:infData>
+    </extension>
+    <trID>
+      <clTRID>ABC-12345</clTRID>
+      <svTRID>54322-XYZ</svTRID>
+    </trID>
+  </response>
+</epp>`
+
+// host info response based on RFC 5732, section 3.1.2
+const eppHostInfo = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <response>
+    <result code="1000">
+      <msg>Command completed successfully</msg>
+    </result>
+    <resData>
+      <host:infData xmlns:host="urn:ietf:params:xml:ns:host-1.0">
+        <host:name>ns1.example.com</host:name>
+        <host:roid>NS1_EXAMPLE1-REP</host:roid>
+        <host:status s="linked"/>
+        <host:status s="clientUpdateProhibited"/>
+        <host:addr ip="v4">192.0.2.2</host:addr>
+        <host:addr ip="v4">192.0.2.29</host:addr>
+        <host:addr ip="v6">1080:0:0:0:8:800:200C:417A</host:addr>
+        <host:clID>ClientY</host:clID>
+        <host:crID>ClientX</host:crID>
+        <host:crDate>1999-04-03T22:00:00.0Z</host:crDate>
+      </host:infData>
+    </resData>
+  </response>
+</epp>`
+
+// contact info response based on RFC 5733, section 3.1.2
+const eppContactInfo = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<epp xmlns="urn:ietf:params:xml:ns:epp-1.0">
+  <response>
+    <result code="1000">
+      <msg>Command completed successfully</msg>
+    </result>
+    <resData>
+      <contact:infData xmlns:contact="urn:ietf:params:xml:ns:contact-1.0">
+        <contact:id>sh8013</contact:id>
+        <contact:roid>SH8013-REP</contact:roid>
+        <contact:status s="linked"/>
+        <contact:status s="clientDeleteProhibited"/>
+        <contact:postalInfo type="int">
+          <contact:name>John Doe</contact:name>
+          <contact:org>Example Inc.</contact:org>
+          <contact:addr>
+            <contact:street>123 Example Dr.</contact:street>
+            <contact:street>Suite 100</contact:street>
+            <contact:city>Dulles</contact:city>
+            <contact:sp>VA</contact:sp>
+            <contact:pc>20166-6503</contact:pc>
+            <contact:cc>US</contact:cc>
+          </contact:addr>
+        </contact:postalInfo>
+        <contact:voice x="1234">+1.7035555555</contact:voice>
+        <contact:fax>+1.7035555556</contact:fax>
+        <contact:email>jdoe@example.com</contact:email>
+        <contact:clID>ClientY</contact:clID>
+        <contact:crID>ClientX</contact:crID>
+        <contact:crDate>1999-04-03T22:00:00.0Z</contact:crDate>
+      </contact:infData>
+    </resData>
+  </response>
+</epp>`
+
+func TestDomainFromEPP(t *testing.T) {
+	domain, err := DomainFromEPP([]byte(eppDomainInfo))
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	expected := &Domain{
+		ObjectClassName: "domain",
+		Handle:          "EXAMPLE1-REP",
+		LDHName:         "example.com",
+		Status:          StatusSet{StatusActive},
+		Entities: []Entity{
+			{ObjectClassName: "entity", Handle: "jd1234", Roles: []string{RoleRegistrant}},
+			{ObjectClassName: "entity", Handle: "sh8013", Roles: []string{RoleAdministrative}},
+			{ObjectClassName: "entity", Handle: "sh8013", Roles: []string{RoleTechnical}},
+			{ObjectClassName: "entity", Handle: "ClientX", Roles: []string{RoleRegistrar}},
+		},
+		Nameservers: []Nameserver{
+			{ObjectClassName: "nameserver", LDHName: "ns1.example.com"},
+			{ObjectClassName: "nameserver", LDHName: "ns1.xn--exmple-cua.net", UnicodeName: "ns1.exämple.net"},
+		},
+		Events: []Event{
+			{Action: EventActionRegistration, Actor: "ClientY", Date: NewEventDate(time.Date(1999, 4, 3, 22, 0, 0, 0, time.UTC))},
+			{Action: EventActionLastChanged, Actor: "ClientX", Date: NewEventDate(time.Date(1999, 12, 3, 9, 0, 0, 0, time.UTC))},
+			{Action: EventActionTransfer, Date: NewEventDate(time.Date(2000, 4, 8, 9, 0, 0, 0, time.UTC))},
+			{Action: EventActionExpiration, Date: NewEventDate(time.Date(2005, 4, 3, 22, 0, 0, 0, time.UTC))},
+		},
+		SecureDNS: &SecureDNS{
+			DelegationSigned: true,
+			MaxSigLife:       604800,
+			DSData: []DS{
+				{KeyTag: 12345, Algorithm: 3, DigestType: 1, Digest: "49FD46E6C4B45C55D4AC"},
+			},
+		},
+	}
+
+	if !equalJSON(t, expected, domain) {
+		t.Errorf("Unexpected domain. Expected “%#v” and got “%#v”", expected, domain)
+	}
+}
+
+func TestNameserverFromEPP(t *testing.T) {
+	nameserver, err := NameserverFromEPP([]byte(eppHostInfo))
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	expected := &Nameserver{
+		ObjectClassName: "nameserver",
+		Handle:          "NS1_EXAMPLE1-REP",
+		LDHName:         "ns1.example.com",
+		Status:          StatusSet{StatusAssociated, StatusClientUpdateProhibited},
+		IPAddresses: &IPAddresses{
+			V4: []string{"192.0.2.2", "192.0.2.29"},
+			V6: []string{"1080:0:0:0:8:800:200C:417A"},
+		},
+		Entities: []Entity{
+			{ObjectClassName: "entity", Handle: "ClientY", Roles: []string{RoleRegistrar}},
+		},
+		Events: []Event{
+			{Action: EventActionRegistration, Actor: "ClientX", Date: NewEventDate(time.Date(1999, 4, 3, 22, 0, 0, 0, time.UTC))},
+		},
+	}
+
+	if !equalJSON(t, expected, nameserver) {
+		t.Errorf("Unexpected nameserver. Expected “%#v” and got “%#v”", expected, nameserver)
+	}
+}
+
+func TestEntityFromEPP(t *testing.T) {
+	entity, err := EntityFromEPP([]byte(eppContactInfo))
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	if entity.Handle != "sh8013" {
+		t.Errorf("Unexpected handle. Expected “sh8013” and got “%s”", entity.Handle)
+	}
+
+	if expected := (StatusSet{StatusAssociated, StatusClientDeleteProhibited}); !reflect.DeepEqual(expected, entity.Status) {
+		t.Errorf("Unexpected status. Expected “%#v” and got “%#v”", expected, entity.Status)
+	}
+
+	vcard, err := entity.VCard()
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	if fn := vcard.FN(); fn != "John Doe" {
+		t.Errorf("Unexpected fn. Expected “John Doe” and got “%s”", fn)
+	}
+
+	if org := vcard.Org(); org != "Example Inc." {
+		t.Errorf("Unexpected org. Expected “Example Inc.” and got “%s”", org)
+	}
+
+	expectedTels := []VCardTel{
+		{Number: "+1.7035555555;ext=1234", Types: []string{"voice"}},
+		{Number: "+1.7035555556", Types: []string{"fax"}},
+	}
+
+	if tels := vcard.Tels(); !reflect.DeepEqual(expectedTels, tels) {
+		t.Errorf("Unexpected tels. Expected “%#v” and got “%#v”", expectedTels, tels)
+	}
+
+	expectedAddresses := []VCardAddress{
+		{
+			Street:     []string{"123 Example Dr.", "Suite 100"},
+			Locality:   "Dulles",
+			Region:     "VA",
+			PostalCode: "20166-6503",
+			CC:         "US",
+		},
+	}
+
+	if addresses := vcard.Addresses(); !reflect.DeepEqual(expectedAddresses, addresses) {
+		t.Errorf("Unexpected addresses. Expected “%#v” and got “%#v”", expectedAddresses, addresses)
+	}
+
+	if emails := vcard.Emails(); len(emails) != 1 || emails[0].Address != "jdoe@example.com" {
+		t.Errorf("Unexpected emails “%#v”", emails)
+	}
+}
+
+func TestEPPErrors(t *testing.T) {
+	data := []struct {
+		description   string
+		convert       func([]byte) error
+		data          string
+		expectedError error
+	}{
+		{
+			description: "it should detect an EPP error result",
+			convert: func(data []byte) error {
+				_, err := DomainFromEPP(data)
+				return err
+			},
+			data: `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><response>` +
+				`<result code="2303"><msg>Object does not exist</msg></result>` +
+				`</response></epp>`,
+			expectedError: fmt.Errorf("EPP error 2303: Object does not exist"),
+		},
+		{
+			description: "it should detect a response of another object",
+			convert: func(data []byte) error {
+				_, err := EntityFromEPP(data)
+				return err
+			},
+			data:          eppHostInfo,
+			expectedError: fmt.Errorf("EPP response without contact info data"),
+		},
+		{
+			description: "it should detect an unknown status",
+			convert: func(data []byte) error {
+				_, err := NameserverFromEPP(data)
+				return err
+			},
+			data: `<epp xmlns="urn:ietf:params:xml:ns:epp-1.0"><response>` +
+				`<result code="1000"/><resData>` +
+				`<host:infData xmlns:host="urn:ietf:params:xml:ns:host-1.0">` +
+				`<host:name>ns1.example.com</host:name><host:status s="clientFrozen"/>` +
+				`</host:infData></resData></response></epp>`,
+			expectedError: fmt.Errorf(`unknown EPP status "clientFrozen"`),
+		},
+	}
+
+	for i, item := range data {
+		err := item.convert([]byte(item.data))
+
+		if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+			t.Errorf("[%d] %s: expected error “%s”, got “%s”", i, item.description, item.expectedError, err)
+		}
+	}
+}
+
+// equalJSON compares the objects using their JSON representation, so time
+// locations don't affect the result
+func equalJSON(t *testing.T, expected, result interface{}) bool {
+	expectedData, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resultData, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(expectedData) == string(resultData)
+}
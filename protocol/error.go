@@ -6,7 +6,7 @@ import (
 	"strings"
 )
 
-// Error describes an Error Response Body as it is in RFC 7483, section 6
+// Error describes an Error Response Body as it is in RFC 9083, section 6
 type Error struct {
 	Notices     []Notice `json:"notices,omitempty"`
 	Lang        string   `json:"lang,omitempty"`
@@ -3,10 +3,10 @@ package protocol
 import "time"
 
 // EventAction can store all different types of event actions described in
-// RFC 7483, section 10.2.3
+// RFC 9083, section 10.2.3
 type EventAction string
 
-// https://tools.ietf.org/html/rfc7483#section-10.2.3
+// https://tools.ietf.org/html/rfc9083#section-10.2.3
 const (
 	// EventActionRegistration the object instance was initially registered
 	EventActionRegistration EventAction = "registration"
@@ -23,6 +23,23 @@ const (
 	// service was updated from the Registry or Registrar database
 	EventActionLastUpdate EventAction = "last update"
 
+	// EventActionLastUpdateOfRDAPDatabase last date and time the database
+	// used by the RDAP service was updated from the Registry or Registrar
+	// database
+	EventActionLastUpdateOfRDAPDatabase EventAction = "last update of RDAP database"
+
+	// EventActionReinstantiation the object instance was reregistered after
+	// having been removed from the registry
+	EventActionReinstantiation EventAction = "reinstantiation"
+
+	// EventActionRegistrarExpiration the object instance will be removed at a
+	// predetermined date and time from the registrar
+	EventActionRegistrarExpiration EventAction = "registrar expiration"
+
+	// EventActionEnumValidationExpiration the E.164 number validation of the
+	// object instance will expire
+	EventActionEnumValidationExpiration EventAction = "enum validation expiration"
+
 	// EventActionExpiration the object instance has been removed or will be
 	// removed at a predetermined date and time from the registry
 	EventActionExpiration EventAction = "expiration"
@@ -61,11 +78,12 @@ const (
 	EventLastCorrectDelegationSignCheck EventAction = "last correct delegation sign check"
 )
 
-// Event describes Events as it is in RFC 7483, section 4.5
+// Event describes Events as it is in RFC 9083, section 4.5
 type Event struct {
 	Action EventAction `json:"eventAction"`
 	Actor  string      `json:"eventActor,omitempty"`
 	Date   EventDate   `json:"eventDate"`
+	Links  []Link      `json:"links,omitempty"`
 
 	// Status was proposed by NIC.br to store the status of a current event.
 	// For NIC.br specific use was useful to store the status of a delegation
@@ -1,6 +1,6 @@
 package protocol
 
-// Help describes an answer to help queries as it is in RFC 7483, section 7
+// Help describes an answer to help queries as it is in RFC 9083, section 7
 type Help struct {
 	Notices []Notice `json:"notices,omitempty"`
 	Conformance
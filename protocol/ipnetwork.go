@@ -1,6 +1,8 @@
 package protocol
 
-// IPNetwork describes the IP Network Object Class as it is in RFC 7483,
+import "encoding/json"
+
+// IPNetwork describes the IP Network Object Class as it is in RFC 9083,
 // section 5.4
 type IPNetwork struct {
 	ObjectClassName    string              `json:"objectClassName"`
@@ -12,7 +14,7 @@ type IPNetwork struct {
 	Type               string              `json:"type"`
 	Country            string              `json:"country"`
 	ParentHandle       string              `json:"parentHandle,omitempty"`
-	Status             []string            `json:"status"`
+	Status             StatusSet           `json:"status"`
 	Autnum             uint32              `json:"nicbr_autnum,omitempty"`
 	Links              []Link              `json:"links"`
 	Events             []Event             `json:"events"`
@@ -20,8 +22,28 @@ type IPNetwork struct {
 	Notices            []Notice            `json:"notices,omitempty"`
 	Remarks            []Remark            `json:"remarks,omitempty"`
 	ReverseDelegations []ReverseDelegation `json:"nicbr_reverseDelegations,omitempty"`
+	Redacted           []Redacted          `json:"redacted,omitempty"`
+	Lang               string              `json:"lang,omitempty"`
 	Conformance
 	Port43
+
+	// HandleRemoved informs that the handle was removed by a redaction (RFC
+	// 9537, section 3.1), so the handle member is omitted from the JSON
+	HandleRemoved bool `json:"-"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, omitting the handle
+// member when it was removed by a redaction
+func (i IPNetwork) MarshalJSON() ([]byte, error) {
+	type ipNetwork IPNetwork
+	if !i.HandleRemoved {
+		return json.Marshal(ipNetwork(i))
+	}
+
+	return json.Marshal(struct {
+		ipNetwork
+		Handle *string `json:"handle,omitempty"`
+	}{ipNetwork: ipNetwork(i)})
 }
 
 // ReverseDelegation is a NIC.br extension to list all the IP network
@@ -0,0 +1,553 @@
+package protocol
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// JSContactConformance is the RDAP extension identifier used by servers
+	// that return the entity contact information in the JSContact format
+	JSContactConformance = "jscontact"
+)
+
+// JSContactCard describes a contact card in the JSContact format as it is in
+// RFC 9553. Only the properties with a direct vCard counterpart are modeled.
+// The vCard properties that could not be converted are stored in VCardProps,
+// as described in RFC 9555, section 2.15.1, so the conversion between jCard
+// and JSContact loses as little information as possible
+type JSContactCard struct {
+	Type               string                           `json:"@type"`
+	Version            string                           `json:"version"`
+	UID                string                           `json:"uid,omitempty"`
+	Kind               string                           `json:"kind,omitempty"`
+	Language           string                           `json:"language,omitempty"`
+	Name               *JSContactName                   `json:"name,omitempty"`
+	Organizations      map[string]JSContactOrganization `json:"organizations,omitempty"`
+	Titles             map[string]JSContactTitle        `json:"titles,omitempty"`
+	Emails             map[string]JSContactEmail        `json:"emails,omitempty"`
+	Phones             map[string]JSContactPhone        `json:"phones,omitempty"`
+	Addresses          map[string]JSContactAddress      `json:"addresses,omitempty"`
+	Links              map[string]JSContactLink         `json:"links,omitempty"`
+	PreferredLanguages map[string]JSContactLanguagePref `json:"preferredLanguages,omitempty"`
+	VCardProps         []interface{}                    `json:"vCardProps,omitempty"`
+}
+
+// JSContactName describes the name of the contact as it is in RFC 9553,
+// section 2.2.1
+type JSContactName struct {
+	Full       string                   `json:"full,omitempty"`
+	Components []JSContactNameComponent `json:"components,omitempty"`
+}
+
+// JSContactNameComponent describes a part of the name, like the given name or
+// the surname
+type JSContactNameComponent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// JSContactOrganization describes an organization as it is in RFC 9553,
+// section 2.2.3
+type JSContactOrganization struct {
+	Name string `json:"name,omitempty"`
+}
+
+// JSContactTitle describes a job title or role as it is in RFC 9553,
+// section 2.2.5
+type JSContactTitle struct {
+	Name string `json:"name"`
+	Kind string `json:"kind,omitempty"`
+}
+
+// JSContactEmail describes an email address as it is in RFC 9553, section
+// 2.3.1
+type JSContactEmail struct {
+	Address  string          `json:"address"`
+	Contexts map[string]bool `json:"contexts,omitempty"`
+	Pref     int             `json:"pref,omitempty"`
+}
+
+// JSContactPhone describes a phone number as it is in RFC 9553, section
+// 2.3.3
+type JSContactPhone struct {
+	Number   string          `json:"number"`
+	Features map[string]bool `json:"features,omitempty"`
+	Contexts map[string]bool `json:"contexts,omitempty"`
+	Pref     int             `json:"pref,omitempty"`
+}
+
+// JSContactAddress describes a postal address as it is in RFC 9553, section
+// 2.5.1
+type JSContactAddress struct {
+	Components  []JSContactAddressComponent `json:"components,omitempty"`
+	CountryCode string                      `json:"countryCode,omitempty"`
+	Full        string                      `json:"full,omitempty"`
+	Contexts    map[string]bool             `json:"contexts,omitempty"`
+	Pref        int                         `json:"pref,omitempty"`
+}
+
+// JSContactAddressComponent describes a part of the address, like the
+// locality or the postal code
+type JSContactAddressComponent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// JSContactLink describes a link to a resource related to the contact as it
+// is in RFC 9553, section 2.6.3
+type JSContactLink struct {
+	URI      string          `json:"uri"`
+	Contexts map[string]bool `json:"contexts,omitempty"`
+	Pref     int             `json:"pref,omitempty"`
+}
+
+// JSContactLanguagePref describes a preferred language for contacting the
+// entity as it is in RFC 9553, section 2.3.4
+type JSContactLanguagePref struct {
+	Language string          `json:"language"`
+	Contexts map[string]bool `json:"contexts,omitempty"`
+	Pref     int             `json:"pref,omitempty"`
+}
+
+// vcardNameComponents maps the components of the vCard n structured value to
+// the JSContact name component kinds (RFC 9555, section 2.2.2)
+var vcardNameComponents = []string{"surname", "given", "given2", "title", "credential"}
+
+// vcardAddressComponents maps the components of the vCard adr structured
+// value to the JSContact address component kinds (RFC 9555, section 2.3.1)
+var vcardAddressComponents = []string{"postOfficeBox", "apartment", "name", "locality", "region", "postcode", "country"}
+
+// vcardPhoneFeatures maps the vCard tel type values to the JSContact phone
+// features
+var vcardPhoneFeatures = map[string]string{
+	"voice":     "voice",
+	"fax":       "fax",
+	"cell":      "mobile",
+	"video":     "video",
+	"text":      "text",
+	"textphone": "textphone",
+	"pager":     "pager",
+}
+
+// VCardToJSContact converts a vCard into a JSContact card. The properties
+// without a JSContact counterpart are kept in the vCardProps member
+func VCardToJSContact(vcard *VCard) *JSContactCard {
+	card := JSContactCard{
+		Type:    "Card",
+		Version: "1.0",
+	}
+
+	if vcard == nil {
+		return &card
+	}
+
+	ids := make(map[string]int)
+	nextID := func(prefix string) string {
+		ids[prefix]++
+		return fmt.Sprintf("%s-%d", prefix, ids[prefix])
+	}
+
+	for _, property := range vcard.Properties {
+		switch strings.ToLower(property.Name) {
+		case VCardPropertyVersion:
+			// the JSContact version is not related to the vCard version
+
+		case VCardPropertyKind:
+			card.Kind = strings.ToLower(property.Text())
+
+		case VCardPropertyFN:
+			if card.Name == nil {
+				card.Name = &JSContactName{}
+			}
+			card.Name.Full = property.Text()
+
+		case VCardPropertyN:
+			if card.Name == nil {
+				card.Name = &JSContactName{}
+			}
+
+			var components []interface{}
+			if len(property.Values) > 0 {
+				components, _ = property.Values[0].([]interface{})
+			}
+
+			for i, component := range components {
+				if i >= len(vcardNameComponents) {
+					break
+				}
+
+				for _, value := range vcardStrings(component) {
+					card.Name.Components = append(card.Name.Components, JSContactNameComponent{
+						Kind:  vcardNameComponents[i],
+						Value: value,
+					})
+				}
+			}
+
+		case VCardPropertyOrg:
+			if card.Organizations == nil {
+				card.Organizations = make(map[string]JSContactOrganization)
+			}
+			card.Organizations[nextID("org")] = JSContactOrganization{Name: property.Text()}
+
+		case VCardPropertyTitle, VCardPropertyRole:
+			if card.Titles == nil {
+				card.Titles = make(map[string]JSContactTitle)
+			}
+			card.Titles[nextID("title")] = JSContactTitle{Name: property.Text(), Kind: strings.ToLower(property.Name)}
+
+		case VCardPropertyEmail:
+			if card.Emails == nil {
+				card.Emails = make(map[string]JSContactEmail)
+			}
+			card.Emails[nextID("email")] = JSContactEmail{
+				Address:  strings.TrimPrefix(property.Text(), "mailto:"),
+				Contexts: jsContactContexts(property.Types()),
+				Pref:     property.Pref(),
+			}
+
+		case VCardPropertyTel:
+			if card.Phones == nil {
+				card.Phones = make(map[string]JSContactPhone)
+			}
+
+			var features map[string]bool
+			for _, t := range property.Types() {
+				if feature, ok := vcardPhoneFeatures[strings.ToLower(t)]; ok {
+					if features == nil {
+						features = make(map[string]bool)
+					}
+					features[feature] = true
+				}
+			}
+
+			card.Phones[nextID("phone")] = JSContactPhone{
+				Number:   property.Text(),
+				Features: features,
+				Contexts: jsContactContexts(property.Types()),
+				Pref:     property.Pref(),
+			}
+
+		case VCardPropertyAdr:
+			if card.Addresses == nil {
+				card.Addresses = make(map[string]JSContactAddress)
+			}
+
+			address := JSContactAddress{
+				CountryCode: property.Parameter(VCardParameterCC),
+				Full:        property.Parameter(VCardParameterLabel),
+				Contexts:    jsContactContexts(property.Types()),
+				Pref:        property.Pref(),
+			}
+
+			var components []interface{}
+			if len(property.Values) > 0 {
+				components, _ = property.Values[0].([]interface{})
+			}
+
+			for i, component := range components {
+				if i >= len(vcardAddressComponents) {
+					break
+				}
+
+				for _, value := range vcardStrings(component) {
+					address.Components = append(address.Components, JSContactAddressComponent{
+						Kind:  vcardAddressComponents[i],
+						Value: value,
+					})
+				}
+			}
+
+			card.Addresses[nextID("address")] = address
+
+		case VCardPropertyURL:
+			if card.Links == nil {
+				card.Links = make(map[string]JSContactLink)
+			}
+			card.Links[nextID("link")] = JSContactLink{
+				URI:      property.Text(),
+				Contexts: jsContactContexts(property.Types()),
+				Pref:     property.Pref(),
+			}
+
+		case VCardPropertyLang:
+			if card.PreferredLanguages == nil {
+				card.PreferredLanguages = make(map[string]JSContactLanguagePref)
+			}
+			card.PreferredLanguages[nextID("lang")] = JSContactLanguagePref{
+				Language: property.Text(),
+				Contexts: jsContactContexts(property.Types()),
+				Pref:     property.Pref(),
+			}
+
+		default:
+			card.VCardProps = append(card.VCardProps, property.array())
+		}
+	}
+
+	return &card
+}
+
+// JSContactToVCard converts a JSContact card into a vCard. The properties
+// stored in the vCardProps member are restored. Map based members are
+// converted in the order of their identifiers
+func JSContactToVCard(card *JSContactCard) (*VCard, error) {
+	vcard := NewVCard()
+	if card == nil {
+		return vcard, nil
+	}
+
+	if card.Kind != "" {
+		vcard.AddKind(card.Kind)
+	}
+
+	if card.Name != nil {
+		if card.Name.Full != "" {
+			vcard.AddFN(card.Name.Full)
+		}
+
+		if len(card.Name.Components) > 0 {
+			components := make([][]string, len(vcardNameComponents))
+			for _, component := range card.Name.Components {
+				for i, kind := range vcardNameComponents {
+					if component.Kind == kind {
+						components[i] = append(components[i], component.Value)
+					}
+				}
+			}
+
+			vcard.Add(VCardProperty{
+				Name:   VCardPropertyN,
+				Type:   "text",
+				Values: []interface{}{vcardStructuredValue(components)},
+			})
+		}
+	}
+
+	for _, id := range sortedKeys(card.Organizations) {
+		vcard.AddOrg(card.Organizations[id].Name)
+	}
+
+	for _, id := range sortedKeys(card.Titles) {
+		title := card.Titles[id]
+		name := VCardPropertyTitle
+		if title.Kind == VCardPropertyRole {
+			name = VCardPropertyRole
+		}
+		vcard.AddText(name, title.Name)
+	}
+
+	for _, id := range sortedKeys(card.Emails) {
+		email := card.Emails[id]
+		vcard.AddEmail(VCardEmail{
+			Address: email.Address,
+			Types:   vcardTypes(email.Contexts, nil),
+			Pref:    email.Pref,
+		})
+	}
+
+	for _, id := range sortedKeys(card.Phones) {
+		phone := card.Phones[id]
+
+		var features []string
+		for _, feature := range sortedKeys(phone.Features) {
+			for vcardType, f := range vcardPhoneFeatures {
+				if f == feature && phone.Features[feature] {
+					features = append(features, vcardType)
+				}
+			}
+		}
+
+		vcard.AddTel(VCardTel{
+			Number: phone.Number,
+			Types:  vcardTypes(phone.Contexts, features),
+			Pref:   phone.Pref,
+		})
+	}
+
+	for _, id := range sortedKeys(card.Addresses) {
+		address := card.Addresses[id]
+
+		components := make([][]string, len(vcardAddressComponents))
+		for _, component := range address.Components {
+			for i, kind := range vcardAddressComponents {
+				if component.Kind == kind {
+					components[i] = append(components[i], component.Value)
+				}
+			}
+		}
+
+		vcard.AddAddress(VCardAddress{
+			POBox:      strings.Join(components[0], ","),
+			Extended:   strings.Join(components[1], ","),
+			Street:     components[2],
+			Locality:   strings.Join(components[3], ","),
+			Region:     strings.Join(components[4], ","),
+			PostalCode: strings.Join(components[5], ","),
+			Country:    strings.Join(components[6], ","),
+			Label:      address.Full,
+			CC:         address.CountryCode,
+			Types:      vcardTypes(address.Contexts, nil),
+			Pref:       address.Pref,
+		})
+	}
+
+	for _, id := range sortedKeys(card.Links) {
+		link := card.Links[id]
+		vcard.Add(VCardProperty{
+			Name:       VCardPropertyURL,
+			Parameters: vcardParameters(vcardTypes(link.Contexts, nil), link.Pref),
+			Type:       "uri",
+			Values:     []interface{}{link.URI},
+		})
+	}
+
+	for _, id := range sortedKeys(card.PreferredLanguages) {
+		lang := card.PreferredLanguages[id]
+		vcard.Add(VCardProperty{
+			Name:       VCardPropertyLang,
+			Parameters: vcardParameters(vcardTypes(lang.Contexts, nil), lang.Pref),
+			Type:       "language-tag",
+			Values:     []interface{}{lang.Language},
+		})
+	}
+
+	if len(card.VCardProps) > 0 {
+		extra, err := ParseVCard([]interface{}{"vcard", card.VCardProps})
+		if err != nil {
+			return nil, err
+		}
+
+		vcard.Properties = append(vcard.Properties, extra.Properties...)
+	}
+
+	return vcard, nil
+}
+
+// JSContact returns the contact information of the entity in the JSContact
+// format. When the server didn't send the JSContact card, it is converted
+// from the vCard. If the entity has no contact information, nil is returned
+// without error
+func (e *Entity) JSContact() (*JSContactCard, error) {
+	if e.JSContactCard != nil {
+		return e.JSContactCard, nil
+	}
+
+	vcard, err := e.VCard()
+	if err != nil || vcard == nil {
+		return nil, err
+	}
+
+	return VCardToJSContact(vcard), nil
+}
+
+// jsContactContexts converts the vCard type parameter values into JSContact
+// contexts
+func jsContactContexts(types []string) map[string]bool {
+	var contexts map[string]bool
+	for _, t := range types {
+		var context string
+		switch strings.ToLower(t) {
+		case "work":
+			context = "work"
+		case "home":
+			context = "private"
+		default:
+			continue
+		}
+
+		if contexts == nil {
+			contexts = make(map[string]bool)
+		}
+		contexts[context] = true
+	}
+
+	return contexts
+}
+
+// vcardTypes converts JSContact contexts into vCard type parameter values,
+// followed by the extra types
+func vcardTypes(contexts map[string]bool, extra []string) []string {
+	var types []string
+	for _, context := range sortedKeys(contexts) {
+		if !contexts[context] {
+			continue
+		}
+
+		switch context {
+		case "work":
+			types = append(types, "work")
+		case "private":
+			types = append(types, "home")
+		}
+	}
+
+	return append(types, extra...)
+}
+
+// vcardStructuredValue builds a jCard structured value, where components
+// with multiple values are represented as arrays
+func vcardStructuredValue(components [][]string) []interface{} {
+	value := make([]interface{}, 0, len(components))
+	for _, component := range components {
+		switch len(component) {
+		case 0:
+			value = append(value, "")
+		case 1:
+			value = append(value, component[0])
+		default:
+			values := make([]interface{}, 0, len(component))
+			for _, v := range component {
+				values = append(values, v)
+			}
+			value = append(value, values)
+		}
+	}
+
+	return value
+}
+
+// sortedKeys returns the keys of a map with string keys in order. Keys that
+// end with a number (like "email-2" and "email-10") are ordered by the number
+func sortedKeys(m interface{}) []string {
+	value := reflect.ValueOf(m)
+	if value.Kind() != reflect.Map {
+		return nil
+	}
+
+	keys := make([]string, 0, value.Len())
+	for _, key := range value.MapKeys() {
+		keys = append(keys, key.String())
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		prefixI, numberI := splitKeyNumber(keys[i])
+		prefixJ, numberJ := splitKeyNumber(keys[j])
+		if prefixI != prefixJ || numberI == numberJ {
+			return keys[i] < keys[j]
+		}
+		return numberI < numberJ
+	})
+
+	return keys
+}
+
+// splitKeyNumber breaks a key into the prefix and the number at the end. If
+// there's no number at the end, -1 is returned
+func splitKeyNumber(key string) (string, int) {
+	i := len(key)
+	for i > 0 && key[i-1] >= '0' && key[i-1] <= '9' {
+		i--
+	}
+
+	number, err := strconv.Atoi(key[i:])
+	if err != nil {
+		return key, -1
+	}
+
+	return key[:i], number
+}
@@ -0,0 +1,187 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestVCardToJSContact(t *testing.T) {
+	var vcard VCard
+	if err := json.Unmarshal([]byte(vcardExample), &vcard); err != nil {
+		t.Fatal(err)
+	}
+
+	card := VCardToJSContact(&vcard)
+
+	expected := &JSContactCard{
+		Type:    "Card",
+		Version: "1.0",
+		Kind:    "individual",
+		Name: &JSContactName{
+			Full: "Joe User",
+			Components: []JSContactNameComponent{
+				{Kind: "surname", Value: "User"},
+				{Kind: "given", Value: "Joe"},
+				{Kind: "credential", Value: "ing. jr"},
+				{Kind: "credential", Value: "M.Sc."},
+			},
+		},
+		Organizations: map[string]JSContactOrganization{
+			"org-1": {Name: "Example"},
+		},
+		Titles: map[string]JSContactTitle{
+			"title-1": {Name: "Research Scientist", Kind: "title"},
+			"title-2": {Name: "Project Lead", Kind: "role"},
+		},
+		Emails: map[string]JSContactEmail{
+			"email-1": {Address: "joe.user@example.com", Contexts: map[string]bool{"work": true}},
+		},
+		Phones: map[string]JSContactPhone{
+			"phone-1": {
+				Number:   "tel:+1-555-555-1234;ext=102",
+				Features: map[string]bool{"voice": true},
+				Contexts: map[string]bool{"work": true},
+				Pref:     1,
+			},
+			"phone-2": {
+				Number:   "tel:+1-555-555-4321",
+				Features: map[string]bool{"mobile": true, "voice": true, "video": true, "text": true},
+				Contexts: map[string]bool{"work": true},
+			},
+		},
+		Addresses: map[string]JSContactAddress{
+			"address-1": {
+				Components: []JSContactAddressComponent{
+					{Kind: "apartment", Value: "Suite 1234"},
+					{Kind: "name", Value: "4321 Rue Somewhere"},
+					{Kind: "locality", Value: "Quebec"},
+					{Kind: "region", Value: "QC"},
+					{Kind: "postcode", Value: "G1V 2M2"},
+					{Kind: "country", Value: "Canada"},
+				},
+				Contexts: map[string]bool{"work": true},
+			},
+			"address-2": {
+				CountryCode: "CA",
+				Full:        "123 Maple Ave\nSuite 90001\nVancouver\nBC\n1239\n",
+				Contexts:    map[string]bool{"private": true},
+			},
+		},
+		Links: map[string]JSContactLink{
+			"link-1": {URI: "https://example.org", Contexts: map[string]bool{"private": true}},
+		},
+		PreferredLanguages: map[string]JSContactLanguagePref{
+			"lang-1": {Language: "fr", Pref: 1},
+			"lang-2": {Language: "en", Pref: 2},
+		},
+		VCardProps: []interface{}{
+			[]interface{}{"geo", map[string]interface{}{"type": "work"}, "uri", "geo:46.772673,-71.282945"},
+			[]interface{}{"key", map[string]interface{}{"type": "work"}, "uri", "https://www.example.com/joe.user/joe.asc"},
+			[]interface{}{"tz", map[string]interface{}{}, "utc-offset", "-05:00"},
+			[]interface{}{"x-custom", map[string]interface{}{"x-param": []interface{}{"a", "b"}}, "unknown", float64(1), float64(2), map[string]interface{}{"c": true}},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, card) {
+		t.Errorf("Unexpected JSContact card. Expected “%#v” and got “%#v”", expected, card)
+	}
+}
+
+func TestJSContactToVCard(t *testing.T) {
+	var vcard VCard
+	if err := json.Unmarshal([]byte(vcardExample), &vcard); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := JSContactToVCard(VCardToJSContact(&vcard))
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	if fn := result.FN(); fn != vcard.FN() {
+		t.Errorf("Unexpected fn. Expected “%s” and got “%s”", vcard.FN(), fn)
+	}
+
+	if n, _ := result.First(VCardPropertyN); n.Text() != "User;Joe;;;ing. jr,M.Sc." {
+		t.Errorf("Unexpected n. Expected “User;Joe;;;ing. jr,M.Sc.” and got “%s”", n.Text())
+	}
+
+	if !reflect.DeepEqual(vcard.Emails(), result.Emails()) {
+		t.Errorf("Unexpected emails. Expected “%#v” and got “%#v”", vcard.Emails(), result.Emails())
+	}
+
+	expectedTels := []VCardTel{
+		{Number: "+1-555-555-1234;ext=102", Types: []string{"work", "voice"}, Pref: 1},
+		{Number: "+1-555-555-4321", Types: []string{"work", "cell", "text", "video", "voice"}},
+	}
+
+	if tels := result.Tels(); !reflect.DeepEqual(expectedTels, tels) {
+		t.Errorf("Unexpected tels. Expected “%#v” and got “%#v”", expectedTels, tels)
+	}
+
+	if !reflect.DeepEqual(vcard.Addresses(), result.Addresses()) {
+		t.Errorf("Unexpected addresses. Expected “%#v” and got “%#v”", vcard.Addresses(), result.Addresses())
+	}
+
+	for _, name := range []string{"geo", "key", "tz", "x-custom", "title", "role", "lang", "url"} {
+		if !reflect.DeepEqual(vcard.Get(name), result.Get(name)) {
+			t.Errorf("Unexpected %s property. Expected “%#v” and got “%#v”", name, vcard.Get(name), result.Get(name))
+		}
+	}
+}
+
+func TestEntityJSContact(t *testing.T) {
+	data := []byte(`{
+  "objectClassName": "entity",
+  "handle": "XXXX",
+  "jscontact_card": {
+    "@type": "Card",
+    "version": "1.0",
+    "kind": "org",
+    "name": {"full": "Example Inc."},
+    "emails": {"e1": {"address": "contact@example.com"}}
+  },
+  "rdapConformance": ["rdap_level_0", "jscontact"]
+}`)
+
+	var entity Entity
+	if err := json.Unmarshal(data, &entity); err != nil {
+		t.Fatal(err)
+	}
+
+	card, err := entity.JSContact()
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	if card != entity.JSContactCard {
+		t.Errorf("Expected the JSContact card sent by the server")
+	}
+
+	vcard, err := entity.VCard()
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	if vcard.FN() != "Example Inc." || vcard.Kind() != "org" {
+		t.Errorf("Unexpected vCard converted from JSContact: “%#v”", vcard)
+	}
+
+	expectedEmails := []VCardEmail{{Address: "contact@example.com"}}
+	if !reflect.DeepEqual(expectedEmails, vcard.Emails()) {
+		t.Errorf("Unexpected emails. Expected “%#v” and got “%#v”", expectedEmails, vcard.Emails())
+	}
+
+	entity = Entity{}
+	entity.SetVCard(NewVCard().AddFN("Joe User"))
+
+	card, err = entity.JSContact()
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	if card.Name == nil || card.Name.Full != "Joe User" {
+		t.Errorf("Unexpected JSContact converted from vCard: “%#v”", card)
+	}
+}
@@ -1,9 +1,68 @@
 package protocol
 
-// Link describes Links as it is in RFC 7483, section 4.2
+import "encoding/json"
+
+// List of link relations commonly used between RDAP objects. The complete
+// list of relations can be found in the IANA Link Relations registry
+const (
+	// LinkRelSelf references the object itself
+	LinkRelSelf = "self"
+
+	// LinkRelRelated references a related object, like the registrar's copy
+	// of a domain in a thin registry
+	LinkRelRelated = "related"
+
+	// LinkRelUp references the parent object in a hierarchy, like the
+	// less-specific IP network
+	LinkRelUp = "up"
+
+	// LinkRelDown references a child object in a hierarchy, like a
+	// more-specific IP network
+	LinkRelDown = "down"
+)
+
+// Link describes Links as it is in RFC 9083, section 4.2
 type Link struct {
-	Value string `json:"value,omitempty"`
-	Rel   string `json:"rel,omitempty"`
-	Href  string `json:"href,omitempty"`
-	Type  string `json:"type,omitempty"`
+	Value    string   `json:"value,omitempty"`
+	Rel      string   `json:"rel,omitempty"`
+	Href     string   `json:"href,omitempty"`
+	HrefLang HrefLang `json:"hreflang,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Media    string   `json:"media,omitempty"`
+	Type     string   `json:"type,omitempty"`
+}
+
+// HrefLang stores the languages of the link target. RFC 9083 uses an array
+// of language tags, but some servers send a single string, so both formats
+// are accepted when decoding
+type HrefLang []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting a string
+// or an array of strings
+func (h *HrefLang) UnmarshalJSON(data []byte) error {
+	var languages []string
+	if err := json.Unmarshal(data, &languages); err == nil {
+		*h = languages
+		return nil
+	}
+
+	var language string
+	if err := json.Unmarshal(data, &language); err != nil {
+		return err
+	}
+
+	*h = HrefLang{language}
+	return nil
+}
+
+// FindLink is an easy way to find a link with a given relation. If more than
+// one link has the same relation, the first one is returned
+func FindLink(links []Link, rel string) (link Link, found bool) {
+	for _, l := range links {
+		if l.Rel == rel {
+			return l, true
+		}
+	}
+
+	return
 }
@@ -1,13 +1,13 @@
 package protocol
 
-// IPAddresses describes the ipAddresses field as it is in RFC 7483, section
+// IPAddresses describes the ipAddresses field as it is in RFC 9083, section
 // 5.2
 type IPAddresses struct {
 	V4 []string `json:"v4,omitempty"`
 	V6 []string `json:"v6,omitempty"`
 }
 
-// Nameserver describes the Nameserver Object Class as it is in RFC 7483,
+// Nameserver describes the Nameserver Object Class as it is in RFC 9083,
 // section 5.2
 type Nameserver struct {
 	ObjectClassName string       `json:"objectClassName"`
@@ -15,10 +15,14 @@ type Nameserver struct {
 	LDHName         string       `json:"ldhName,omitempty"`
 	UnicodeName     string       `json:"unicodeName,omitempty"`
 	Entities        []Entity     `json:"entities,omitempty"`
-	Status          []Status     `json:"status,omitempty"`
+	Status          StatusSet    `json:"status,omitempty"`
 	IPAddresses     *IPAddresses `json:"ipAddresses,omitempty"`
 	Remarks         []Remark     `json:"remarks,omitempty"`
 	Links           []Link       `json:"links,omitempty"`
 	Port43          string       `json:"port43,omitempty"`
 	Events          []Event      `json:"events,omitempty"`
+	Notices         []Notice     `json:"notices,omitempty"`
+	Redacted        []Redacted   `json:"redacted,omitempty"`
+	Lang            string       `json:"lang,omitempty"`
+	Conformance
 }
@@ -0,0 +1,151 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// List of object class names as described in RFC 9083, section 5. The object
+// class name is used to identify the type of the RDAP response
+const (
+	// ObjectClassDomain identifies the Domain Object Class
+	ObjectClassDomain = "domain"
+
+	// ObjectClassNameserver identifies the Nameserver Object Class
+	ObjectClassNameserver = "nameserver"
+
+	// ObjectClassEntity identifies the Entity Object Class
+	ObjectClassEntity = "entity"
+
+	// ObjectClassIPNetwork identifies the IP Network Object Class
+	ObjectClassIPNetwork = "ip network"
+
+	// ObjectClassAutnum identifies the Autonomous System Number Object Class
+	ObjectClassAutnum = "autnum"
+)
+
+// DecodeObject parses a RDAP response, using the objectClassName member to
+// detect the object class. It returns a pointer to a Domain, Nameserver,
+// Entity, IPNetwork or AS object, or an error if the object class is unknown
+func DecodeObject(data []byte) (interface{}, error) {
+	var header struct {
+		ObjectClassName string `json:"objectClassName"`
+	}
+
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	var object interface{}
+
+	switch header.ObjectClassName {
+	case ObjectClassDomain:
+		object = &Domain{}
+	case ObjectClassNameserver:
+		object = &Nameserver{}
+	case ObjectClassEntity:
+		object = &Entity{}
+	case ObjectClassIPNetwork:
+		object = &IPNetwork{}
+	case ObjectClassAutnum:
+		object = &AS{}
+	default:
+		return nil, fmt.Errorf("unknown object class %q", header.ObjectClassName)
+	}
+
+	if err := json.Unmarshal(data, object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// ObjectLinks returns the links of a RDAP object. The object can be a Domain,
+// Nameserver, Entity, IPNetwork or AS, as a value or as a pointer. For any
+// other type no link is returned
+func ObjectLinks(object interface{}) []Link {
+	switch o := object.(type) {
+	case *Domain:
+		return o.Links
+	case Domain:
+		return o.Links
+	case *Nameserver:
+		return o.Links
+	case Nameserver:
+		return o.Links
+	case *Entity:
+		return o.Links
+	case Entity:
+		return o.Links
+	case *IPNetwork:
+		return o.Links
+	case IPNetwork:
+		return o.Links
+	case *AS:
+		return o.Links
+	case AS:
+		return o.Links
+	}
+
+	return nil
+}
+
+// ObjectEntities returns the entities of a RDAP object. The object can be a
+// Domain, Nameserver, Entity, IPNetwork or AS, as a value or as a pointer. For
+// any other type no entity is returned
+func ObjectEntities(object interface{}) []Entity {
+	switch o := object.(type) {
+	case *Domain:
+		return o.Entities
+	case Domain:
+		return o.Entities
+	case *Nameserver:
+		return o.Entities
+	case Nameserver:
+		return o.Entities
+	case *Entity:
+		return o.Entities
+	case Entity:
+		return o.Entities
+	case *IPNetwork:
+		return o.Entities
+	case IPNetwork:
+		return o.Entities
+	case *AS:
+		return o.Entities
+	case AS:
+		return o.Entities
+	}
+
+	return nil
+}
+
+// ObjectEvents returns the events of a RDAP object. The object can be a
+// Domain, Nameserver, Entity, IPNetwork or AS, as a value or as a pointer. For
+// any other type no event is returned
+func ObjectEvents(object interface{}) []Event {
+	switch o := object.(type) {
+	case *Domain:
+		return o.Events
+	case Domain:
+		return o.Events
+	case *Nameserver:
+		return o.Events
+	case Nameserver:
+		return o.Events
+	case *Entity:
+		return o.Events
+	case Entity:
+		return o.Events
+	case *IPNetwork:
+		return o.Events
+	case IPNetwork:
+		return o.Events
+	case *AS:
+		return o.Events
+	case AS:
+		return o.Events
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package protocol
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeObject(t *testing.T) {
+	data := []struct {
+		description   string
+		data          []byte
+		expected      interface{}
+		expectedError error
+	}{
+		{
+			description: "it should decode a domain",
+			data:        []byte(`{"objectClassName":"domain","ldhName":"example.com"}`),
+			expected:    &Domain{ObjectClassName: "domain", LDHName: "example.com"},
+		},
+		{
+			description: "it should decode a nameserver",
+			data:        []byte(`{"objectClassName":"nameserver","ldhName":"ns1.example.com"}`),
+			expected:    &Nameserver{ObjectClassName: "nameserver", LDHName: "ns1.example.com"},
+		},
+		{
+			description: "it should decode an entity",
+			data:        []byte(`{"objectClassName":"entity","handle":"XXXX"}`),
+			expected:    &Entity{ObjectClassName: "entity", Handle: "XXXX"},
+		},
+		{
+			description: "it should decode an IP network",
+			data:        []byte(`{"objectClassName":"ip network","handle":"XXXX-RIR"}`),
+			expected:    &IPNetwork{ObjectClassName: "ip network", Handle: "XXXX-RIR"},
+		},
+		{
+			description: "it should decode an autnum",
+			data:        []byte(`{"objectClassName":"autnum","handle":"AS65536"}`),
+			expected:    &AS{ObjectClassName: "autnum", Handle: "AS65536"},
+		},
+		{
+			description:   "it should fail for an unknown object class",
+			data:          []byte(`{"objectClassName":"help"}`),
+			expectedError: fmt.Errorf(`unknown object class "help"`),
+		},
+		{
+			description:   "it should fail for an invalid JSON",
+			data:          []byte(`{{{{`),
+			expectedError: fmt.Errorf("invalid character '{' looking for beginning of object key string"),
+		},
+	}
+
+	for i, item := range data {
+		object, err := DecodeObject(item.data)
+
+		if item.expectedError != nil {
+			if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+				t.Errorf("[%d] %s: expected error “%s”, got “%s”", i, item.description, item.expectedError, err)
+			}
+
+		} else if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+
+		} else if !reflect.DeepEqual(item.expected, object) {
+			t.Errorf("[%d] %s: unexpected object. Expected “%#v” and got “%#v”", i, item.description, item.expected, object)
+		}
+	}
+}
+
+func TestObjectLinks(t *testing.T) {
+	links := []Link{{Rel: "self", Href: "https://rdap.example/domain/example.com"}}
+
+	data := []struct {
+		description string
+		object      interface{}
+		expected    []Link
+	}{
+		{
+			description: "it should return the links of a domain pointer",
+			object:      &Domain{Links: links},
+			expected:    links,
+		},
+		{
+			description: "it should return the links of an entity value",
+			object:      Entity{Links: links},
+			expected:    links,
+		},
+		{
+			description: "it should not return links for unknown types",
+			object:      Help{},
+		},
+	}
+
+	for i, item := range data {
+		if result := ObjectLinks(item.object); !reflect.DeepEqual(item.expected, result) {
+			t.Errorf("[%d] %s: unexpected links. Expected “%#v” and got “%#v”", i, item.description, item.expected, result)
+		}
+	}
+}
+
+func TestObjectEntities(t *testing.T) {
+	entities := []Entity{{Handle: "XXXX", Roles: []string{"abuse"}}}
+
+	data := []struct {
+		description string
+		object      interface{}
+		expected    []Entity
+	}{
+		{
+			description: "it should return the entities of an IP network pointer",
+			object:      &IPNetwork{Entities: entities},
+			expected:    entities,
+		},
+		{
+			description: "it should return the entities of an AS value",
+			object:      AS{Entities: entities},
+			expected:    entities,
+		},
+		{
+			description: "it should not return entities for unknown types",
+			object:      Help{},
+		},
+	}
+
+	for i, item := range data {
+		if result := ObjectEntities(item.object); !reflect.DeepEqual(item.expected, result) {
+			t.Errorf("[%d] %s: unexpected entities. Expected “%#v” and got “%#v”", i, item.description, item.expected, result)
+		}
+	}
+}
+
+func TestObjectEvents(t *testing.T) {
+	events := []Event{{Action: EventActionLastChanged}}
+
+	data := []struct {
+		description string
+		object      interface{}
+		expected    []Event
+	}{
+		{
+			description: "it should return the events of a domain pointer",
+			object:      &Domain{Events: events},
+			expected:    events,
+		},
+		{
+			description: "it should return the events of a nameserver value",
+			object:      Nameserver{Events: events},
+			expected:    events,
+		},
+		{
+			description: "it should not return events for unknown types",
+			object:      Help{},
+		},
+	}
+
+	for i, item := range data {
+		if result := ObjectEvents(item.object); !reflect.DeepEqual(item.expected, result) {
+			t.Errorf("[%d] %s: unexpected events. Expected “%#v” and got “%#v”", i, item.description, item.expected, result)
+		}
+	}
+}
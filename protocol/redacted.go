@@ -0,0 +1,143 @@
+package protocol
+
+const (
+	// RedactedConformance is the RDAP extension identifier used by servers
+	// that inform the redacted fields as described in RFC 9537
+	RedactedConformance = "redacted"
+)
+
+// https://www.rfc-editor.org/rfc/rfc9537#section-3
+const (
+	// RedactionMethodRemoval the field was removed from the response. This is
+	// the default method when none is informed
+	RedactionMethodRemoval RedactionMethod = "removal"
+
+	// RedactionMethodEmptyValue the field value was replaced by an empty value
+	RedactionMethodEmptyValue RedactionMethod = "emptyValue"
+
+	// RedactionMethodPartialValue part of the field value was removed
+	RedactionMethodPartialValue RedactionMethod = "partialValue"
+
+	// RedactionMethodReplacementValue the field value was replaced by another
+	// value, or the field was replaced by another field
+	RedactionMethodReplacementValue RedactionMethod = "replacementValue"
+)
+
+// RedactionMethod stores one of the possible redaction methods as listed in
+// RFC 9537, section 3
+type RedactionMethod string
+
+// RedactedName identifies the redacted field as it is in RFC 9537, section
+// 4.2. Registered redacted names use the type member, otherwise a
+// description is used
+type RedactedName struct {
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// String returns the registered type of the redacted field, or the
+// description when there's no type
+func (r RedactedName) String() string {
+	if r.Type != "" {
+		return r.Type
+	}
+
+	return r.Description
+}
+
+// RedactedReason explains why the field was redacted as it is in RFC 9537,
+// section 4.2
+type RedactedReason struct {
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// String returns the registered type of the reason, or the description when
+// there's no type
+func (r RedactedReason) String() string {
+	if r.Type != "" {
+		return r.Type
+	}
+
+	return r.Description
+}
+
+// Redacted describes a redacted field as it is in RFC 9537, section 4.2. The
+// paths are JSONPath expressions (RFC 9535) unless pathLang says otherwise
+type Redacted struct {
+	Name            RedactedName    `json:"name"`
+	PrePath         string          `json:"prePath,omitempty"`
+	PostPath        string          `json:"postPath,omitempty"`
+	ReplacementPath string          `json:"replacementPath,omitempty"`
+	PathLang        string          `json:"pathLang,omitempty"`
+	Method          RedactionMethod `json:"method,omitempty"`
+	Reason          *RedactedReason `json:"reason,omitempty"`
+}
+
+// RedactedField summarizes a redacted field, so clients can report which
+// fields were redacted and why
+type RedactedField struct {
+	Name   string
+	Method RedactionMethod
+	Reason string
+
+	// Path is the JSONPath expression of the field, before the redaction for
+	// removed or replaced fields, and after the redaction for emptied or
+	// partially redacted fields
+	Path string
+}
+
+// RedactedFields returns the fields that the server redacted in the object.
+// The object can be a Domain, Nameserver, Entity, IPNetwork or AS, as a value
+// or as a pointer. For any other type no field is returned
+func RedactedFields(object interface{}) []RedactedField {
+	var redacted []Redacted
+
+	switch o := object.(type) {
+	case *Domain:
+		redacted = o.Redacted
+	case Domain:
+		redacted = o.Redacted
+	case *Nameserver:
+		redacted = o.Redacted
+	case Nameserver:
+		redacted = o.Redacted
+	case *Entity:
+		redacted = o.Redacted
+	case Entity:
+		redacted = o.Redacted
+	case *IPNetwork:
+		redacted = o.Redacted
+	case IPNetwork:
+		redacted = o.Redacted
+	case *AS:
+		redacted = o.Redacted
+	case AS:
+		redacted = o.Redacted
+	}
+
+	var fields []RedactedField
+	for _, r := range redacted {
+		field := RedactedField{
+			Name:   r.Name.String(),
+			Method: r.Method,
+			Path:   r.PrePath,
+		}
+
+		if field.Method == "" {
+			field.Method = RedactionMethodRemoval
+		}
+
+		if r.Reason != nil {
+			field.Reason = r.Reason.String()
+		}
+
+		if field.Path == "" {
+			field.Path = r.PostPath
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
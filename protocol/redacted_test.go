@@ -0,0 +1,69 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRedactedFields(t *testing.T) {
+	// redacted members from the examples of RFC 9537, section 4.2
+	data := []byte(`{
+  "objectClassName": "domain",
+  "ldhName": "example.com",
+  "rdapConformance": ["rdap_level_0", "redacted"],
+  "redacted": [
+    {
+      "name": {"type": "Registry Domain ID"},
+      "prePath": "$.handle",
+      "pathLang": "jsonpath",
+      "method": "removal",
+      "reason": {"type": "Server policy"}
+    },
+    {
+      "name": {"type": "Registrant Name"},
+      "postPath": "$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='fn')][3]",
+      "pathLang": "jsonpath",
+      "method": "emptyValue",
+      "reason": {"description": "Personal data"}
+    },
+    {
+      "name": {"description": "Administrative Contact ID"},
+      "prePath": "$.entities[?(@.roles[0]=='administrative')].handle"
+    }
+  ]
+}`)
+
+	var domain Domain
+	if err := json.Unmarshal(data, &domain); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []RedactedField{
+		{
+			Name:   "Registry Domain ID",
+			Method: RedactionMethodRemoval,
+			Reason: "Server policy",
+			Path:   "$.handle",
+		},
+		{
+			Name:   "Registrant Name",
+			Method: RedactionMethodEmptyValue,
+			Reason: "Personal data",
+			Path:   "$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='fn')][3]",
+		},
+		{
+			Name:   "Administrative Contact ID",
+			Method: RedactionMethodRemoval,
+			Path:   "$.entities[?(@.roles[0]=='administrative')].handle",
+		},
+	}
+
+	if fields := RedactedFields(&domain); !reflect.DeepEqual(expected, fields) {
+		t.Errorf("Unexpected redacted fields. Expected “%#v” and got “%#v”", expected, fields)
+	}
+
+	if fields := RedactedFields(Help{}); fields != nil {
+		t.Errorf("Unexpected redacted fields for an unknown type: “%#v”", fields)
+	}
+}
@@ -0,0 +1,67 @@
+package protocol
+
+const (
+	// SortingConformance is the RDAP extension identifier used by servers
+	// that sort the search results as described in RFC 8977
+	SortingConformance = "sorting"
+
+	// PagingConformance is the RDAP extension identifier used by servers that
+	// split the search results in pages as described in RFC 8977
+	PagingConformance = "paging"
+)
+
+// DomainSearchResults describes the response of domain searches as it is in
+// RFC 9083, section 8
+type DomainSearchResults struct {
+	Results []Domain         `json:"domainSearchResults"`
+	Notices []Notice         `json:"notices,omitempty"`
+	Sorting *SortingMetadata `json:"sorting_metadata,omitempty"`
+	Paging  *PagingMetadata  `json:"paging_metadata,omitempty"`
+	Conformance
+}
+
+// NameserverSearchResults describes the response of nameserver searches as it
+// is in RFC 9083, section 8
+type NameserverSearchResults struct {
+	Results []Nameserver     `json:"nameserverSearchResults"`
+	Notices []Notice         `json:"notices,omitempty"`
+	Sorting *SortingMetadata `json:"sorting_metadata,omitempty"`
+	Paging  *PagingMetadata  `json:"paging_metadata,omitempty"`
+	Conformance
+}
+
+// EntitySearchResults describes the response of entity searches as it is in
+// RFC 9083, section 8
+type EntitySearchResults struct {
+	Results []Entity         `json:"entitySearchResults"`
+	Notices []Notice         `json:"notices,omitempty"`
+	Sorting *SortingMetadata `json:"sorting_metadata,omitempty"`
+	Paging  *PagingMetadata  `json:"paging_metadata,omitempty"`
+	Conformance
+}
+
+// SortingMetadata describes the sorting options of the search results as it
+// is in RFC 8977, section 2.3.2
+type SortingMetadata struct {
+	CurrentSort    string          `json:"currentSort,omitempty"`
+	AvailableSorts []AvailableSort `json:"availableSorts,omitempty"`
+}
+
+// AvailableSort describes a property that can be used to sort the search
+// results as it is in RFC 8977, section 2.3.2
+type AvailableSort struct {
+	Property string `json:"property"`
+	JSONPath string `json:"jsonPath"`
+	Default  bool   `json:"default"`
+	Links    []Link `json:"links,omitempty"`
+}
+
+// PagingMetadata describes the page of the search results as it is in RFC
+// 8977, section 2.3.3. The next page is informed in a link with the "next"
+// relation
+type PagingMetadata struct {
+	TotalCount int    `json:"totalCount,omitempty"`
+	PageSize   int    `json:"pageSize,omitempty"`
+	PageNumber int    `json:"pageNumber,omitempty"`
+	Links      []Link `json:"links,omitempty"`
+}
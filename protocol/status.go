@@ -1,7 +1,15 @@
 package protocol
 
-// https://tools.ietf.org/html/rfc7483#section-10.2.2
+import "fmt"
+
+// https://tools.ietf.org/html/rfc9083#section-10.2.2
 const (
+	// StatusValidated signifies that the data of the object instance has
+	// been found to be accurate. This type of status is usually found on
+	// entity object instances to note the validity of identifying contact
+	// information
+	StatusValidated Status = "validated"
+
 	// StatusActive the object instance is in use.  For domain names, it
 	// signifies that the domain name is published in DNS.  For network and autnum
 	// registrations, it signifies that they are allocated or assigned for use in
@@ -36,24 +44,114 @@ const (
 
 	// StatusRenewProhibited Renewal or reregistration of the object instance is
 	// forbidden.
-	StatusRenewProhibited = "renew prohibited"
+	StatusRenewProhibited Status = "renew prohibited"
 
 	// StatusTransferProhibited Transfers of the registration from one registrar
 	// to another are forbidden. This type of status normally applies to DNR
 	// domain names.
-	StatusTransferProhibited = "transfer prohibited"
+	StatusTransferProhibited Status = "transfer prohibited"
 
 	// StatusUpdateProhibited Updates to the object instance are forbidden.
-	StatusUpdateProhibited = "update prohibited"
+	StatusUpdateProhibited Status = "update prohibited"
 
 	// StatusDeleteProhibited Deletion of the registration of the object instance
 	// is forbidden. This type of status normally applies to DNR domain names.
-	StatusDeleteProhibited = "delete prohibited"
+	StatusDeleteProhibited Status = "delete prohibited"
 
 	// StatusRemoved some of the information of the object instance has not
 	// been made available and has been removed. This is most commonly applied
 	// to entities
 	StatusRemoved Status = "removed"
+
+	// StatusProxy the object instance represents a third party through which
+	// the registration was conducted (i.e., not the registrant)
+	StatusProxy Status = "proxy"
+
+	// StatusPrivate the object instance represents a third party with
+	// information that is not available due to privacy policies
+	StatusPrivate Status = "private"
+
+	// StatusObscured some of the information of the object instance has been
+	// altered for the purposes of not readily revealing the actual information
+	// of the object instance
+	StatusObscured Status = "obscured"
+
+	// StatusAssociated the object instance is associated with other object
+	// instances in the registry. This is most commonly used to signify that a
+	// nameserver is associated with a domain or that an entity is associated
+	// with a network resource or domain
+	StatusAssociated Status = "associated"
+
+	// StatusLocked changes to the object instance cannot be made, including
+	// the association of other object instances
+	StatusLocked Status = "locked"
+)
+
+// https://tools.ietf.org/html/rfc8056#section-2
+const (
+	// StatusAddPeriod the object instance is in the grace period provided
+	// after the initial registration
+	StatusAddPeriod Status = "add period"
+
+	// StatusAutoRenewPeriod the object instance is in the grace period
+	// provided after it was automatically renewed by the registry
+	StatusAutoRenewPeriod Status = "auto renew period"
+
+	// StatusClientDeleteProhibited the client requested that requests to
+	// delete the object instance be rejected
+	StatusClientDeleteProhibited Status = "client delete prohibited"
+
+	// StatusClientHold the client requested that the DNS delegation
+	// information not be published for the object instance
+	StatusClientHold Status = "client hold"
+
+	// StatusClientRenewProhibited the client requested that requests to
+	// renew the object instance be rejected
+	StatusClientRenewProhibited Status = "client renew prohibited"
+
+	// StatusClientTransferProhibited the client requested that requests to
+	// transfer the object instance be rejected
+	StatusClientTransferProhibited Status = "client transfer prohibited"
+
+	// StatusClientUpdateProhibited the client requested that requests to
+	// update the object instance be rejected
+	StatusClientUpdateProhibited Status = "client update prohibited"
+
+	// StatusPendingRestore an object instance is in the process of being
+	// restored after being in the redemption period state
+	StatusPendingRestore Status = "pending restore"
+
+	// StatusRedemptionPeriod a delete has been received, but the object
+	// instance is being held in a redemption period where it can be restored
+	StatusRedemptionPeriod Status = "redemption period"
+
+	// StatusRenewPeriod the object instance is in the grace period provided
+	// after it was explicitly renewed by the client
+	StatusRenewPeriod Status = "renew period"
+
+	// StatusServerDeleteProhibited the server set the status so that requests
+	// to delete the object instance are rejected
+	StatusServerDeleteProhibited Status = "server delete prohibited"
+
+	// StatusServerRenewProhibited the server set the status so that requests
+	// to renew the object instance are rejected
+	StatusServerRenewProhibited Status = "server renew prohibited"
+
+	// StatusServerTransferProhibited the server set the status so that
+	// requests to transfer the object instance are rejected
+	StatusServerTransferProhibited Status = "server transfer prohibited"
+
+	// StatusServerUpdateProhibited the server set the status so that requests
+	// to update the object instance are rejected
+	StatusServerUpdateProhibited Status = "server update prohibited"
+
+	// StatusServerHold the server set the status so that DNS delegation
+	// information is not published for the object instance
+	StatusServerHold Status = "server hold"
+
+	// StatusTransferPeriod the object instance is in the grace period
+	// provided after it was transferred
+	StatusTransferPeriod Status = "transfer period"
 )
 
 // Proposed by NIC.br for DNS and DNSSEC checks of delegations
@@ -140,6 +238,139 @@ const (
 	StatusInactiveCG Status = "nicbr inactive CG"
 )
 
-// Status stores one of the possible status as listed in RFC 7483, section
+// Status stores one of the possible status as listed in RFC 9083, section
 // 10.2.2
 type Status string
+
+// eppStatuses maps the EPP status codes to the RDAP statuses as described in
+// RFC 8056, section 2
+var eppStatuses = map[string]Status{
+	"addPeriod":                StatusAddPeriod,
+	"autoRenewPeriod":          StatusAutoRenewPeriod,
+	"clientDeleteProhibited":   StatusClientDeleteProhibited,
+	"clientHold":               StatusClientHold,
+	"clientRenewProhibited":    StatusClientRenewProhibited,
+	"clientTransferProhibited": StatusClientTransferProhibited,
+	"clientUpdateProhibited":   StatusClientUpdateProhibited,
+	"inactive":                 StatusInactive,
+	"linked":                   StatusAssociated,
+	"ok":                       StatusActive,
+	"pendingCreate":            StatusPendingCreate,
+	"pendingDelete":            StatusPendingDelete,
+	"pendingRenew":             StatusPendingRenew,
+	"pendingRestore":           StatusPendingRestore,
+	"pendingTransfer":          StatusPendingTransfer,
+	"pendingUpdate":            StatusPendingUpdate,
+	"redemptionPeriod":         StatusRedemptionPeriod,
+	"renewPeriod":              StatusRenewPeriod,
+	"serverDeleteProhibited":   StatusServerDeleteProhibited,
+	"serverHold":               StatusServerHold,
+	"serverRenewProhibited":    StatusServerRenewProhibited,
+	"serverTransferProhibited": StatusServerTransferProhibited,
+	"serverUpdateProhibited":   StatusServerUpdateProhibited,
+	"transferPeriod":           StatusTransferPeriod,
+}
+
+// rdapStatuses is the inverse of eppStatuses
+var rdapStatuses = make(map[Status]string)
+
+func init() {
+	for epp, status := range eppStatuses {
+		rdapStatuses[status] = epp
+	}
+}
+
+// StatusFromEPP returns the RDAP status of an EPP status code (RFC 5731,
+// 5732, 5733 and 3915) as described in RFC 8056. If the code has no mapping
+// found is false
+func StatusFromEPP(code string) (status Status, found bool) {
+	status, found = eppStatuses[code]
+	return
+}
+
+// EPP returns the EPP status code of the RDAP status as described in RFC
+// 8056. Statuses without an EPP equivalent (e.g. "locked" or the NIC.br
+// statuses) return found as false
+func (s Status) EPP() (code string, found bool) {
+	code, found = rdapStatuses[s]
+	return
+}
+
+// StatusSet stores the statuses of an object. The same status should appear
+// only once in the set
+type StatusSet []Status
+
+// NewStatusSetFromEPP converts a list of EPP status codes to RDAP statuses.
+// An error is returned if any code has no RDAP equivalent
+func NewStatusSetFromEPP(codes []string) (StatusSet, error) {
+	var set StatusSet
+	for _, code := range codes {
+		status, found := StatusFromEPP(code)
+		if !found {
+			return nil, fmt.Errorf("unknown EPP status %q", code)
+		}
+
+		set.Add(status)
+	}
+
+	return set, nil
+}
+
+// Contains checks if the status is in the set
+func (s StatusSet) Contains(status Status) bool {
+	for _, v := range s {
+		if v == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainsAny checks if at least one of the statuses is in the set
+func (s StatusSet) ContainsAny(statuses ...Status) bool {
+	for _, status := range statuses {
+		if s.Contains(status) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Add appends the statuses that are not already in the set
+func (s *StatusSet) Add(statuses ...Status) {
+	for _, status := range statuses {
+		if !s.Contains(status) {
+			*s = append(*s, status)
+		}
+	}
+}
+
+// Remove removes the statuses from the set, keeping the order of the
+// remaining statuses
+func (s *StatusSet) Remove(statuses ...Status) {
+	remove := StatusSet(statuses)
+
+	var kept StatusSet
+	for _, status := range *s {
+		if !remove.Contains(status) {
+			kept = append(kept, status)
+		}
+	}
+
+	*s = kept
+}
+
+// EPP converts the set to EPP status codes, ignoring the statuses without an
+// EPP equivalent
+func (s StatusSet) EPP() []string {
+	var codes []string
+	for _, status := range s {
+		if code, found := status.EPP(); found {
+			codes = append(codes, code)
+		}
+	}
+
+	return codes
+}
@@ -0,0 +1,83 @@
+package protocol
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNewStatusSetFromEPP(t *testing.T) {
+	data := []struct {
+		description   string
+		codes         []string
+		expected      StatusSet
+		expectedError error
+	}{
+		{
+			description: "it should convert EPP status codes",
+			codes:       []string{"ok", "linked", "clientHold", "serverTransferProhibited"},
+			expected: StatusSet{
+				StatusActive,
+				StatusAssociated,
+				StatusClientHold,
+				StatusServerTransferProhibited,
+			},
+		},
+		{
+			description: "it should ignore repeated statuses",
+			codes:       []string{"clientHold", "clientHold"},
+			expected:    StatusSet{StatusClientHold},
+		},
+		{
+			description:   "it should detect an unknown EPP status code",
+			codes:         []string{"ok", "clientFrozen"},
+			expectedError: fmt.Errorf(`unknown EPP status "clientFrozen"`),
+		},
+	}
+
+	for i, item := range data {
+		set, err := NewStatusSetFromEPP(item.codes)
+
+		if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+			t.Errorf("[%d] %s: expected error “%s”, got “%s”", i, item.description, item.expectedError, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(item.expected, set) {
+			t.Errorf("[%d] %s: expected “%#v” and got “%#v”", i, item.description, item.expected, set)
+		}
+	}
+}
+
+func TestStatusSet(t *testing.T) {
+	set := StatusSet{StatusActive, StatusLocked}
+	set.Add(StatusServerHold, StatusActive)
+
+	if expected := (StatusSet{StatusActive, StatusLocked, StatusServerHold}); !reflect.DeepEqual(expected, set) {
+		t.Errorf("Unexpected set after add. Expected “%#v” and got “%#v”", expected, set)
+	}
+
+	if !set.Contains(StatusServerHold) {
+		t.Error("Expected set to contain “server hold”")
+	}
+
+	if set.ContainsAny(StatusClientHold, StatusPendingDelete) {
+		t.Error("Unexpected status found in the set")
+	}
+
+	if expected := []string{"ok", "serverHold"}; !reflect.DeepEqual(expected, set.EPP()) {
+		t.Errorf("Unexpected EPP codes. Expected “%#v” and got “%#v”", expected, set.EPP())
+	}
+
+	set.Remove(StatusActive)
+
+	if expected := (StatusSet{StatusLocked, StatusServerHold}); !reflect.DeepEqual(expected, set) {
+		t.Errorf("Unexpected set after remove. Expected “%#v” and got “%#v”", expected, set)
+	}
+
+	for epp, status := range eppStatuses {
+		if code, found := status.EPP(); !found || code != epp {
+			t.Errorf("Unexpected EPP mapping of “%s”. Expected “%s” and got “%s”", status, epp, code)
+		}
+	}
+}
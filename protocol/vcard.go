@@ -0,0 +1,548 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// List of vCard properties commonly used in RDAP responses, as described in
+// RFC 6350, section 6
+const (
+	VCardPropertyVersion = "version"
+	VCardPropertyKind    = "kind"
+	VCardPropertyFN      = "fn"
+	VCardPropertyN       = "n"
+	VCardPropertyOrg     = "org"
+	VCardPropertyTitle   = "title"
+	VCardPropertyRole    = "role"
+	VCardPropertyAdr     = "adr"
+	VCardPropertyTel     = "tel"
+	VCardPropertyEmail   = "email"
+	VCardPropertyURL     = "url"
+	VCardPropertyLang    = "lang"
+	VCardPropertyContact = "contact-uri"
+)
+
+// List of vCard property parameters used by the accessors, as described in
+// RFC 6350, section 5 and RFC 8605
+const (
+	VCardParameterType  = "type"
+	VCardParameterPref  = "pref"
+	VCardParameterLabel = "label"
+	VCardParameterCC    = "cc"
+)
+
+// VCard describes a vCard in the jCard format as it is in RFC 7095. It is the
+// typed representation of the Entity vcardArray member. The properties keep
+// the order and the values of the original jCard, so unknown properties are
+// preserved when the vCard is encoded again
+type VCard struct {
+	Properties []VCardProperty
+}
+
+// VCardProperty describes a jCard property as it is in RFC 7095, section
+// 3.3. Parameters store the values exactly as decoded (a string or an array of
+// strings), and each value can be a simple value or a structured value (array)
+type VCardProperty struct {
+	Name       string
+	Parameters map[string]interface{}
+	Type       string
+	Values     []interface{}
+}
+
+// VCardEmail stores the information of an email property
+type VCardEmail struct {
+	Address string
+	Types   []string
+	Pref    int
+}
+
+// VCardTel stores the information of a tel property
+type VCardTel struct {
+	Number string
+	Types  []string
+	Pref   int
+}
+
+// VCardAddress stores the information of an adr property. The components
+// follow the structured value of RFC 6350, section 6.3.1
+type VCardAddress struct {
+	POBox      string
+	Extended   string
+	Street     []string
+	Locality   string
+	Region     string
+	PostalCode string
+	Country    string
+	Label      string
+	CC         string
+	Types      []string
+	Pref       int
+}
+
+// ParseVCard converts the jCard array format, as found in the Entity
+// vcardArray member, into the typed vCard. Malformed properties are skipped,
+// so a single bad property doesn't hide the rest of the contact. The property
+// names are kept as sent by the server, as they are case insensitive (RFC
+// 6350, section 3.3)
+func ParseVCard(vcardArray []interface{}) (*VCard, error) {
+	if len(vcardArray) != 2 {
+		return nil, fmt.Errorf("invalid jCard: expected 2 items and got %d", len(vcardArray))
+	}
+
+	if name, ok := vcardArray[0].(string); !ok || name != "vcard" {
+		return nil, fmt.Errorf("invalid jCard: missing vcard identifier")
+	}
+
+	properties, ok := vcardArray[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid jCard: properties must be an array")
+	}
+
+	var vcard VCard
+	for _, p := range properties {
+		property, ok := p.([]interface{})
+		if !ok || len(property) < 4 {
+			continue
+		}
+
+		name, ok := property[0].(string)
+		if !ok || name == "" {
+			continue
+		}
+
+		parameters, ok := property[1].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		valueType, ok := property[2].(string)
+		if !ok {
+			continue
+		}
+
+		vcard.Properties = append(vcard.Properties, VCardProperty{
+			Name:       name,
+			Parameters: parameters,
+			Type:       valueType,
+			Values:     property[3:],
+		})
+	}
+
+	return &vcard, nil
+}
+
+// Array converts the vCard into the jCard array format, that can be stored
+// in the Entity vcardArray member
+func (v *VCard) Array() []interface{} {
+	properties := make([]interface{}, 0, len(v.Properties))
+
+	for _, property := range v.Properties {
+		properties = append(properties, property.array())
+	}
+
+	return []interface{}{"vcard", properties}
+}
+
+// array converts the property into the jCard array format
+func (p VCardProperty) array() []interface{} {
+	parameters := p.Parameters
+	if parameters == nil {
+		parameters = make(map[string]interface{})
+	}
+
+	item := []interface{}{p.Name, parameters, p.Type}
+	return append(item, p.Values...)
+}
+
+// MarshalJSON implements the json.Marshaler interface, encoding the vCard
+// in the jCard format
+func (v VCard) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Array())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, decoding the
+// vCard from the jCard format
+func (v *VCard) UnmarshalJSON(data []byte) error {
+	var vcardArray []interface{}
+	if err := json.Unmarshal(data, &vcardArray); err != nil {
+		return err
+	}
+
+	vcard, err := ParseVCard(vcardArray)
+	if err != nil {
+		return err
+	}
+
+	*v = *vcard
+	return nil
+}
+
+// Get returns all properties with the given name
+func (v *VCard) Get(name string) []VCardProperty {
+	var properties []VCardProperty
+	for _, property := range v.Properties {
+		if strings.EqualFold(property.Name, name) {
+			properties = append(properties, property)
+		}
+	}
+
+	return properties
+}
+
+// First returns the property with the given name and the lowest pref
+// parameter. When there's no preference the first property is returned
+func (v *VCard) First(name string) (property VCardProperty, found bool) {
+	for _, p := range v.Get(name) {
+		if !found || (p.Pref() > 0 && (property.Pref() == 0 || p.Pref() < property.Pref())) {
+			property = p
+			found = true
+		}
+	}
+
+	return
+}
+
+// Kind returns the kind of the object represented by the vCard (individual,
+// group, org, ...)
+func (v *VCard) Kind() string {
+	property, _ := v.First(VCardPropertyKind)
+	return property.Text()
+}
+
+// FN returns the formatted name of the vCard
+func (v *VCard) FN() string {
+	property, _ := v.First(VCardPropertyFN)
+	return property.Text()
+}
+
+// Org returns the organization name of the vCard
+func (v *VCard) Org() string {
+	property, _ := v.First(VCardPropertyOrg)
+	return property.Text()
+}
+
+// Emails returns all email addresses of the vCard
+func (v *VCard) Emails() []VCardEmail {
+	var emails []VCardEmail
+	for _, property := range v.Get(VCardPropertyEmail) {
+		emails = append(emails, VCardEmail{
+			Address: strings.TrimPrefix(property.Text(), "mailto:"),
+			Types:   property.Types(),
+			Pref:    property.Pref(),
+		})
+	}
+
+	return emails
+}
+
+// Tels returns all telephone numbers of the vCard. URI values have the "tel:"
+// scheme removed
+func (v *VCard) Tels() []VCardTel {
+	var tels []VCardTel
+	for _, property := range v.Get(VCardPropertyTel) {
+		tels = append(tels, VCardTel{
+			Number: strings.TrimPrefix(property.Text(), "tel:"),
+			Types:  property.Types(),
+			Pref:   property.Pref(),
+		})
+	}
+
+	return tels
+}
+
+// Addresses returns all postal addresses of the vCard
+func (v *VCard) Addresses() []VCardAddress {
+	var addresses []VCardAddress
+	for _, property := range v.Get(VCardPropertyAdr) {
+		address := VCardAddress{
+			Label: property.Parameter(VCardParameterLabel),
+			CC:    property.Parameter(VCardParameterCC),
+			Types: property.Types(),
+			Pref:  property.Pref(),
+		}
+
+		var components []interface{}
+		if len(property.Values) > 0 {
+			components, _ = property.Values[0].([]interface{})
+		}
+
+		component := func(i int) []string {
+			if i >= len(components) {
+				return nil
+			}
+			return vcardStrings(components[i])
+		}
+
+		address.POBox = strings.Join(component(0), ",")
+		address.Extended = strings.Join(component(1), ",")
+		address.Street = component(2)
+		address.Locality = strings.Join(component(3), ",")
+		address.Region = strings.Join(component(4), ",")
+		address.PostalCode = strings.Join(component(5), ",")
+		address.Country = strings.Join(component(6), ",")
+		addresses = append(addresses, address)
+	}
+
+	return addresses
+}
+
+// Text returns the first value of the property as a string. Structured
+// values have their components joined with ";", as in the vCard text format
+func (p VCardProperty) Text() string {
+	if len(p.Values) == 0 {
+		return ""
+	}
+
+	switch value := p.Values[0].(type) {
+	case string:
+		return value
+	case []interface{}:
+		var components []string
+		for _, component := range value {
+			components = append(components, strings.Join(vcardStrings(component), ","))
+		}
+		return strings.Join(components, ";")
+	case nil:
+		return ""
+	}
+
+	return fmt.Sprintf("%v", p.Values[0])
+}
+
+// Parameter returns the value of a parameter. Parameters with multiple
+// values have them joined with ","
+func (p VCardProperty) Parameter(name string) string {
+	return strings.Join(p.ParameterValues(name), ",")
+}
+
+// ParameterValues returns all values of a parameter
+func (p VCardProperty) ParameterValues(name string) []string {
+	for key, value := range p.Parameters {
+		if strings.ToLower(key) == strings.ToLower(name) {
+			return vcardStrings(value)
+		}
+	}
+
+	return nil
+}
+
+// Types returns the values of the type parameter
+func (p VCardProperty) Types() []string {
+	var types []string
+	for _, value := range p.ParameterValues(VCardParameterType) {
+		// the type parameter could also be a comma separated list
+		types = append(types, strings.Split(value, ",")...)
+	}
+
+	return types
+}
+
+// HasType checks if the property has the given type parameter value
+func (p VCardProperty) HasType(t string) bool {
+	for _, value := range p.Types() {
+		if strings.ToLower(value) == strings.ToLower(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Pref returns the preference of the property (1 is the most preferred). When
+// the property has no preference 0 is returned
+func (p VCardProperty) Pref() int {
+	pref, err := strconv.Atoi(p.Parameter(VCardParameterPref))
+	if err != nil {
+		return 0
+	}
+
+	return pref
+}
+
+// NewVCard starts a vCard with the version property, that is mandatory in
+// RFC 6350. The other properties can be added with the builder methods
+func NewVCard() *VCard {
+	return &VCard{
+		Properties: []VCardProperty{
+			{Name: VCardPropertyVersion, Type: "text", Values: []interface{}{"4.0"}},
+		},
+	}
+}
+
+// Add appends a property to the vCard
+func (v *VCard) Add(property VCardProperty) *VCard {
+	property.Name = strings.ToLower(property.Name)
+	if property.Parameters == nil {
+		property.Parameters = make(map[string]interface{})
+	}
+
+	v.Properties = append(v.Properties, property)
+	return v
+}
+
+// AddText appends a property with a single text value
+func (v *VCard) AddText(name, value string) *VCard {
+	return v.Add(VCardProperty{Name: name, Type: "text", Values: []interface{}{value}})
+}
+
+// AddKind appends the kind of the object represented by the vCard
+func (v *VCard) AddKind(kind string) *VCard {
+	return v.AddText(VCardPropertyKind, kind)
+}
+
+// AddFN appends the formatted name
+func (v *VCard) AddFN(name string) *VCard {
+	return v.AddText(VCardPropertyFN, name)
+}
+
+// AddOrg appends the organization name
+func (v *VCard) AddOrg(org string) *VCard {
+	return v.AddText(VCardPropertyOrg, org)
+}
+
+// AddEmail appends an email address
+func (v *VCard) AddEmail(email VCardEmail) *VCard {
+	return v.Add(VCardProperty{
+		Name:       VCardPropertyEmail,
+		Parameters: vcardParameters(email.Types, email.Pref),
+		Type:       "text",
+		Values:     []interface{}{email.Address},
+	})
+}
+
+// AddTel appends a telephone number, using the tel URI format as
+// recommended by RFC 6350, section 6.4.1
+func (v *VCard) AddTel(tel VCardTel) *VCard {
+	number := tel.Number
+	if !strings.HasPrefix(number, "tel:") {
+		number = "tel:" + number
+	}
+
+	return v.Add(VCardProperty{
+		Name:       VCardPropertyTel,
+		Parameters: vcardParameters(tel.Types, tel.Pref),
+		Type:       "uri",
+		Values:     []interface{}{number},
+	})
+}
+
+// AddAddress appends a postal address
+func (v *VCard) AddAddress(address VCardAddress) *VCard {
+	parameters := vcardParameters(address.Types, address.Pref)
+	if address.Label != "" {
+		parameters[VCardParameterLabel] = address.Label
+	}
+
+	if address.CC != "" {
+		parameters[VCardParameterCC] = address.CC
+	}
+
+	var street interface{} = ""
+	switch len(address.Street) {
+	case 0:
+	case 1:
+		street = address.Street[0]
+	default:
+		streetLines := make([]interface{}, 0, len(address.Street))
+		for _, line := range address.Street {
+			streetLines = append(streetLines, line)
+		}
+		street = streetLines
+	}
+
+	return v.Add(VCardProperty{
+		Name:       VCardPropertyAdr,
+		Parameters: parameters,
+		Type:       "text",
+		Values: []interface{}{
+			[]interface{}{
+				address.POBox,
+				address.Extended,
+				street,
+				address.Locality,
+				address.Region,
+				address.PostalCode,
+				address.Country,
+			},
+		},
+	})
+}
+
+// VCard parses the vcardArray member of the entity into the typed vCard. When
+// the server only sent the contact information in the JSContact format, it is
+// converted to a vCard. If the entity has no contact information, nil is
+// returned without error. The JSContact card should only be used when the
+// response declares the JSContactConformance (the rdap Client removes it
+// otherwise)
+func (e *Entity) VCard() (*VCard, error) {
+	if len(e.VCardArray) == 0 {
+		if e.JSContactCard != nil {
+			return JSContactToVCard(e.JSContactCard)
+		}
+
+		return nil, nil
+	}
+
+	return ParseVCard(e.VCardArray)
+}
+
+// SetVCard stores the vCard in the vcardArray member of the entity
+func (e *Entity) SetVCard(vcard *VCard) {
+	if vcard == nil {
+		e.VCardArray = nil
+		return
+	}
+
+	e.VCardArray = vcard.Array()
+}
+
+func vcardParameters(types []string, pref int) map[string]interface{} {
+	parameters := make(map[string]interface{})
+
+	switch len(types) {
+	case 0:
+	case 1:
+		parameters[VCardParameterType] = types[0]
+	default:
+		values := make([]interface{}, 0, len(types))
+		for _, t := range types {
+			values = append(values, t)
+		}
+		parameters[VCardParameterType] = values
+	}
+
+	if pref > 0 {
+		parameters[VCardParameterPref] = strconv.Itoa(pref)
+	}
+
+	return parameters
+}
+
+// vcardStrings converts a jCard value, that could be a string, a number or an
+// array, into a list of strings
+func vcardStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			values = append(values, vcardStrings(item)...)
+		}
+		return values
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	}
+
+	return []string{fmt.Sprintf("%v", value)}
+}
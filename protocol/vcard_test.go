@@ -0,0 +1,252 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// jCard example from RFC 9083, section 5.1, with an unknown property
+const vcardExample = `["vcard",[
+  ["version",{},"text","4.0"],
+  ["fn",{},"text","Joe User"],
+  ["n",{},"text",["User","Joe","","",["ing. jr","M.Sc."]]],
+  ["kind",{},"text","individual"],
+  ["lang",{"pref":"1"},"language-tag","fr"],
+  ["lang",{"pref":"2"},"language-tag","en"],
+  ["org",{"type":"work"},"text","Example"],
+  ["title",{},"text","Research Scientist"],
+  ["role",{},"text","Project Lead"],
+  ["adr",{"type":"work"},"text",["","Suite 1234","4321 Rue Somewhere","Quebec","QC","G1V 2M2","Canada"]],
+  ["adr",{"type":"home","label":"123 Maple Ave\nSuite 90001\nVancouver\nBC\n1239\n","cc":"CA"},"text",["","","","","","",""]],
+  ["tel",{"type":["work","voice"],"pref":"1"},"uri","tel:+1-555-555-1234;ext=102"],
+  ["tel",{"type":["work","cell","voice","video","text"]},"uri","tel:+1-555-555-4321"],
+  ["email",{"type":"work"},"text","joe.user@example.com"],
+  ["geo",{"type":"work"},"uri","geo:46.772673,-71.282945"],
+  ["key",{"type":"work"},"uri","https://www.example.com/joe.user/joe.asc"],
+  ["tz",{},"utc-offset","-05:00"],
+  ["x-custom",{"x-param":["a","b"]},"unknown",1,2,{"c":true}],
+  ["url",{"type":"home"},"uri","https://example.org"]
+]]`
+
+func TestParseVCard(t *testing.T) {
+	var vcardArray []interface{}
+	if err := json.Unmarshal([]byte(vcardExample), &vcardArray); err != nil {
+		t.Fatal(err)
+	}
+
+	vcard, err := ParseVCard(vcardArray)
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	if fn := vcard.FN(); fn != "Joe User" {
+		t.Errorf("Unexpected fn. Expected “Joe User” and got “%s”", fn)
+	}
+
+	if kind := vcard.Kind(); kind != "individual" {
+		t.Errorf("Unexpected kind. Expected “individual” and got “%s”", kind)
+	}
+
+	if org := vcard.Org(); org != "Example" {
+		t.Errorf("Unexpected org. Expected “Example” and got “%s”", org)
+	}
+
+	if lang, _ := vcard.First(VCardPropertyLang); lang.Text() != "fr" {
+		t.Errorf("Unexpected preferred lang. Expected “fr” and got “%s”", lang.Text())
+	}
+
+	expectedEmails := []VCardEmail{
+		{Address: "joe.user@example.com", Types: []string{"work"}},
+	}
+
+	if emails := vcard.Emails(); !reflect.DeepEqual(expectedEmails, emails) {
+		t.Errorf("Unexpected emails. Expected “%#v” and got “%#v”", expectedEmails, emails)
+	}
+
+	expectedTels := []VCardTel{
+		{Number: "+1-555-555-1234;ext=102", Types: []string{"work", "voice"}, Pref: 1},
+		{Number: "+1-555-555-4321", Types: []string{"work", "cell", "voice", "video", "text"}},
+	}
+
+	if tels := vcard.Tels(); !reflect.DeepEqual(expectedTels, tels) {
+		t.Errorf("Unexpected tels. Expected “%#v” and got “%#v”", expectedTels, tels)
+	}
+
+	expectedAddresses := []VCardAddress{
+		{
+			Extended:   "Suite 1234",
+			Street:     []string{"4321 Rue Somewhere"},
+			Locality:   "Quebec",
+			Region:     "QC",
+			PostalCode: "G1V 2M2",
+			Country:    "Canada",
+			Types:      []string{"work"},
+		},
+		{
+			Label: "123 Maple Ave\nSuite 90001\nVancouver\nBC\n1239\n",
+			CC:    "CA",
+			Types: []string{"home"},
+		},
+	}
+
+	if addresses := vcard.Addresses(); !reflect.DeepEqual(expectedAddresses, addresses) {
+		t.Errorf("Unexpected addresses. Expected “%#v” and got “%#v”", expectedAddresses, addresses)
+	}
+
+	if n, _ := vcard.First(VCardPropertyN); n.Text() != "User;Joe;;;ing. jr,M.Sc." {
+		t.Errorf("Unexpected n. Expected “User;Joe;;;ing. jr,M.Sc.” and got “%s”", n.Text())
+	}
+}
+
+func TestParseVCardErrors(t *testing.T) {
+	data := []struct {
+		description   string
+		vcardArray    []interface{}
+		expectedError error
+	}{
+		{
+			description:   "it should detect an invalid number of items",
+			vcardArray:    []interface{}{"vcard"},
+			expectedError: fmt.Errorf("invalid jCard: expected 2 items and got 1"),
+		},
+		{
+			description:   "it should detect a missing vcard identifier",
+			vcardArray:    []interface{}{"vcalendar", []interface{}{}},
+			expectedError: fmt.Errorf("invalid jCard: missing vcard identifier"),
+		},
+		{
+			description:   "it should detect invalid properties",
+			vcardArray:    []interface{}{"vcard", "fn"},
+			expectedError: fmt.Errorf("invalid jCard: properties must be an array"),
+		},
+	}
+
+	for i, item := range data {
+		_, err := ParseVCard(item.vcardArray)
+
+		if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+			t.Errorf("[%d] %s: expected error “%s”, got “%s”", i, item.description, item.expectedError, err)
+		}
+	}
+}
+
+func TestParseVCardMalformedProperties(t *testing.T) {
+	vcard, err := ParseVCard([]interface{}{"vcard", []interface{}{
+		[]interface{}{"version", map[string]interface{}{}, "text", "4.0"},
+		[]interface{}{"fn", map[string]interface{}{}, "text"},
+		[]interface{}{"tel", "params", "uri", "tel:+1-555-555-1234"},
+		[]interface{}{10, map[string]interface{}{}, "text", "Joe"},
+		[]interface{}{"email", map[string]interface{}{}, 5, "joe.user@example.com"},
+		"adr",
+		[]interface{}{"EMAIL", map[string]interface{}{}, "text", "joe.user@example.com"},
+	}})
+
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	expected := &VCard{
+		Properties: []VCardProperty{
+			{Name: "version", Parameters: map[string]interface{}{}, Type: "text", Values: []interface{}{"4.0"}},
+			{Name: "EMAIL", Parameters: map[string]interface{}{}, Type: "text", Values: []interface{}{"joe.user@example.com"}},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, vcard) {
+		t.Errorf("Unexpected vCard. Expected “%#v” and got “%#v”", expected, vcard)
+	}
+
+	if emails := vcard.Emails(); len(emails) != 1 || emails[0].Address != "joe.user@example.com" {
+		t.Errorf("Unexpected emails “%#v”", emails)
+	}
+
+	if data, err := json.Marshal(vcard); err != nil || !strings.Contains(string(data), `"EMAIL"`) {
+		t.Errorf("Expected the original property name in “%s” (%v)", data, err)
+	}
+}
+
+func TestVCardRoundTrip(t *testing.T) {
+	var expected interface{}
+	if err := json.Unmarshal([]byte(vcardExample), &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	var vcard VCard
+	if err := json.Unmarshal([]byte(vcardExample), &vcard); err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	data, err := json.Marshal(vcard)
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("Unexpected jCard. Expected “%#v” and got “%#v”", expected, result)
+	}
+}
+
+func TestVCardBuilder(t *testing.T) {
+	vcard := NewVCard().
+		AddKind("org").
+		AddFN("Example Inc.").
+		AddOrg("Example").
+		AddEmail(VCardEmail{Address: "abuse@example.com", Types: []string{"work"}, Pref: 1}).
+		AddTel(VCardTel{Number: "+55.1155093500", Types: []string{"work", "voice"}}).
+		AddAddress(VCardAddress{
+			Street:     []string{"Av. das Nações Unidas, 11541", "7o andar"},
+			Locality:   "São Paulo",
+			Region:     "SP",
+			PostalCode: "04578-000",
+			CC:         "BR",
+		})
+
+	var entity Entity
+	entity.SetVCard(vcard)
+
+	data, err := json.Marshal(entity.VCardArray)
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	expected := `["vcard",[` +
+		`["version",{},"text","4.0"],` +
+		`["kind",{},"text","org"],` +
+		`["fn",{},"text","Example Inc."],` +
+		`["org",{},"text","Example"],` +
+		`["email",{"pref":"1","type":"work"},"text","abuse@example.com"],` +
+		`["tel",{"type":["work","voice"]},"uri","tel:+55.1155093500"],` +
+		`["adr",{"cc":"BR"},"text",["","",["Av. das Nações Unidas, 11541","7o andar"],"São Paulo","SP","04578-000",""]]` +
+		`]]`
+
+	if string(data) != expected {
+		t.Errorf("Unexpected jCard. Expected “%s” and got “%s”", expected, string(data))
+	}
+
+	parsed, err := entity.VCard()
+	if err != nil {
+		t.Fatalf("unexpected error “%s”", err)
+	}
+
+	expectedAddresses := []VCardAddress{
+		{
+			Street:     []string{"Av. das Nações Unidas, 11541", "7o andar"},
+			Locality:   "São Paulo",
+			Region:     "SP",
+			PostalCode: "04578-000",
+			CC:         "BR",
+		},
+	}
+
+	if addresses := parsed.Addresses(); !reflect.DeepEqual(expectedAddresses, addresses) {
+		t.Errorf("Unexpected addresses. Expected “%#v” and got “%#v”", expectedAddresses, addresses)
+	}
+}
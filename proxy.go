@@ -0,0 +1,325 @@
+package rdap
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+const (
+	// DefaultProxyCacheSize is the maximum number of responses kept in the
+	// proxy cache
+	DefaultProxyCacheSize = 1000
+)
+
+// errOutboundLimit is returned by the proxy HTTP client when the queries to
+// an authoritative server are over the outbound limit
+var errOutboundLimit = errors.New("outbound rate limit exceeded")
+
+// Proxy is an ObjectHandler that answers the queries with the responses of
+// the authoritative RDAP servers, found with the bootstrap (see
+// NewBootstrapFetcher). The responses are proxied, with the self link
+// rewritten to the proxy URL, or the clients are redirected to the
+// authoritative servers as described in RFC 7480, section 5.2. Queries that
+// are not supported by the bootstrap (e.g. entities) are answered as not
+// found
+type Proxy struct {
+	// Redirect answers with redirects instead of proxying the responses
+	Redirect bool
+
+	// CacheTTL is the time that the proxied responses are cached. If zero the
+	// responses are not cached
+	CacheTTL time.Duration
+
+	// CacheSize is the maximum number of cached responses
+	CacheSize int
+
+	// Outbound limits the queries sent to each authoritative server (the
+	// action is ignored). When over the limit the client is answered with
+	// 429 Too Many Requests. If the number of queries is zero there's no
+	// limit
+	Outbound RateLimitPolicy
+
+	// ForwardClientIP sends the client address to the authoritative servers
+	// in the X-Forwarded-For header. It's disabled by default, as the
+	// address of the client is personal data
+	ForwardClientIP bool
+
+	// AuditLog logs each query answered by the proxy, with the client
+	// address, the authoritative server and the result. If nil the queries
+	// are not logged
+	AuditLog *log.Logger
+
+	fetcher Fetcher
+
+	mu      sync.Mutex
+	cache   map[string]proxyCacheEntry
+	buckets map[string]*rateLimitBucket
+
+	// now is replaced in the tests
+	now func() time.Time
+}
+
+type proxyCacheEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewProxy returns a proxy that finds the authoritative servers with the
+// bootstrap registries of bootstrapURI (e.g. IANABootstrap)
+func NewProxy(httpClient httpClient, bootstrapURI string, cacheDetector CacheDetector) *Proxy {
+	p := &Proxy{
+		CacheSize: DefaultProxyCacheSize,
+		cache:     make(map[string]proxyCacheEntry),
+		buckets:   make(map[string]*rateLimitBucket),
+	}
+
+	p.fetcher = NewBootstrapFetcher(proxyHTTPClient{proxy: p, client: httpClient}, bootstrapURI, cacheDetector)
+	return p
+}
+
+// ServeObject implements the ObjectHandler interface
+func (p *Proxy) ServeObject(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+	start := p.currentTime()
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	audit := func(upstream, result string) {
+		if p.AuditLog != nil {
+			p.AuditLog.Printf("rdap proxy: %s %s/%s %s %s (%s)", client, queryType, queryValue,
+				upstream, result, p.currentTime().Sub(start))
+		}
+	}
+
+	if _, ok := newBootstrapQueryType(queryType, queryValue); !ok {
+		audit("-", "not supported")
+		return nil, ErrNotFound
+	}
+
+	key := string(queryType) + "/" + queryValue
+	if data, ok := p.cached(key); ok && !p.Redirect {
+		audit("-", "cached")
+		return p.decode(r, data)
+	}
+
+	// the header is changed by the fetcher, so it can't be reused
+	header := make(http.Header)
+	if p.ForwardClientIP {
+		header.Set("X-Forwarded-For", client)
+	}
+
+	resp, err := p.fetcher.Fetch(nil, queryType, queryValue, header, nil)
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+
+	upstream := "-"
+	if resp != nil && resp.Request != nil {
+		upstream = resp.Request.URL.String()
+	}
+
+	if err != nil {
+		audit(upstream, err.Error())
+		return nil, proxyError(err)
+	}
+
+	if p.Redirect {
+		audit(upstream, "redirect")
+		return &Redirect{URL: upstream}, nil
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		audit(upstream, err.Error())
+		return nil, proxyError(err)
+	}
+
+	object, err := p.decode(r, data)
+	if err != nil {
+		audit(upstream, err.Error())
+		return nil, proxyError(err)
+	}
+
+	p.store(key, data)
+	audit(upstream, "ok")
+	return object, nil
+}
+
+// decode parses the authoritative server response, rewriting the self link
+// to the proxy URL
+func (p *Proxy) decode(r *http.Request, data []byte) (interface{}, error) {
+	object, err := protocol.DecodeObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	self := searchURL(r)
+	self.RawQuery = ""
+
+	links := protocol.ObjectLinks(object)
+	for i := range links {
+		if links[i].Rel == "self" {
+			links[i].Value = self.String()
+			links[i].Href = self.String()
+		}
+	}
+
+	return object, nil
+}
+
+func (p *Proxy) cached(key string) ([]byte, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.cache[key]
+	if !ok || !p.currentTime().Before(entry.expires) {
+		return nil, false
+	}
+
+	return entry.data, true
+}
+
+func (p *Proxy) store(key string, data []byte) {
+	if p.CacheTTL <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cache == nil {
+		p.cache = make(map[string]proxyCacheEntry)
+	}
+
+	size := p.CacheSize
+	if size <= 0 {
+		size = DefaultProxyCacheSize
+	}
+
+	now := p.currentTime()
+
+	if len(p.cache) >= size {
+		for k, entry := range p.cache {
+			if !now.Before(entry.expires) {
+				delete(p.cache, k)
+			}
+		}
+	}
+
+	// when there's no expired response the oldest one is removed. All
+	// responses have the same TTL, so the oldest expires first
+	for len(p.cache) >= size {
+		var oldest string
+		for k, entry := range p.cache {
+			if oldest == "" || entry.expires.Before(p.cache[oldest].expires) {
+				oldest = k
+			}
+		}
+		delete(p.cache, oldest)
+	}
+
+	p.cache[key] = proxyCacheEntry{data: data, expires: now.Add(p.CacheTTL)}
+}
+
+// allowOutbound consumes a query of the authoritative server quota
+func (p *Proxy) allowOutbound(host string) bool {
+	if p.Outbound.Queries <= 0 || p.Outbound.Period <= 0 {
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.buckets == nil {
+		p.buckets = make(map[string]*rateLimitBucket)
+	}
+
+	now := p.currentTime()
+
+	bucket, ok := p.buckets[host]
+	if !ok {
+		bucket = &rateLimitBucket{tokens: float64(p.Outbound.Queries), last: now}
+		p.buckets[host] = bucket
+	}
+
+	return bucket.take(p.Outbound, now)
+}
+
+func (p *Proxy) currentTime() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+
+	return time.Now()
+}
+
+// proxyError converts the errors of the authoritative servers to the errors
+// answered by the proxy
+func proxyError(err error) error {
+	switch err.(type) {
+	case protocol.Error, *protocol.Error:
+		return err
+	case bootstrapNoMatchError:
+		return ErrNotFound
+	}
+
+	switch err {
+	case ErrNotFound, ErrForbidden:
+		return err
+
+	case errOutboundLimit:
+		return protocol.Error{
+			ErrorCode:   http.StatusTooManyRequests,
+			Title:       "too many requests",
+			Description: []string{"the query limit of the authoritative server was exceeded"},
+		}
+	}
+
+	return protocol.Error{
+		ErrorCode:   http.StatusBadGateway,
+		Title:       "bad gateway",
+		Description: []string{"the authoritative server couldn't be queried"},
+	}
+}
+
+// proxyHTTPClient sends the requests of the proxy, applying the outbound
+// limits. In redirect mode the RDAP requests are not sent, only the URL is
+// needed
+type proxyHTTPClient struct {
+	proxy  *Proxy
+	client httpClient
+}
+
+func (c proxyHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	// the bootstrap registries are requested with the generic JSON media type
+	rdapRequest := req.Header.Get("Accept") == ContentTypeRDAP
+
+	if rdapRequest && c.proxy.Redirect {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{ContentTypeRDAP}},
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+			Request:    req,
+		}, nil
+	}
+
+	if rdapRequest && !c.proxy.allowOutbound(req.URL.Host) {
+		return nil, errOutboundLimit
+	}
+
+	resp, err := c.client.Do(req)
+	if resp != nil && resp.Request == nil {
+		resp.Request = req
+	}
+
+	return resp, err
+}
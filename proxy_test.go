@@ -0,0 +1,307 @@
+package rdap
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestProxyServeObject(t *testing.T) {
+	queries := make(map[string]int)
+	var forwardedFor string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rdap/", func(w http.ResponseWriter, r *http.Request) {
+		queries[r.URL.Path]++
+
+		switch r.URL.Path {
+		case "/rdap/domain/example.br", "/rdap/domain/private.br":
+			forwardedFor = r.Header.Get("X-Forwarded-For")
+
+			fqdn := strings.TrimPrefix(r.URL.Path, "/rdap/domain/")
+			w.Header().Set("Content-Type", "application/rdap+json")
+			fmt.Fprintf(w, `{"objectClassName":"domain","ldhName":"%s",`+
+				`"links":[{"value":"https://rdap.registro.br/domain/%s","rel":"self","href":"https://rdap.registro.br/domain/%s"}]}`,
+				fqdn, fqdn, fqdn)
+
+		case "/rdap/domain/error.br":
+			w.Header().Set("Content-Type", "application/rdap+json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"errorCode":429,"title":"too many requests"}`)
+
+		case "/rdap/domain/down.br":
+			w.WriteHeader(http.StatusInternalServerError)
+
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	builder := NewBootstrapBuilder()
+	builder.AddDNS([]string{"br"}, server.URL+"/rdap/")
+
+	bootstrap, err := NewBootstrapHandler(builder, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Handle("/bootstrap/", bootstrap)
+
+	data := []struct {
+		description          string
+		redirect             bool
+		forwardClientIP      bool
+		queryType            QueryType
+		queryValue           string
+		expected             interface{}
+		expectedForwardedFor string
+		expectedError        error
+	}{
+		{
+			description:          "it should proxy a domain rewriting the self link",
+			forwardClientIP:      true,
+			queryType:            QueryTypeDomain,
+			queryValue:           "example.br",
+			expectedForwardedFor: "192.0.2.1",
+			expected: &protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "example.br",
+				Links: []protocol.Link{
+					{
+						Value: "http://proxy.example.com/domain/example.br",
+						Rel:   "self",
+						Href:  "http://proxy.example.com/domain/example.br",
+					},
+				},
+			},
+		},
+		{
+			description: "it should not forward the client address by default",
+			queryType:   QueryTypeDomain,
+			queryValue:  "private.br",
+			expected: &protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "private.br",
+				Links: []protocol.Link{
+					{
+						Value: "http://proxy.example.com/domain/private.br",
+						Rel:   "self",
+						Href:  "http://proxy.example.com/domain/private.br",
+					},
+				},
+			},
+		},
+		{
+			description: "it should redirect to the authoritative server",
+			redirect:    true,
+			queryType:   QueryTypeDomain,
+			queryValue:  "example.br",
+			expected:    &Redirect{URL: server.URL + "/rdap/domain/example.br"},
+		},
+		{
+			description:   "it should detect an object that doesn't exist",
+			queryType:     QueryTypeDomain,
+			queryValue:    "missing.br",
+			expectedError: ErrNotFound,
+		},
+		{
+			description:   "it should detect a query without authoritative server",
+			queryType:     QueryTypeDomain,
+			queryValue:    "example.com",
+			expectedError: ErrNotFound,
+		},
+		{
+			description:   "it should detect a query type not supported by the bootstrap",
+			queryType:     QueryTypeEntity,
+			queryValue:    "EXAMPLE",
+			expectedError: ErrNotFound,
+		},
+		{
+			description:   "it should forward the authoritative server error",
+			queryType:     QueryTypeDomain,
+			queryValue:    "error.br",
+			expectedError: protocol.Error{ErrorCode: http.StatusTooManyRequests, Title: "too many requests"},
+		},
+		{
+			description: "it should detect an authoritative server failure",
+			queryType:   QueryTypeDomain,
+			queryValue:  "down.br",
+			expectedError: protocol.Error{
+				ErrorCode:   http.StatusBadGateway,
+				Title:       "bad gateway",
+				Description: []string{"the authoritative server couldn't be queried"},
+			},
+		},
+	}
+
+	for i, item := range data {
+		var audit bytes.Buffer
+		forwardedFor = ""
+
+		proxy := NewProxy(http.DefaultClient, server.URL+"/bootstrap/%s.json", nil)
+		proxy.Redirect = item.redirect
+		proxy.ForwardClientIP = item.forwardClientIP
+		proxy.AuditLog = log.New(&audit, "", 0)
+
+		r := httptest.NewRequest("GET", "/domain/"+item.queryValue, nil)
+		r.Host = "proxy.example.com"
+		r.RemoteAddr = "192.0.2.1:12345"
+
+		object, err := proxy.ServeObject(r, item.queryType, item.queryValue)
+		if !reflect.DeepEqual(item.expectedError, err) {
+			t.Errorf("[%d] %s: expected error “%v”, got “%v”", i, item.description, item.expectedError, err)
+
+		} else if !reflect.DeepEqual(item.expected, object) {
+			t.Errorf("[%d] %s: mismatch results.\n%v", i, item.description, diff(item.expected, object))
+		}
+
+		if forwardedFor != item.expectedForwardedFor {
+			t.Errorf("[%d] %s: expected X-Forwarded-For “%s” and got “%s”", i, item.description, item.expectedForwardedFor, forwardedFor)
+		}
+
+		if !strings.HasPrefix(audit.String(), "rdap proxy: 192.0.2.1 "+string(item.queryType)+"/"+item.queryValue+" ") {
+			t.Errorf("[%d] %s: unexpected audit log “%s”", i, item.description, audit.String())
+		}
+	}
+
+	if queries["/rdap/domain/example.br"] != 1 {
+		t.Errorf("Redirect mode shouldn't query the authoritative server. Expected “1” query and got “%d”",
+			queries["/rdap/domain/example.br"])
+	}
+}
+
+func TestProxyCache(t *testing.T) {
+	queries := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rdap/", func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		w.Header().Set("Content-Type", "application/rdap+json")
+		fmt.Fprintf(w, `{"objectClassName":"domain","ldhName":"%s"}`, strings.TrimPrefix(r.URL.Path, "/rdap/domain/"))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	builder := NewBootstrapBuilder()
+	builder.AddDNS([]string{"br"}, server.URL+"/rdap/")
+
+	bootstrap, err := NewBootstrapHandler(builder, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Handle("/bootstrap/", bootstrap)
+
+	now := time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	proxy := NewProxy(http.DefaultClient, server.URL+"/bootstrap/%s.json", nil)
+	proxy.CacheTTL = time.Minute
+	proxy.CacheSize = 2
+	proxy.Outbound = RateLimitPolicy{Queries: 3, Period: time.Hour}
+	proxy.now = func() time.Time { return now }
+
+	tooManyRequests := protocol.Error{
+		ErrorCode:   http.StatusTooManyRequests,
+		Title:       "too many requests",
+		Description: []string{"the query limit of the authoritative server was exceeded"},
+	}
+
+	data := []struct {
+		description     string
+		queryValue      string
+		elapsed         time.Duration
+		expectedQueries int
+		expectedError   error
+	}{
+		{
+			description:     "it should query the authoritative server",
+			queryValue:      "example.br",
+			expectedQueries: 1,
+		},
+		{
+			description:     "it should answer from the cache",
+			queryValue:      "example.br",
+			elapsed:         30 * time.Second,
+			expectedQueries: 1,
+		},
+		{
+			description:     "it should query the authoritative server when the cache expires",
+			queryValue:      "example.br",
+			elapsed:         time.Minute,
+			expectedQueries: 2,
+		},
+		{
+			description:     "it should query another domain",
+			queryValue:      "example2.br",
+			expectedQueries: 3,
+		},
+		{
+			description:     "it should apply the outbound limit",
+			queryValue:      "example3.br",
+			expectedQueries: 3,
+			expectedError:   tooManyRequests,
+		},
+		{
+			description:     "it should answer from the cache when over the outbound limit",
+			queryValue:      "example2.br",
+			expectedQueries: 3,
+		},
+	}
+
+	for i, item := range data {
+		now = now.Add(item.elapsed)
+
+		r := httptest.NewRequest("GET", "/domain/"+item.queryValue, nil)
+		object, err := proxy.ServeObject(r, QueryTypeDomain, item.queryValue)
+		if !reflect.DeepEqual(item.expectedError, err) {
+			t.Errorf("[%d] %s: expected error “%v”, got “%v”", i, item.description, item.expectedError, err)
+
+		} else if err == nil && object.(*protocol.Domain).LDHName != item.queryValue {
+			t.Errorf("[%d] %s: unexpected domain “%s”", i, item.description, object.(*protocol.Domain).LDHName)
+		}
+
+		if queries != item.expectedQueries {
+			t.Errorf("[%d] %s: expected “%d” queries and got “%d”", i, item.description, item.expectedQueries, queries)
+		}
+	}
+
+	if len(proxy.cache) > proxy.CacheSize {
+		t.Errorf("Cache size exceeded. Expected at most “%d” responses and got “%d”", proxy.CacheSize, len(proxy.cache))
+	}
+}
+
+func TestProxyCacheEviction(t *testing.T) {
+	now := time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	proxy := &Proxy{CacheTTL: time.Minute, CacheSize: 3}
+	proxy.now = func() time.Time { return now }
+
+	for _, key := range []string{"domain/a.br", "domain/b.br", "domain/c.br", "domain/d.br", "domain/e.br"} {
+		proxy.store(key, []byte(key))
+		now = now.Add(time.Second)
+	}
+
+	if _, ok := proxy.cached("domain/a.br"); ok {
+		t.Error("Expected the oldest response to be removed")
+	}
+
+	if _, ok := proxy.cached("domain/b.br"); ok {
+		t.Error("Expected the second oldest response to be removed")
+	}
+
+	for _, key := range []string{"domain/c.br", "domain/d.br", "domain/e.br"} {
+		if _, ok := proxy.cached(key); !ok {
+			t.Errorf("Expected the response “%s” in the cache", key)
+		}
+	}
+}
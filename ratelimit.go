@@ -0,0 +1,320 @@
+package rdap
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+const (
+	// RateLimitReject answers the queries over the limit with 429 Too Many
+	// Requests, as described in RFC 7480, section 5.5
+	RateLimitReject RateLimitAction = iota
+
+	// RateLimitTruncate answers the queries over the limit with the object
+	// without the entities (and the entities without the contact data),
+	// adding a remark with the type
+	// protocol.RemarkTypeObjectTruncatedServerPolicy. Objects that can't be
+	// truncated (e.g. search results) are rejected
+	RateLimitTruncate
+)
+
+// RateLimitAction defines how the queries over the limit are answered
+type RateLimitAction int
+
+// RateLimitError is returned by the limited backends when the query is
+// rejected. The server answers with 429 Too Many Requests and the
+// Retry-After header (RFC 6585, section 4)
+type RateLimitError struct {
+	Response protocol.Error
+
+	// RetryAfter is the time until the client quota allows a new query
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e RateLimitError) Error() string {
+	return e.Response.Error()
+}
+
+// RateLimitPolicy is the number of queries that each client can send in a
+// period. The client can send all the queries at once, and the quota is
+// recovered gradually along the period
+type RateLimitPolicy struct {
+	Queries int
+	Period  time.Duration
+	Action  RateLimitAction
+}
+
+// RateLimiter limits the number of queries of each client IP address, with a
+// policy for each query type. The client IP address is retrieved from the
+// X-Forwarded-For header when the request comes from a trusted proxy. Use
+// Limit to apply the limits to the server backends
+type RateLimiter struct {
+	// Policies stores the policy of each query type. Query types without a
+	// policy are not limited
+	Policies map[QueryType]RateLimitPolicy
+
+	// TrustedProxies are the networks of the reverse proxies that inform the
+	// client IP address in the X-Forwarded-For header
+	TrustedProxies []*net.IPNet
+
+	mu        sync.Mutex
+	buckets   map[rateLimitKey]*rateLimitBucket
+	lastSweep time.Time
+
+	// now is replaced in the tests
+	now func() time.Time
+}
+
+type rateLimitKey struct {
+	client    string
+	queryType QueryType
+}
+
+// rateLimitBucket is a token bucket, where each query consumes a token and
+// the tokens are refilled along the policy period
+type rateLimitBucket struct {
+	tokens float64
+	last   time.Time
+	period time.Duration
+}
+
+// NewRateLimiter returns a rate limiter without policies
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		Policies: make(map[QueryType]RateLimitPolicy),
+	}
+}
+
+// SetPolicy defines the policy of the query type
+func (l *RateLimiter) SetPolicy(queryType QueryType, policy RateLimitPolicy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.Policies == nil {
+		l.Policies = make(map[QueryType]RateLimitPolicy)
+	}
+
+	l.Policies[queryType] = policy
+}
+
+// Limit returns a backend that applies the policies before calling the
+// handler
+func (l *RateLimiter) Limit(handler ObjectHandler) ObjectHandler {
+	return ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		policy, retryAfter, allowed := l.allow(r, queryType)
+		if allowed {
+			return handler.ServeObject(r, queryType, queryValue)
+		}
+
+		if policy.Action == RateLimitTruncate {
+			object, err := handler.ServeObject(r, queryType, queryValue)
+			if err != nil {
+				return nil, err
+			}
+
+			if truncated, ok := truncateObject(object); ok {
+				return truncated, nil
+			}
+		}
+
+		return nil, RateLimitError{
+			Response: protocol.Error{
+				ErrorCode: http.StatusTooManyRequests,
+				Title:     "too many requests",
+				Description: []string{
+					fmt.Sprintf("The limit of %d queries in %s was exceeded.", policy.Queries, policy.Period),
+				},
+			},
+			RetryAfter: retryAfter,
+		}
+	})
+}
+
+// Allow consumes a query of the client quota, returning false when the quota
+// is over. The policy of the query type is also returned
+func (l *RateLimiter) Allow(r *http.Request, queryType QueryType) (RateLimitPolicy, bool) {
+	policy, _, allowed := l.allow(r, queryType)
+	return policy, allowed
+}
+
+// allow works like Allow, also returning the time until the next query is
+// allowed when the quota is over
+func (l *RateLimiter) allow(r *http.Request, queryType QueryType) (RateLimitPolicy, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	policy, ok := l.Policies[queryType]
+	if !ok || policy.Queries <= 0 || policy.Period <= 0 {
+		return policy, 0, true
+	}
+
+	now := time.Now()
+	if l.now != nil {
+		now = l.now()
+	}
+
+	if l.buckets == nil {
+		l.buckets = make(map[rateLimitKey]*rateLimitBucket)
+	}
+
+	// buckets that were completely refilled are removed to release memory
+	if now.Sub(l.lastSweep) >= time.Minute {
+		for key, bucket := range l.buckets {
+			if now.Sub(bucket.last) >= bucket.period {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	// requests without a valid remote address (e.g. Unix sockets) are
+	// identified by the raw address, so they don't share the quota with
+	// other invalid addresses
+	key := rateLimitKey{client: r.RemoteAddr, queryType: queryType}
+	if client := l.ClientIP(r); client != nil {
+		key.client = client.String()
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &rateLimitBucket{tokens: float64(policy.Queries), last: now}
+		l.buckets[key] = bucket
+	}
+
+	if bucket.take(policy, now) {
+		return policy, 0, true
+	}
+
+	return policy, bucket.wait(policy), false
+}
+
+// take refills the bucket with the tokens recovered since the last query,
+// and consumes a token if available
+func (b *rateLimitBucket) take(policy RateLimitPolicy, now time.Time) bool {
+	rate := float64(policy.Queries) / float64(policy.Period)
+	b.tokens = math.Min(float64(policy.Queries), b.tokens+rate*float64(now.Sub(b.last)))
+	b.last = now
+	b.period = policy.Period
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// wait returns the time until the bucket has a token
+func (b *rateLimitBucket) wait(policy RateLimitPolicy) time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	rate := float64(policy.Queries) / float64(policy.Period)
+	return time.Duration(math.Ceil((1 - b.tokens) / rate))
+}
+
+// ClientIP returns the IP address of the client. When the request comes from
+// a trusted proxy, the X-Forwarded-For header is checked from right to left,
+// and the first address that isn't from a trusted proxy is the client
+func (l *RateLimiter) ClientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	client := net.ParseIP(host)
+	if client == nil || !l.trusted(client) {
+		return client
+	}
+
+	forwardedFor := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwardedFor[i]))
+		if ip == nil {
+			break
+		}
+
+		client = ip
+		if !l.trusted(ip) {
+			break
+		}
+	}
+
+	return client
+}
+
+func (l *RateLimiter) trusted(ip net.IP) bool {
+	for _, network := range l.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// truncateObject returns a copy of the object without the entities (and
+// without the contact data of entities), with a remark informing the
+// truncation. The object given is not changed
+func truncateObject(object interface{}) (interface{}, bool) {
+	remark := protocol.Remark{
+		Title:       "Server Policy",
+		Type:        string(protocol.RemarkTypeObjectTruncatedServerPolicy),
+		Description: []string{"The query rate limit was exceeded, so some members were removed."},
+	}
+
+	remarks := func(r []protocol.Remark) []protocol.Remark {
+		return append(append([]protocol.Remark{}, r...), remark)
+	}
+
+	switch o := object.(type) {
+	case *protocol.Domain:
+		return truncateObject(*o)
+	case protocol.Domain:
+		o.Entities = nil
+		o.Remarks = remarks(o.Remarks)
+		return &o, true
+
+	case *protocol.Nameserver:
+		return truncateObject(*o)
+	case protocol.Nameserver:
+		o.Entities = nil
+		o.Remarks = remarks(o.Remarks)
+		return &o, true
+
+	case *protocol.Entity:
+		return truncateObject(*o)
+	case protocol.Entity:
+		// the contact data of the entity is also removed
+		o.Entities = nil
+		o.VCardArray = nil
+		o.JSContactCard = nil
+		o.Remarks = remarks(o.Remarks)
+		return &o, true
+
+	case *protocol.IPNetwork:
+		return truncateObject(*o)
+	case protocol.IPNetwork:
+		o.Entities = nil
+		o.Remarks = remarks(o.Remarks)
+		return &o, true
+
+	case *protocol.AS:
+		return truncateObject(*o)
+	case protocol.AS:
+		o.Entities = nil
+		o.Remarks = remarks(o.Remarks)
+		return &o, true
+	}
+
+	return nil, false
+}
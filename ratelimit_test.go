@@ -0,0 +1,254 @@
+package rdap
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestRateLimiterLimit(t *testing.T) {
+	now := time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	limiter := NewRateLimiter()
+	limiter.now = func() time.Time { return now }
+	limiter.SetPolicy(QueryTypeDomain, RateLimitPolicy{Queries: 2, Period: time.Minute, Action: RateLimitTruncate})
+	limiter.SetPolicy(QueryTypeIP, RateLimitPolicy{Queries: 1, Period: time.Minute, Action: RateLimitReject})
+
+	domain := &protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.br",
+		Entities:        []protocol.Entity{{ObjectClassName: "entity", Handle: "XXXX"}},
+	}
+
+	handler := limiter.Limit(ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		if queryType == QueryTypeDomain {
+			return domain, nil
+		}
+		return &protocol.IPNetwork{ObjectClassName: "ip network"}, nil
+	}))
+
+	truncated := &protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.br",
+		Remarks: []protocol.Remark{
+			{
+				Title:       "Server Policy",
+				Type:        "object truncated due to server policy",
+				Description: []string{"The query rate limit was exceeded, so some members were removed."},
+			},
+		},
+	}
+
+	data := []struct {
+		description   string
+		queryType     QueryType
+		remoteAddr    string
+		elapsed       time.Duration
+		expected      interface{}
+		expectedError bool
+	}{
+		{
+			description: "it should answer the first query",
+			queryType:   QueryTypeDomain,
+			remoteAddr:  "192.0.2.1:1234",
+			expected:    domain,
+		},
+		{
+			description: "it should answer the second query",
+			queryType:   QueryTypeDomain,
+			remoteAddr:  "192.0.2.1:1234",
+			expected:    domain,
+		},
+		{
+			description: "it should truncate the object over the limit",
+			queryType:   QueryTypeDomain,
+			remoteAddr:  "192.0.2.1:1234",
+			expected:    truncated,
+		},
+		{
+			description: "it should answer other clients",
+			queryType:   QueryTypeDomain,
+			remoteAddr:  "192.0.2.2:1234",
+			expected:    domain,
+		},
+		{
+			description: "it should recover the quota along the period",
+			queryType:   QueryTypeDomain,
+			remoteAddr:  "192.0.2.1:1234",
+			elapsed:     30 * time.Second,
+			expected:    domain,
+		},
+		{
+			description: "it should answer the first query of other query type",
+			queryType:   QueryTypeIP,
+			remoteAddr:  "192.0.2.1:1234",
+			expected:    &protocol.IPNetwork{ObjectClassName: "ip network"},
+		},
+		{
+			description:   "it should reject the query over the limit",
+			queryType:     QueryTypeIP,
+			remoteAddr:    "192.0.2.1:1234",
+			expectedError: true,
+		},
+		{
+			description: "it should share the quota of the same invalid remote address",
+			queryType:   QueryTypeIP,
+			remoteAddr:  "invalid",
+			expected:    &protocol.IPNetwork{ObjectClassName: "ip network"},
+		},
+		{
+			description: "it should not share the quota of different invalid remote addresses",
+			queryType:   QueryTypeIP,
+			remoteAddr:  "other-invalid",
+			expected:    &protocol.IPNetwork{ObjectClassName: "ip network"},
+		},
+		{
+			description:   "it should reject the query over the limit of an invalid remote address",
+			queryType:     QueryTypeIP,
+			remoteAddr:    "invalid",
+			expectedError: true,
+		},
+		{
+			description: "it should not limit query types without policy",
+			queryType:   QueryTypeAutnum,
+			remoteAddr:  "192.0.2.1:1234",
+			expected:    &protocol.IPNetwork{ObjectClassName: "ip network"},
+		},
+	}
+
+	for i, item := range data {
+		now = now.Add(item.elapsed)
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = item.remoteAddr
+
+		object, err := handler.ServeObject(r, item.queryType, "")
+		if item.expectedError {
+			if e, ok := err.(RateLimitError); !ok || e.Response.ErrorCode != http.StatusTooManyRequests || e.RetryAfter != time.Minute {
+				t.Errorf("[%d] %s: expected a too many requests error and got “%v”", i, item.description, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(item.expected, object) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, object))
+		}
+	}
+
+	if len(domain.Entities) != 1 || len(domain.Remarks) != 0 {
+		t.Error("The backend object was changed by the truncation")
+	}
+}
+
+func TestRateLimiterClientIP(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	limiter := RateLimiter{TrustedProxies: []*net.IPNet{proxies}}
+
+	data := []struct {
+		description  string
+		remoteAddr   string
+		forwardedFor []string
+		expectedIP   string
+	}{
+		{
+			description:  "it should use the remote address",
+			remoteAddr:   "192.0.2.1:1234",
+			forwardedFor: []string{"198.51.100.1"},
+			expectedIP:   "192.0.2.1",
+		},
+		{
+			description:  "it should use the forwarded address of a trusted proxy",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"198.51.100.1, 10.0.0.2"},
+			expectedIP:   "198.51.100.1",
+		},
+		{
+			description:  "it should ignore addresses informed by the client",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"203.0.113.1", "198.51.100.1"},
+			expectedIP:   "198.51.100.1",
+		},
+		{
+			description:  "it should stop in an invalid forwarded address",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"198.51.100.1, unknown"},
+			expectedIP:   "10.0.0.1",
+		},
+	}
+
+	for i, item := range data {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = item.remoteAddr
+		r.Header["X-Forwarded-For"] = item.forwardedFor
+
+		if ip := limiter.ClientIP(r).String(); ip != item.expectedIP {
+			t.Errorf("[%d] %s: expected IP “%s” and got “%s”", i, item.description, item.expectedIP, ip)
+		}
+	}
+}
+
+func TestRateLimiterRetryAfter(t *testing.T) {
+	now := time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	limiter := NewRateLimiter()
+	limiter.now = func() time.Time { return now }
+	limiter.SetPolicy(QueryTypeEntity, RateLimitPolicy{Queries: 4, Period: time.Minute, Action: RateLimitTruncate})
+	limiter.SetPolicy(QueryTypeDomain, RateLimitPolicy{Queries: 4, Period: time.Minute})
+
+	entity := &protocol.Entity{
+		ObjectClassName: "entity",
+		Handle:          "XXXX",
+		VCardArray:      protocol.NewVCard().AddFN("Joe User").Array(),
+		JSContactCard:   &protocol.JSContactCard{},
+	}
+
+	server := NewServer()
+	for _, queryType := range []QueryType{QueryTypeEntity, QueryTypeDomain} {
+		server.Handle(queryType, limiter.Limit(ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+			if queryType == QueryTypeEntity {
+				return entity, nil
+			}
+			return &protocol.Domain{ObjectClassName: "domain", LDHName: queryValue}, nil
+		})))
+	}
+
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 5; i++ {
+		w = httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest("GET", "/entity/XXXX", nil))
+	}
+
+	var truncated protocol.Entity
+	if err := json.Unmarshal(w.Body.Bytes(), &truncated); err != nil {
+		t.Fatal(err)
+	}
+
+	if truncated.VCardArray != nil || truncated.JSContactCard != nil || len(truncated.Remarks) != 1 {
+		t.Errorf("Expected the contact data to be truncated and got “%s”", w.Body.String())
+	}
+
+	for i := 0; i < 5; i++ {
+		w = httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest("GET", "/domain/example.br", nil))
+	}
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected HTTP status “429” and got “%d”", w.Code)
+	}
+
+	// a token is recovered every 15 seconds
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "15" {
+		t.Errorf("Expected Retry-After “15” and got “%s”", retryAfter)
+	}
+}
@@ -0,0 +1,298 @@
+package rdap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+const (
+	// RedactionMemberHandle is used in redaction rules to redact the handle
+	// of the object, instead of a vCard property
+	RedactionMemberHandle = "handle"
+)
+
+// RedactionRule describes a field that must be redacted by the server, and
+// how the redaction is informed to the client as described in RFC 9537
+type RedactionRule struct {
+	// Name is the redacted name, preferably one registered in the IANA RDAP
+	// JSON Values registry (e.g. "Registrant Name")
+	Name string
+
+	// Role identifies the entities of the object that are redacted. When
+	// empty the rule is applied to the object itself. Only the entities
+	// directly associated with the object are analyzed
+	Role protocol.Role
+
+	// Member is the redacted field: RedactionMemberHandle or the name of a
+	// vCard property (e.g. "fn", "email", "adr")
+	Member string
+
+	// Method defines how the field is redacted. For the partial value method
+	// only the components of structured values listed in KeepComponents are
+	// kept, and for the replacement value method the vCard property is
+	// replaced by the Replacement property
+	Method         protocol.RedactionMethod
+	KeepComponents []int
+	Replacement    *protocol.VCardProperty
+
+	// Reason explains why the field was redacted
+	Reason string
+}
+
+// RedactionPolicy is the list of redaction rules applied to the objects
+// before sending them to the clients
+type RedactionPolicy []RedactionRule
+
+// RedactDomain applies the policy to the domain, changing the redacted
+// fields and adding a redacted member for each rule that matched any field
+func (p RedactionPolicy) RedactDomain(domain *protocol.Domain) {
+	domain.Redacted = append(domain.Redacted, p.redactObject(&domain.Handle, nil, domain.Entities)...)
+}
+
+// RedactNameserver applies the policy to the nameserver, changing the
+// redacted fields and adding a redacted member for each rule that matched any
+// field
+func (p RedactionPolicy) RedactNameserver(nameserver *protocol.Nameserver) {
+	nameserver.Redacted = append(nameserver.Redacted, p.redactObject(&nameserver.Handle, nil, nameserver.Entities)...)
+}
+
+// RedactIPNetwork applies the policy to the IP network, changing the redacted
+// fields and adding a redacted member for each rule that matched any field
+func (p RedactionPolicy) RedactIPNetwork(ipNetwork *protocol.IPNetwork) {
+	ipNetwork.Redacted = append(ipNetwork.Redacted, p.redactObject(&ipNetwork.Handle, &ipNetwork.HandleRemoved, ipNetwork.Entities)...)
+}
+
+// RedactAS applies the policy to the AS, changing the redacted fields and
+// adding a redacted member for each rule that matched any field
+func (p RedactionPolicy) RedactAS(as *protocol.AS) {
+	as.Redacted = append(as.Redacted, p.redactObject(&as.Handle, &as.HandleRemoved, as.Entities)...)
+}
+
+// redactObject applies the policy to the handle and to the entities of an
+// object that isn't an entity. The removed flag is nil for the objects that
+// already omit an empty handle
+func (p RedactionPolicy) redactObject(handle *string, removed *bool, entities []protocol.Entity) []protocol.Redacted {
+	var redacted []protocol.Redacted
+
+	for _, rule := range p {
+		var result redactionResult
+
+		if rule.Role == "" {
+			if rule.Member == RedactionMemberHandle {
+				result = redactHandle(handle, removed, "$", rule)
+			}
+
+		} else {
+			result = redactEntities(entities, "$", rule)
+		}
+
+		if result.matched {
+			redacted = append(redacted, result.redacted(rule))
+		}
+	}
+
+	return redacted
+}
+
+// RedactEntity applies the policy to the entity, changing the redacted fields
+// and adding a redacted member for each rule that matched any field
+func (p RedactionPolicy) RedactEntity(entity *protocol.Entity) {
+	for _, rule := range p {
+		var result redactionResult
+
+		if rule.Role == "" {
+			result = redactEntity(entity, "$", rule)
+		} else {
+			result = redactEntities(entity.Entities, "$", rule)
+		}
+
+		if result.matched {
+			entity.Redacted = append(entity.Redacted, result.redacted(rule))
+		}
+	}
+}
+
+// redactionResult stores the JSONPath expressions of the first redacted
+// field that matched a rule
+type redactionResult struct {
+	matched         bool
+	path            string
+	replacementPath string
+}
+
+func (r redactionResult) redacted(rule RedactionRule) protocol.Redacted {
+	redacted := protocol.Redacted{
+		Name:   protocol.RedactedName{Type: rule.Name},
+		Method: rule.Method,
+	}
+
+	switch rule.Method {
+	case protocol.RedactionMethodEmptyValue, protocol.RedactionMethodPartialValue:
+		redacted.PostPath = r.path
+	case protocol.RedactionMethodReplacementValue:
+		redacted.PrePath = r.path
+		redacted.ReplacementPath = r.replacementPath
+	default:
+		redacted.PrePath = r.path
+	}
+
+	if rule.Reason != "" {
+		redacted.Reason = &protocol.RedactedReason{Type: rule.Reason}
+	}
+
+	return redacted
+}
+
+func redactEntities(entities []protocol.Entity, base string, rule RedactionRule) redactionResult {
+	var result redactionResult
+
+	for i := range entities {
+		for j, role := range entities[i].Roles {
+			if role != string(rule.Role) {
+				continue
+			}
+
+			path := fmt.Sprintf("%s.entities[?(@.roles[%d]=='%s')]", base, j, role)
+			if entityResult := redactEntity(&entities[i], path, rule); entityResult.matched && !result.matched {
+				result = entityResult
+			}
+			break
+		}
+	}
+
+	return result
+}
+
+func redactEntity(entity *protocol.Entity, base string, rule RedactionRule) redactionResult {
+	if rule.Member == RedactionMemberHandle {
+		return redactHandle(&entity.Handle, &entity.HandleRemoved, base, rule)
+	}
+
+	// the entity vCard is only changed when the server already informed it,
+	// to avoid adding a vcardArray to entities that only have JSContact
+	if len(entity.VCardArray) == 0 {
+		return redactionResult{}
+	}
+
+	vcard, err := protocol.ParseVCard(entity.VCardArray)
+	if err != nil {
+		return redactionResult{}
+	}
+
+	result := redactionResult{
+		path: fmt.Sprintf("%s.vcardArray[1][?(@[0]=='%s')][3]", base, rule.Member),
+	}
+
+	var properties []protocol.VCardProperty
+	for _, property := range vcard.Properties {
+		if !strings.EqualFold(property.Name, rule.Member) {
+			properties = append(properties, property)
+			continue
+		}
+
+		switch rule.Method {
+		case protocol.RedactionMethodEmptyValue:
+			property.Values = []interface{}{emptyVCardValue(property.Values, nil)}
+			properties = append(properties, property)
+
+		case protocol.RedactionMethodPartialValue:
+			property.Values = []interface{}{emptyVCardValue(property.Values, rule.KeepComponents)}
+			properties = append(properties, property)
+
+		case protocol.RedactionMethodReplacementValue:
+			if rule.Replacement == nil {
+				properties = append(properties, property)
+				continue
+			}
+
+			replacement := *rule.Replacement
+			if replacement.Parameters == nil {
+				replacement.Parameters = make(map[string]interface{})
+			}
+			properties = append(properties, replacement)
+			result.replacementPath = fmt.Sprintf("%s.vcardArray[1][?(@[0]=='%s')][3]", base, replacement.Name)
+
+		default:
+			// the property is removed
+		}
+
+		result.matched = true
+	}
+
+	if result.matched {
+		vcard.Properties = properties
+		entity.SetVCard(vcard)
+	}
+
+	return result
+}
+
+func redactHandle(handle *string, removed *bool, base string, rule RedactionRule) redactionResult {
+	if *handle == "" {
+		return redactionResult{}
+	}
+
+	result := redactionResult{
+		matched: true,
+		path:    base + ".handle",
+	}
+
+	switch rule.Method {
+	case protocol.RedactionMethodReplacementValue:
+		if rule.Replacement == nil || len(rule.Replacement.Values) == 0 {
+			return redactionResult{}
+		}
+
+		*handle = fmt.Sprintf("%v", rule.Replacement.Values[0])
+		result.replacementPath = result.path
+
+	case protocol.RedactionMethodEmptyValue:
+		*handle = ""
+
+	case protocol.RedactionMethodPartialValue:
+		// a handle has no components to keep, so the partial value is also
+		// an empty string
+		*handle = ""
+
+	default:
+		// the handle member of the domains and nameservers is omitted when
+		// empty, the other objects must be flagged to omit it
+		*handle = ""
+		if removed != nil {
+			*removed = true
+		}
+	}
+
+	return result
+}
+
+// emptyVCardValue empties the value of a vCard property, keeping the format
+// of structured values. The components listed in keep are not changed
+func emptyVCardValue(values []interface{}, keep []int) interface{} {
+	if len(values) == 0 {
+		return ""
+	}
+
+	components, ok := values[0].([]interface{})
+	if !ok {
+		return ""
+	}
+
+	kept := make(map[int]bool)
+	for _, i := range keep {
+		kept[i] = true
+	}
+
+	redacted := make([]interface{}, len(components))
+	for i, component := range components {
+		if kept[i] {
+			redacted[i] = component
+		} else {
+			redacted[i] = ""
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,286 @@
+package rdap
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestRedactionPolicyRedactDomain(t *testing.T) {
+	newDomain := func() *protocol.Domain {
+		registrant := protocol.Entity{
+			ObjectClassName: "entity",
+			Handle:          "REG-1",
+			Roles:           []string{"registrant"},
+		}
+
+		registrant.SetVCard(protocol.NewVCard().
+			AddFN("Joe User").
+			AddEmail(protocol.VCardEmail{Address: "joe.user@example.com"}).
+			AddAddress(protocol.VCardAddress{
+				Street:     []string{"4321 Rue Somewhere"},
+				Locality:   "Quebec",
+				Region:     "QC",
+				PostalCode: "G1V 2M2",
+				CC:         "CA",
+			}))
+
+		technical := protocol.Entity{
+			ObjectClassName: "entity",
+			Handle:          "TECH-1",
+			Roles:           []string{"administrative", "technical"},
+		}
+
+		return &protocol.Domain{
+			ObjectClassName: "domain",
+			Handle:          "EXAMPLE-1",
+			LDHName:         "example.com",
+			Entities:        []protocol.Entity{registrant, technical},
+		}
+	}
+
+	policy := RedactionPolicy{
+		{
+			Name:   "Registry Domain ID",
+			Member: RedactionMemberHandle,
+			Method: protocol.RedactionMethodRemoval,
+			Reason: "Server policy",
+		},
+		{
+			Name:   "Registrant Name",
+			Role:   "registrant",
+			Member: protocol.VCardPropertyFN,
+			Method: protocol.RedactionMethodEmptyValue,
+		},
+		{
+			Name:   "Registrant Email",
+			Role:   "registrant",
+			Member: protocol.VCardPropertyEmail,
+			Method: protocol.RedactionMethodReplacementValue,
+			Replacement: &protocol.VCardProperty{
+				Name:   protocol.VCardPropertyContact,
+				Type:   "uri",
+				Values: []interface{}{"https://registrar.example/contact"},
+			},
+		},
+		{
+			Name:           "Registrant Street",
+			Role:           "registrant",
+			Member:         protocol.VCardPropertyAdr,
+			Method:         protocol.RedactionMethodPartialValue,
+			KeepComponents: []int{3, 4, 6},
+		},
+		{
+			Name:   "Tech ID",
+			Role:   "technical",
+			Member: RedactionMemberHandle,
+			Method: protocol.RedactionMethodRemoval,
+		},
+		{
+			Name:   "Billing ID",
+			Role:   "billing",
+			Member: RedactionMemberHandle,
+		},
+	}
+
+	domain := newDomain()
+	policy.RedactDomain(domain)
+
+	expectedRedacted := []protocol.Redacted{
+		{
+			Name:    protocol.RedactedName{Type: "Registry Domain ID"},
+			PrePath: "$.handle",
+			Method:  protocol.RedactionMethodRemoval,
+			Reason:  &protocol.RedactedReason{Type: "Server policy"},
+		},
+		{
+			Name:     protocol.RedactedName{Type: "Registrant Name"},
+			PostPath: "$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='fn')][3]",
+			Method:   protocol.RedactionMethodEmptyValue,
+		},
+		{
+			Name:            protocol.RedactedName{Type: "Registrant Email"},
+			PrePath:         "$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='email')][3]",
+			ReplacementPath: "$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='contact-uri')][3]",
+			Method:          protocol.RedactionMethodReplacementValue,
+		},
+		{
+			Name:     protocol.RedactedName{Type: "Registrant Street"},
+			PostPath: "$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='adr')][3]",
+			Method:   protocol.RedactionMethodPartialValue,
+		},
+		{
+			Name:    protocol.RedactedName{Type: "Tech ID"},
+			PrePath: "$.entities[?(@.roles[1]=='technical')].handle",
+			Method:  protocol.RedactionMethodRemoval,
+		},
+	}
+
+	if !reflect.DeepEqual(expectedRedacted, domain.Redacted) {
+		t.Errorf("mismatch redacted members.\n%v", diff(expectedRedacted, domain.Redacted))
+	}
+
+	if domain.Handle != "" {
+		t.Errorf("Expected the domain handle to be removed and got “%s”", domain.Handle)
+	}
+
+	if domain.Entities[1].Handle != "" {
+		t.Errorf("Expected the technical handle to be removed and got “%s”", domain.Entities[1].Handle)
+	}
+
+	vcard, err := domain.Entities[0].VCard()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fn := vcard.FN(); fn != "" {
+		t.Errorf("Expected an empty fn and got “%s”", fn)
+	}
+
+	if emails := vcard.Emails(); len(emails) > 0 {
+		t.Errorf("Expected the emails to be removed and got “%#v”", emails)
+	}
+
+	if contact, _ := vcard.First(protocol.VCardPropertyContact); contact.Text() != "https://registrar.example/contact" {
+		t.Errorf("Expected a contact URI and got “%s”", contact.Text())
+	}
+
+	expectedAddresses := []protocol.VCardAddress{
+		{Locality: "Quebec", Region: "QC", CC: "CA"},
+	}
+
+	if addresses := vcard.Addresses(); !reflect.DeepEqual(expectedAddresses, addresses) {
+		t.Errorf("mismatch addresses.\n%v", diff(expectedAddresses, addresses))
+	}
+}
+
+func TestRedactionPolicyRedactEntity(t *testing.T) {
+	entity := &protocol.Entity{
+		ObjectClassName: "entity",
+		Handle:          "REG-1",
+	}
+	entity.SetVCard(protocol.NewVCard().AddFN("Joe User"))
+
+	policy := RedactionPolicy{
+		{
+			Name:   "Registrant Name",
+			Member: protocol.VCardPropertyFN,
+		},
+		{
+			Name:   "Registrant Organization",
+			Member: protocol.VCardPropertyOrg,
+		},
+	}
+
+	policy.RedactEntity(entity)
+
+	expectedRedacted := []protocol.Redacted{
+		{
+			Name:    protocol.RedactedName{Type: "Registrant Name"},
+			PrePath: "$.vcardArray[1][?(@[0]=='fn')][3]",
+		},
+	}
+
+	if !reflect.DeepEqual(expectedRedacted, entity.Redacted) {
+		t.Errorf("mismatch redacted members.\n%v", diff(expectedRedacted, entity.Redacted))
+	}
+
+	expectedVCard := protocol.NewVCard().Array()
+	if !reflect.DeepEqual(expectedVCard, entity.VCardArray) {
+		t.Errorf("mismatch vCard.\n%v", diff(expectedVCard, entity.VCardArray))
+	}
+}
+
+func TestRedactionPolicyRedactHandleJSON(t *testing.T) {
+	data := []struct {
+		description string
+		method      protocol.RedactionMethod
+		replacement *protocol.VCardProperty
+		expected    string
+	}{
+		{
+			description: "it should keep an empty handle for the empty value method",
+			method:      protocol.RedactionMethodEmptyValue,
+			expected:    `{"handle":"","redacted":[{"name":{"type":"Registrant ID"},"postPath":"$.handle","method":"emptyValue"}]}`,
+		},
+		{
+			description: "it should keep an empty handle for the partial value method",
+			method:      protocol.RedactionMethodPartialValue,
+			expected:    `{"handle":"","redacted":[{"name":{"type":"Registrant ID"},"postPath":"$.handle","method":"partialValue"}]}`,
+		},
+		{
+			description: "it should replace the handle",
+			method:      protocol.RedactionMethodReplacementValue,
+			replacement: &protocol.VCardProperty{Values: []interface{}{"REDACTED"}},
+			expected:    `{"handle":"REDACTED","redacted":[{"name":{"type":"Registrant ID"},"prePath":"$.handle","replacementPath":"$.handle","method":"replacementValue"}]}`,
+		},
+		{
+			description: "it should remove the handle",
+			method:      protocol.RedactionMethodRemoval,
+			expected:    `{"redacted":[{"name":{"type":"Registrant ID"},"prePath":"$.handle","method":"removal"}]}`,
+		},
+	}
+
+	for i, item := range data {
+		entity := &protocol.Entity{Handle: "REG-1"}
+
+		policy := RedactionPolicy{
+			{
+				Name:        "Registrant ID",
+				Member:      RedactionMemberHandle,
+				Method:      item.method,
+				Replacement: item.replacement,
+			},
+		}
+		policy.RedactEntity(entity)
+
+		data, err := json.Marshal(entity)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var members map[string]json.RawMessage
+		if err := json.Unmarshal(data, &members); err != nil {
+			t.Fatal(err)
+		}
+
+		// only the handle and redacted members are compared
+		for member := range members {
+			if member != "handle" && member != "redacted" {
+				delete(members, member)
+			}
+		}
+
+		output, err := json.Marshal(members)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(output) != item.expected {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, string(output)))
+		}
+	}
+
+	output, err := json.Marshal(protocol.Entity{ObjectClassName: "entity"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(output), `"handle":""`) {
+		t.Errorf("Expected the handle member in the entity and got “%s”", output)
+	}
+
+	ipNetwork := &protocol.IPNetwork{Handle: "200.160.0.0/20"}
+	RedactionPolicy{{Name: "Network ID", Member: RedactionMemberHandle, Method: protocol.RedactionMethodRemoval}}.RedactIPNetwork(ipNetwork)
+
+	if output, err = json.Marshal(ipNetwork); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(output), `"handle"`) || !strings.Contains(string(output), `"startAddress"`) {
+		t.Errorf("Expected the IP network without the handle member and got “%s”", output)
+	}
+}
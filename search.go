@@ -0,0 +1,611 @@
+package rdap
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/registrobr/rdap/protocol"
+	"golang.org/x/net/idna"
+)
+
+const (
+	// DefaultMaxSearchResults is the maximum number of search results used
+	// when the search handler doesn't define one
+	DefaultMaxSearchResults = 100
+)
+
+// searchProperties lists the query string parameters of each search type, as
+// described in RFC 9082, section 3.2
+var searchProperties = map[QueryType][]string{
+	QueryTypeDomains:     {"name", "nsLdhName", "nsIp"},
+	QueryTypeNameservers: {"name", "ip"},
+	QueryTypeEntities:    {"fn", "handle"},
+}
+
+// SearchQuery stores a search request. The pattern was already normalized:
+// names are lowercase A-labels (except the labels with the "*" partial
+// matching character), IP addresses are in the canonical format and the full
+// names and handles are lowercase
+type SearchQuery struct {
+	Type     QueryType
+	Property string
+	Pattern  string
+
+	// Offset is the number of objects, in the order of the sorts, that the
+	// backend should skip. It's used to answer the pages of the result set
+	Offset int
+
+	// Limit is the maximum number of objects that the backend should return.
+	// If zero there's no limit
+	Limit int
+
+	// Sorts is the order requested by the client. When the backend limits
+	// the objects, it should return the first objects in this order
+	Sorts []SearchSort
+}
+
+// SearchSort is a sort property requested by the client, as described in RFC
+// 8977, section 2.3
+type SearchSort struct {
+	Property   string
+	Descending bool
+}
+
+// Match checks if the value matches the search pattern. As described in RFC
+// 9082, section 4.1, the "*" character matches zero or more characters. The
+// comparison is case insensitive
+func (q SearchQuery) Match(value string) bool {
+	return matchSearchPattern(q.Pattern, strings.ToLower(value))
+}
+
+// SearchResult stores the objects found by a search backend
+type SearchResult struct {
+	// Objects are the domains, nameservers or entities found, as values or
+	// pointers
+	Objects []interface{}
+
+	// Truncated is the reason for the backend not returning all the objects
+	// found (e.g. protocol.RemarkTypeResultTruncatedAuthorization). It's
+	// empty when all objects were returned
+	Truncated protocol.RemarkType
+}
+
+// SearchBackend finds the objects of the searches. The backend should skip
+// the first query.Offset objects and return at most query.Limit objects
+type SearchBackend interface {
+	Search(r *http.Request, query SearchQuery) (SearchResult, error)
+}
+
+// SearchBackendFunc is a function type that implements the SearchBackend
+// interface
+type SearchBackendFunc func(r *http.Request, query SearchQuery) (SearchResult, error)
+
+// Search calls f(r, query)
+func (f SearchBackendFunc) Search(r *http.Request, query SearchQuery) (SearchResult, error) {
+	return f(r, query)
+}
+
+// SearchHandler is an ObjectHandler that answers the searches described in
+// RFC 9082, section 3.2, using a backend to find the objects. The results can
+// be sorted and split in pages as described in RFC 8977. It should be
+// registered in the server for the search query types (QueryTypeDomains,
+// QueryTypeNameservers and QueryTypeEntities)
+type SearchHandler struct {
+	// Backend finds the objects of the searches
+	Backend SearchBackend
+
+	// MaxResults is the maximum number of objects in a response. Without
+	// pages, when the backend finds more objects the result set is truncated
+	// with the protocol.RemarkTypeResultTruncatedExcessiveLoad notice. If zero
+	// DefaultMaxSearchResults is used
+	MaxResults int
+
+	// PageSize is the number of objects in each page of the result set,
+	// limited to MaxResults. Each page is retrieved from the backend with the
+	// query offset. If zero the results are not split in pages
+	PageSize int
+}
+
+// NewSearchHandler returns a search handler with the default maximum number
+// of results and without pages
+func NewSearchHandler(backend SearchBackend) *SearchHandler {
+	return &SearchHandler{
+		Backend:    backend,
+		MaxResults: DefaultMaxSearchResults,
+	}
+}
+
+// ServeObject implements the ObjectHandler interface
+func (h *SearchHandler) ServeObject(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+	parameters := r.URL.Query()
+
+	query, err := newSearchQuery(queryType, parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	sorts, err := parseSearchSorts(queryType, parameters.Get("sort"))
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := decodeSearchCursor(parameters.Get("cursor"))
+	if err != nil {
+		return nil, err
+	}
+
+	maxResults := h.MaxResults
+	if maxResults <= 0 {
+		maxResults = DefaultMaxSearchResults
+	}
+
+	// without pages the result set is the first maxResults objects
+	limit := maxResults
+	if h.PageSize > 0 {
+		if h.PageSize < limit {
+			limit = h.PageSize
+		}
+		query.Offset = offset
+	}
+
+	// one more object is requested to detect when there are more objects
+	// than the limit (truncated result set or next page)
+	query.Limit = limit + 1
+	query.Sorts = sorts
+
+	result, err := h.Backend.Search(r, query)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]interface{}, 0, len(result.Objects))
+	for _, object := range result.Objects {
+		value, err := searchResultValue(queryType, object)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, value)
+	}
+
+	// the objects are sorted before the truncation, so the client receives
+	// the first objects in the requested order (RFC 8977, section 2)
+	sortSearchResults(queryType, objects, sorts)
+
+	more := len(objects) > limit
+	if more {
+		objects = objects[:limit]
+	}
+
+	truncated := result.Truncated != "" || (more && h.PageSize <= 0)
+
+	var notices []protocol.Notice
+	if result.Truncated != "" {
+		notices = append(notices, protocol.Notice{
+			Title:       "Search Policy",
+			Type:        string(result.Truncated),
+			Description: []string{"Some objects of the search were not returned."},
+		})
+
+	} else if truncated {
+		notices = append(notices, protocol.Notice{
+			Title:       "Search Policy",
+			Type:        string(protocol.RemarkTypeResultTruncatedExcessiveLoad),
+			Description: []string{fmt.Sprintf("Search results are limited to %d objects.", maxResults)},
+		})
+	}
+
+	self := searchURL(r)
+	sorting := &protocol.SortingMetadata{
+		CurrentSort: parameters.Get("sort"),
+	}
+
+	for _, s := range searchSortTypes[queryType] {
+		sorting.AvailableSorts = append(sorting.AvailableSorts, protocol.AvailableSort{
+			Property: s.property,
+			JSONPath: s.jsonPath,
+			Links: []protocol.Link{
+				searchLink(self, "alternate", "Result Ascending Sort", "sort", s.property),
+				searchLink(self, "alternate", "Result Descending Sort", "sort", s.property+":d"),
+			},
+		})
+	}
+
+	var paging *protocol.PagingMetadata
+	if h.PageSize > 0 || parameters.Get("count") == "true" {
+		paging = &protocol.PagingMetadata{}
+		// the total is only known in the last page of a result set that
+		// wasn't truncated
+		if parameters.Get("count") == "true" && !truncated && !more {
+			paging.TotalCount = offset + len(objects)
+			if h.PageSize <= 0 {
+				paging.TotalCount = len(objects)
+			}
+		}
+	}
+
+	if h.PageSize > 0 {
+		if more {
+			paging.Links = append(paging.Links,
+				searchLink(self, "next", "Result Pagination", "cursor", encodeSearchCursor(offset+limit)))
+		}
+
+		paging.PageSize = len(objects)
+		paging.PageNumber = offset/limit + 1
+	}
+
+	switch queryType {
+	case QueryTypeDomains:
+		results := &protocol.DomainSearchResults{Results: []protocol.Domain{}, Notices: notices, Sorting: sorting, Paging: paging}
+		for _, object := range objects {
+			results.Results = append(results.Results, object.(protocol.Domain))
+		}
+		return results, nil
+
+	case QueryTypeNameservers:
+		results := &protocol.NameserverSearchResults{Results: []protocol.Nameserver{}, Notices: notices, Sorting: sorting, Paging: paging}
+		for _, object := range objects {
+			results.Results = append(results.Results, object.(protocol.Nameserver))
+		}
+		return results, nil
+	}
+
+	results := &protocol.EntitySearchResults{Results: []protocol.Entity{}, Notices: notices, Sorting: sorting, Paging: paging}
+	for _, object := range objects {
+		results.Results = append(results.Results, object.(protocol.Entity))
+	}
+	return results, nil
+}
+
+// newSearchQuery builds the search query from the query string. Only one
+// search property can be informed
+func newSearchQuery(queryType QueryType, parameters url.Values) (SearchQuery, error) {
+	query := SearchQuery{Type: queryType}
+
+	for _, property := range searchProperties[queryType] {
+		if _, ok := parameters[property]; !ok {
+			continue
+		}
+
+		if query.Property != "" {
+			return query, invalidSearchError("only one search property is allowed")
+		}
+
+		query.Property = property
+		query.Pattern = parameters.Get(property)
+	}
+
+	if query.Property == "" {
+		return query, invalidSearchError(fmt.Sprintf("the search property is missing (%s)",
+			strings.Join(searchProperties[queryType], ", ")))
+	}
+
+	if strings.Trim(query.Pattern, "*.") == "" {
+		return query, invalidSearchError("the search pattern is too broad")
+	}
+
+	pattern, ok := normalizeSearchPattern(query.Property, query.Pattern)
+	if !ok {
+		return query, invalidSearchError("the search pattern is malformed")
+	}
+
+	query.Pattern = pattern
+	return query, nil
+}
+
+// normalizeSearchPattern converts the pattern to the format used in the
+// comparisons
+func normalizeSearchPattern(property, pattern string) (string, bool) {
+	switch property {
+	case "name", "nsLdhName":
+		labels := strings.Split(strings.TrimSuffix(strings.ToLower(pattern), "."), ".")
+		for i, label := range labels {
+			if strings.Contains(label, "*") {
+				continue
+			}
+
+			var err error
+			if labels[i], err = idna.ToASCII(label); err != nil || labels[i] == "" {
+				return "", false
+			}
+		}
+		return strings.Join(labels, "."), true
+
+	case "ip", "nsIp":
+		ip := net.ParseIP(pattern)
+		if ip == nil {
+			return "", false
+		}
+		return ip.String(), true
+	}
+
+	return strings.ToLower(pattern), true
+}
+
+func invalidSearchError(description string) error {
+	return protocol.Error{
+		ErrorCode:   http.StatusBadRequest,
+		Title:       "invalid search",
+		Description: []string{description},
+	}
+}
+
+// matchSearchPattern compares the value with a pattern where "*" matches
+// zero or more characters
+func matchSearchPattern(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i == -1 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
+
+// searchResultValue checks the object type of the search, returning it as a
+// value, so the result set is not affected by changes in the backend objects
+func searchResultValue(queryType QueryType, object interface{}) (interface{}, error) {
+	switch o := object.(type) {
+	case *protocol.Domain:
+		if queryType == QueryTypeDomains {
+			return *o, nil
+		}
+	case protocol.Domain:
+		if queryType == QueryTypeDomains {
+			return o, nil
+		}
+	case *protocol.Nameserver:
+		if queryType == QueryTypeNameservers {
+			return *o, nil
+		}
+	case protocol.Nameserver:
+		if queryType == QueryTypeNameservers {
+			return o, nil
+		}
+	case *protocol.Entity:
+		if queryType == QueryTypeEntities {
+			return *o, nil
+		}
+	case protocol.Entity:
+		if queryType == QueryTypeEntities {
+			return o, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected search result %T in %s search", object, queryType)
+}
+
+// searchSortType is a property that can be used to sort the search results,
+// as described in RFC 8977, section 2.3.1
+type searchSortType struct {
+	property string
+	jsonPath string
+	key      func(object interface{}) string
+}
+
+var searchSortTypes = map[QueryType][]searchSortType{
+	QueryTypeDomains: {
+		{property: "name", jsonPath: "$.domainSearchResults[*].ldhName", key: searchNameKey},
+		{property: "registrationDate", jsonPath: searchEventPath("domain", protocol.EventActionRegistration), key: searchEventKey(protocol.EventActionRegistration)},
+		{property: "expirationDate", jsonPath: searchEventPath("domain", protocol.EventActionExpiration), key: searchEventKey(protocol.EventActionExpiration)},
+		{property: "lastChangedDate", jsonPath: searchEventPath("domain", protocol.EventActionLastChanged), key: searchEventKey(protocol.EventActionLastChanged)},
+	},
+	QueryTypeNameservers: {
+		{property: "name", jsonPath: "$.nameserverSearchResults[*].ldhName", key: searchNameKey},
+	},
+	QueryTypeEntities: {
+		{property: "handle", jsonPath: "$.entitySearchResults[*].handle", key: searchNameKey},
+		{property: "fn", jsonPath: `$.entitySearchResults[*].vcardArray[1][?(@[0]=="fn")][3]`, key: searchFNKey},
+		{property: "registrationDate", jsonPath: searchEventPath("entity", protocol.EventActionRegistration), key: searchEventKey(protocol.EventActionRegistration)},
+		{property: "lastChangedDate", jsonPath: searchEventPath("entity", protocol.EventActionLastChanged), key: searchEventKey(protocol.EventActionLastChanged)},
+	},
+}
+
+// parseSearchSorts parses the sort parameter, that is a list of properties
+// separated by comma, each one optionally followed by ":a" (ascending, the
+// default) or ":d" (descending)
+func parseSearchSorts(queryType QueryType, parameter string) ([]SearchSort, error) {
+	if parameter == "" {
+		return nil, nil
+	}
+
+	var sorts []SearchSort
+
+	for _, item := range strings.Split(parameter, ",") {
+		property := item
+		descending := false
+
+		if i := strings.Index(item, ":"); i != -1 {
+			property = item[:i]
+
+			switch item[i+1:] {
+			case "a":
+			case "d":
+				descending = true
+			default:
+				return nil, invalidSearchError(fmt.Sprintf("invalid sort order in %q", item))
+			}
+		}
+
+		if searchSortKey(queryType, property) == nil {
+			return nil, invalidSearchError(fmt.Sprintf("unknown sort property %q", property))
+		}
+
+		sorts = append(sorts, SearchSort{Property: property, Descending: descending})
+	}
+
+	return sorts, nil
+}
+
+// searchSortKey returns the function that builds the sort key of the
+// property, or nil if the property can't be used to sort the results
+func searchSortKey(queryType QueryType, property string) func(object interface{}) string {
+	for _, sortType := range searchSortTypes[queryType] {
+		if sortType.property == property {
+			return sortType.key
+		}
+	}
+
+	return nil
+}
+
+// sortSearchResults sorts the domains, nameservers or entities (as values)
+// in the requested order. Unknown sort properties are ignored
+func sortSearchResults(queryType QueryType, objects []interface{}, sorts []SearchSort) {
+	if len(sorts) == 0 {
+		return
+	}
+
+	var keys []func(object interface{}) string
+	for _, s := range sorts {
+		keys = append(keys, searchSortKey(queryType, s.Property))
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		for k, s := range sorts {
+			if keys[k] == nil {
+				continue
+			}
+
+			ki, kj := keys[k](objects[i]), keys[k](objects[j])
+			if ki == kj {
+				continue
+			}
+
+			if s.Descending {
+				return ki > kj
+			}
+			return ki < kj
+		}
+
+		return false
+	})
+}
+
+func searchNameKey(object interface{}) string {
+	switch o := object.(type) {
+	case protocol.Domain:
+		return strings.ToLower(o.LDHName)
+	case protocol.Nameserver:
+		return strings.ToLower(o.LDHName)
+	case protocol.Entity:
+		return strings.ToLower(o.Handle)
+	}
+
+	return ""
+}
+
+func searchFNKey(object interface{}) string {
+	if entity, ok := object.(protocol.Entity); ok {
+		if vcard, err := entity.VCard(); err == nil && vcard != nil {
+			return strings.ToLower(vcard.FN())
+		}
+	}
+
+	return ""
+}
+
+func searchEventKey(action protocol.EventAction) func(object interface{}) string {
+	return func(object interface{}) string {
+		var events []protocol.Event
+
+		switch o := object.(type) {
+		case protocol.Domain:
+			events = o.Events
+		case protocol.Nameserver:
+			events = o.Events
+		case protocol.Entity:
+			events = o.Events
+		}
+
+		for _, event := range events {
+			if event.Action == action {
+				// fixed length format, so the dates can be compared as strings
+				return event.Date.UTC().Format("2006-01-02T15:04:05.000000000")
+			}
+		}
+
+		return ""
+	}
+}
+
+func searchEventPath(objectClass string, action protocol.EventAction) string {
+	return fmt.Sprintf(`$.%sSearchResults[*].events[?(@.eventAction=="%s")].eventDate`, objectClass, action)
+}
+
+// encodeSearchCursor builds the opaque cursor of RFC 8977, section 2.2,
+// that identifies the first object of a page
+func encodeSearchCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset=" + strconv.Itoa(offset)))
+}
+
+func decodeSearchCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(data), "offset=") {
+		offset, err := strconv.Atoi(strings.TrimPrefix(string(data), "offset="))
+		if err == nil && offset >= 0 {
+			return offset, nil
+		}
+	}
+
+	return 0, invalidSearchError("the cursor is malformed")
+}
+
+// searchURL returns the absolute URL of the search request. The request URI
+// is used instead of the URL path to keep the prefixes removed by
+// http.StripPrefix
+func searchURL(r *http.Request) *url.URL {
+	u := &url.URL{
+		Scheme:   "http",
+		Host:     r.Host,
+		Path:     r.URL.Path,
+		RawQuery: r.URL.RawQuery,
+	}
+
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+
+	if requestURI, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		u.Path = requestURI.Path
+	}
+
+	return u
+}
+
+// searchLink returns a link to the search with the parameter replaced
+func searchLink(self *url.URL, rel, title, parameter, value string) protocol.Link {
+	href := *self
+	query := href.Query()
+	query.Set(parameter, value)
+	href.RawQuery = query.Encode()
+
+	return protocol.Link{
+		Value: self.String(),
+		Rel:   rel,
+		Href:  href.String(),
+		Title: title,
+		Type:  ContentTypeRDAP,
+	}
+}
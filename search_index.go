@@ -0,0 +1,235 @@
+package rdap
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// SearchIndex is an in-memory SearchBackend. It's an inverted index that maps
+// the terms of each search property to the objects, keeping the terms sorted
+// to find the partial matches by prefix. It's safe for concurrent use
+type SearchIndex struct {
+	mu      sync.RWMutex
+	objects []interface{}
+	fields  map[searchField]*searchTerms
+}
+
+// searchField identifies the terms of a search property
+type searchField struct {
+	queryType QueryType
+	property  string
+}
+
+// searchTerms stores the objects (positions in SearchIndex.objects) of each
+// term
+type searchTerms struct {
+	postings map[string][]int
+	sorted   []string
+}
+
+// NewSearchIndex returns an empty search index
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		fields: make(map[searchField]*searchTerms),
+	}
+}
+
+// Add indexes a domain, nameserver or entity, as a value or as a pointer. The
+// index stores a copy of the object, so later changes in the object are not
+// visible in the searches
+func (s *SearchIndex) Add(object interface{}) error {
+	var terms map[searchField][]string
+
+	switch o := object.(type) {
+	case *protocol.Domain:
+		return s.Add(*o)
+	case protocol.Domain:
+		terms = domainSearchTerms(o)
+	case *protocol.Nameserver:
+		return s.Add(*o)
+	case protocol.Nameserver:
+		terms = nameserverSearchTerms(QueryTypeNameservers, o)
+	case *protocol.Entity:
+		return s.Add(*o)
+	case protocol.Entity:
+		terms = entitySearchTerms(o)
+	default:
+		return fmt.Errorf("unsupported search object %T", object)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := len(s.objects)
+	s.objects = append(s.objects, object)
+
+	for field, values := range terms {
+		t, ok := s.fields[field]
+		if !ok {
+			t = &searchTerms{postings: make(map[string][]int)}
+			s.fields[field] = t
+		}
+
+		for _, value := range values {
+			t.add(value, id)
+		}
+	}
+
+	return nil
+}
+
+// Search implements the SearchBackend interface. The objects are returned in
+// the order that they were added, or in the order of the query sorts
+func (s *SearchIndex) Search(r *http.Request, query SearchQuery) (SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result SearchResult
+
+	t, ok := s.fields[searchField{queryType: query.Type, property: query.Property}]
+	if !ok {
+		return result, nil
+	}
+
+	ids := make(map[int]bool)
+	for _, term := range t.match(query) {
+		for _, id := range t.postings[term] {
+			ids[id] = true
+		}
+	}
+
+	sortedIDs := make([]int, 0, len(ids))
+	for id := range ids {
+		sortedIDs = append(sortedIDs, id)
+	}
+	sort.Ints(sortedIDs)
+
+	for _, id := range sortedIDs {
+		result.Objects = append(result.Objects, s.objects[id])
+	}
+
+	// the offset and the limit are applied after sorting, to return the
+	// objects in the requested order
+	sortSearchResults(query.Type, result.Objects, query.Sorts)
+
+	if query.Offset >= len(result.Objects) {
+		result.Objects = nil
+	} else if query.Offset > 0 {
+		result.Objects = result.Objects[query.Offset:]
+	}
+
+	if query.Limit > 0 && len(result.Objects) > query.Limit {
+		result.Objects = result.Objects[:query.Limit]
+	}
+
+	return result, nil
+}
+
+func (t *searchTerms) add(term string, id int) {
+	ids, ok := t.postings[term]
+	if !ok {
+		i := sort.SearchStrings(t.sorted, term)
+		t.sorted = append(t.sorted, "")
+		copy(t.sorted[i+1:], t.sorted[i:])
+		t.sorted[i] = term
+	}
+
+	// the same object can have the term more than once (e.g. the LDH and
+	// the Unicode names are the same)
+	if len(ids) == 0 || ids[len(ids)-1] != id {
+		t.postings[term] = append(ids, id)
+	}
+}
+
+// match returns the terms that match the query. Only the terms starting with
+// the pattern prefix (before the first "*") are compared
+func (t *searchTerms) match(query SearchQuery) []string {
+	i := strings.Index(query.Pattern, "*")
+	if i == -1 {
+		if _, ok := t.postings[query.Pattern]; ok {
+			return []string{query.Pattern}
+		}
+		return nil
+	}
+
+	prefix := query.Pattern[:i]
+
+	var terms []string
+	for j := sort.SearchStrings(t.sorted, prefix); j < len(t.sorted); j++ {
+		if !strings.HasPrefix(t.sorted[j], prefix) {
+			break
+		}
+
+		if query.Match(t.sorted[j]) {
+			terms = append(terms, t.sorted[j])
+		}
+	}
+
+	return terms
+}
+
+func domainSearchTerms(domain protocol.Domain) map[searchField][]string {
+	terms := map[searchField][]string{
+		{queryType: QueryTypeDomains, property: "name"}: searchNames(domain.LDHName, domain.UnicodeName),
+	}
+
+	for _, nameserver := range domain.Nameservers {
+		for field, values := range nameserverSearchTerms(QueryTypeDomains, nameserver) {
+			if field.property == "name" {
+				field.property = "nsLdhName"
+			} else {
+				field.property = "nsIp"
+			}
+			terms[field] = append(terms[field], values...)
+		}
+	}
+
+	return terms
+}
+
+func nameserverSearchTerms(queryType QueryType, nameserver protocol.Nameserver) map[searchField][]string {
+	terms := map[searchField][]string{
+		{queryType: queryType, property: "name"}: searchNames(nameserver.LDHName, nameserver.UnicodeName),
+	}
+
+	if nameserver.IPAddresses != nil {
+		field := searchField{queryType: queryType, property: "ip"}
+		for _, addresses := range [][]string{nameserver.IPAddresses.V4, nameserver.IPAddresses.V6} {
+			for _, address := range addresses {
+				if ip := net.ParseIP(address); ip != nil {
+					terms[field] = append(terms[field], ip.String())
+				}
+			}
+		}
+	}
+
+	return terms
+}
+
+func entitySearchTerms(entity protocol.Entity) map[searchField][]string {
+	terms := map[searchField][]string{
+		{queryType: QueryTypeEntities, property: "handle"}: {strings.ToLower(entity.Handle)},
+	}
+
+	if vcard, err := entity.VCard(); err == nil && vcard != nil && vcard.FN() != "" {
+		terms[searchField{queryType: QueryTypeEntities, property: "fn"}] = []string{strings.ToLower(vcard.FN())}
+	}
+
+	return terms
+}
+
+func searchNames(names ...string) []string {
+	var terms []string
+	for _, name := range names {
+		if name != "" {
+			terms = append(terms, strings.TrimSuffix(strings.ToLower(name), "."))
+		}
+	}
+	return terms
+}
@@ -0,0 +1,131 @@
+package rdap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestSearchIndexSearch(t *testing.T) {
+	index := NewSearchIndex()
+
+	objects := []interface{}{
+		&protocol.Domain{
+			ObjectClassName: "domain",
+			LDHName:         "example.br",
+			Nameservers: []protocol.Nameserver{
+				{LDHName: "a.dns.br", IPAddresses: &protocol.IPAddresses{V6: []string{"2001:DB8::1"}}},
+			},
+		},
+		protocol.Domain{ObjectClassName: "domain", LDHName: "xn--caf-dma.br", UnicodeName: "café.br"},
+		protocol.Domain{ObjectClassName: "domain", LDHName: "exemplo.com.br"},
+		&protocol.Nameserver{ObjectClassName: "nameserver", LDHName: "a.dns.br"},
+		&protocol.Entity{
+			ObjectClassName: "entity",
+			Handle:          "XXXX",
+			VCardArray:      protocol.NewVCard().AddFN("Joe User").Array(),
+		},
+	}
+
+	for _, object := range objects {
+		if err := index.Add(object); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := index.Add(&protocol.AS{}); err == nil {
+		t.Error("Expected an error when adding an unsupported object")
+	}
+
+	data := []struct {
+		description string
+		query       SearchQuery
+		expected    []string
+	}{
+		{
+			description: "it should find a domain by the exact name",
+			query:       SearchQuery{Type: QueryTypeDomains, Property: "name", Pattern: "example.br"},
+			expected:    []string{"example.br"},
+		},
+		{
+			description: "it should find domains by partial name",
+			query:       SearchQuery{Type: QueryTypeDomains, Property: "name", Pattern: "ex*.br"},
+			expected:    []string{"example.br", "exemplo.com.br"},
+		},
+		{
+			description: "it should find a domain by the Unicode name",
+			query:       SearchQuery{Type: QueryTypeDomains, Property: "name", Pattern: "caf*"},
+			expected:    []string{"xn--caf-dma.br"},
+		},
+		{
+			description: "it should find domains with a leading partial name",
+			query:       SearchQuery{Type: QueryTypeDomains, Property: "name", Pattern: "*.br"},
+			expected:    []string{"example.br", "xn--caf-dma.br", "exemplo.com.br"},
+		},
+		{
+			description: "it should limit the number of objects",
+			query:       SearchQuery{Type: QueryTypeDomains, Property: "name", Pattern: "*.br", Limit: 2},
+			expected:    []string{"example.br", "xn--caf-dma.br"},
+		},
+		{
+			description: "it should limit the number of objects after sorting",
+			query: SearchQuery{
+				Type:     QueryTypeDomains,
+				Property: "name",
+				Pattern:  "*.br",
+				Limit:    2,
+				Sorts:    []SearchSort{{Property: "name", Descending: true}},
+			},
+			expected: []string{"xn--caf-dma.br", "exemplo.com.br"},
+		},
+		{
+			description: "it should skip the objects before the offset",
+			query:       SearchQuery{Type: QueryTypeDomains, Property: "name", Pattern: "*.br", Offset: 1, Limit: 1},
+			expected:    []string{"xn--caf-dma.br"},
+		},
+		{
+			description: "it should return no objects after the last one",
+			query:       SearchQuery{Type: QueryTypeDomains, Property: "name", Pattern: "*.br", Offset: 3},
+		},
+		{
+			description: "it should find a domain by the nameserver IP address",
+			query:       SearchQuery{Type: QueryTypeDomains, Property: "nsIp", Pattern: "2001:db8::1"},
+			expected:    []string{"example.br"},
+		},
+		{
+			description: "it should find a nameserver",
+			query:       SearchQuery{Type: QueryTypeNameservers, Property: "name", Pattern: "a.*"},
+			expected:    []string{"a.dns.br"},
+		},
+		{
+			description: "it should find an entity by the full name",
+			query:       SearchQuery{Type: QueryTypeEntities, Property: "fn", Pattern: "joe*"},
+			expected:    []string{"XXXX"},
+		},
+		{
+			description: "it should not find objects of an unknown property",
+			query:       SearchQuery{Type: QueryTypeEntities, Property: "email", Pattern: "joe*"},
+		},
+	}
+
+	for i, item := range data {
+		result, err := index.Search(nil, item.query)
+		if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+			continue
+		}
+
+		var names []string
+		for _, object := range result.Objects {
+			names = append(names, searchNameKey(object))
+			if entity, ok := object.(protocol.Entity); ok {
+				names[len(names)-1] = entity.Handle
+			}
+		}
+
+		if !reflect.DeepEqual(item.expected, names) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, names))
+		}
+	}
+}
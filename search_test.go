@@ -0,0 +1,233 @@
+package rdap
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestMatchSearchPattern(t *testing.T) {
+	data := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{pattern: "example.br", value: "example.br", expected: true},
+		{pattern: "example.br", value: "example.com.br", expected: false},
+		{pattern: "exa*.br", value: "example.br", expected: true},
+		{pattern: "exa*.br", value: "exa.br", expected: true},
+		{pattern: "exa*.br", value: "example.com", expected: false},
+		{pattern: "*.com.br", value: "example.com.br", expected: true},
+		{pattern: "ex*le.*.br", value: "example.com.br", expected: true},
+		{pattern: "ex*le.*.br", value: "exle.br", expected: false},
+		{pattern: "a*a", value: "a", expected: false},
+	}
+
+	for i, item := range data {
+		if result := matchSearchPattern(item.pattern, item.value); result != item.expected {
+			t.Errorf("[%d] expected “%s” matching “%s” to be %t", i, item.pattern, item.value, item.expected)
+		}
+	}
+}
+
+func TestSearchHandlerServeObject(t *testing.T) {
+	index := NewSearchIndex()
+	for _, name := range []string{"example3.br", "example1.br", "example2.br", "other.br"} {
+		index.Add(&protocol.Domain{ObjectClassName: "domain", LDHName: name})
+	}
+
+	domains := func(names ...string) []protocol.Domain {
+		result := []protocol.Domain{}
+		for _, name := range names {
+			result = append(result, protocol.Domain{ObjectClassName: "domain", LDHName: name})
+		}
+		return result
+	}
+
+	data := []struct {
+		description    string
+		handler        *SearchHandler
+		path           string
+		expectedStatus int
+		expected       []protocol.Domain
+		expectedNotice string
+		expectedPaging *protocol.PagingMetadata
+	}{
+		{
+			description:    "it should search domains by partial name",
+			handler:        NewSearchHandler(index),
+			path:           "/domains?name=EXA*.br",
+			expectedStatus: http.StatusOK,
+			expected:       domains("example3.br", "example1.br", "example2.br"),
+		},
+		{
+			description:    "it should sort the results",
+			handler:        NewSearchHandler(index),
+			path:           "/domains?name=exa*.br&sort=name:d",
+			expectedStatus: http.StatusOK,
+			expected:       domains("example3.br", "example2.br", "example1.br"),
+		},
+		{
+			description:    "it should truncate the results",
+			handler:        &SearchHandler{Backend: index, MaxResults: 2},
+			path:           "/domains?name=*.br",
+			expectedStatus: http.StatusOK,
+			expected:       domains("example3.br", "example1.br"),
+			expectedNotice: string(protocol.RemarkTypeResultTruncatedExcessiveLoad),
+		},
+		{
+			description:    "it should limit the page size to the maximum results",
+			handler:        &SearchHandler{Backend: index, MaxResults: 2, PageSize: 10},
+			path:           "/domains?name=*.br&sort=name:d&count=true",
+			expectedStatus: http.StatusOK,
+			expected:       domains("other.br", "example3.br"),
+			expectedPaging: &protocol.PagingMetadata{
+				PageSize:   2,
+				PageNumber: 1,
+				Links: []protocol.Link{
+					{
+						Value: "http://example.com/domains?name=*.br&sort=name:d&count=true",
+						Rel:   "next",
+						Href:  "http://example.com/domains?count=true&cursor=b2Zmc2V0PTI&name=%2A.br&sort=name%3Ad",
+						Title: "Result Pagination",
+						Type:  "application/rdap+json",
+					},
+				},
+			},
+		},
+		{
+			description:    "it should page past the maximum results",
+			handler:        &SearchHandler{Backend: index, MaxResults: 2, PageSize: 2},
+			path:           "/domains?name=*.br&sort=name:d&count=true&cursor=b2Zmc2V0PTI",
+			expectedStatus: http.StatusOK,
+			expected:       domains("example2.br", "example1.br"),
+			expectedPaging: &protocol.PagingMetadata{TotalCount: 4, PageSize: 2, PageNumber: 2},
+		},
+		{
+			description: "it should limit the results of a backend that truncated them",
+			handler: &SearchHandler{
+				Backend: SearchBackendFunc(func(r *http.Request, query SearchQuery) (SearchResult, error) {
+					return SearchResult{
+						Objects:   []interface{}{domains("example1.br")[0], domains("example2.br")[0], domains("example3.br")[0]},
+						Truncated: protocol.RemarkTypeResultTruncatedAuthorization,
+					}, nil
+				}),
+				MaxResults: 2,
+			},
+			path:           "/domains?name=*.br",
+			expectedStatus: http.StatusOK,
+			expected:       domains("example1.br", "example2.br"),
+			expectedNotice: string(protocol.RemarkTypeResultTruncatedAuthorization),
+		},
+		{
+			description: "it should inform the backend truncation",
+			handler: NewSearchHandler(SearchBackendFunc(func(r *http.Request, query SearchQuery) (SearchResult, error) {
+				return SearchResult{Truncated: protocol.RemarkTypeResultTruncatedAuthorization}, nil
+			})),
+			path:           "/domains?name=*.br",
+			expectedStatus: http.StatusOK,
+			expected:       domains(),
+			expectedNotice: string(protocol.RemarkTypeResultTruncatedAuthorization),
+		},
+		{
+			description:    "it should split the results in pages, without the total count before the last page",
+			handler:        &SearchHandler{Backend: index, PageSize: 2},
+			path:           "/domains?name=*.br&sort=name&count=true",
+			expectedStatus: http.StatusOK,
+			expected:       domains("example1.br", "example2.br"),
+			expectedPaging: &protocol.PagingMetadata{
+				PageSize:   2,
+				PageNumber: 1,
+				Links: []protocol.Link{
+					{
+						Value: "http://example.com/domains?name=*.br&sort=name&count=true",
+						Rel:   "next",
+						Href:  "http://example.com/domains?count=true&cursor=b2Zmc2V0PTI&name=%2A.br&sort=name",
+						Title: "Result Pagination",
+						Type:  "application/rdap+json",
+					},
+				},
+			},
+		},
+		{
+			description:    "it should answer the last page",
+			handler:        &SearchHandler{Backend: index, PageSize: 2},
+			path:           "/domains?name=*.br&sort=name&cursor=b2Zmc2V0PTI",
+			expectedStatus: http.StatusOK,
+			expected:       domains("example3.br", "other.br"),
+			expectedPaging: &protocol.PagingMetadata{PageSize: 2, PageNumber: 2},
+		},
+		{
+			description:    "it should detect a missing search property",
+			handler:        NewSearchHandler(index),
+			path:           "/domains?fn=joe",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:    "it should detect a broad search",
+			handler:        NewSearchHandler(index),
+			path:           "/domains?name=*",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:    "it should detect an invalid sort property",
+			handler:        NewSearchHandler(index),
+			path:           "/domains?name=exa*&sort=fn",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:    "it should detect an invalid cursor",
+			handler:        &SearchHandler{Backend: index, PageSize: 2},
+			path:           "/domains?name=exa*&cursor=xxx",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for i, item := range data {
+		server := NewServer()
+		server.Handle(QueryTypeDomains, item.handler)
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest("GET", item.path, nil))
+
+		if w.Code != item.expectedStatus {
+			t.Errorf("[%d] %s: expected HTTP status “%d” and got “%d”", i, item.description, item.expectedStatus, w.Code)
+			continue
+		}
+
+		if w.Code != http.StatusOK {
+			continue
+		}
+
+		var results protocol.DomainSearchResults
+		if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(item.expected, results.Results) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, results.Results))
+		}
+
+		var notice string
+		if len(results.Notices) > 0 {
+			notice = results.Notices[0].Type
+		}
+
+		if notice != item.expectedNotice {
+			t.Errorf("[%d] %s: expected notice “%s” and got “%s”", i, item.description, item.expectedNotice, notice)
+		}
+
+		if !reflect.DeepEqual(item.expectedPaging, results.Paging) {
+			t.Errorf("[%d] “%s”: mismatch paging.\n%v", i, item.description, diff(item.expectedPaging, results.Paging))
+		}
+
+		if results.Sorting == nil || len(results.Sorting.AvailableSorts) != 4 {
+			t.Errorf("[%d] %s: unexpected sorting metadata “%#v”", i, item.description, results.Sorting)
+		}
+	}
+}
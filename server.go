@@ -0,0 +1,383 @@
+package rdap
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+	"golang.org/x/net/idna"
+)
+
+const (
+	// ContentTypeRDAP is the media type of RDAP responses as described in RFC
+	// 7480, section 4.2
+	ContentTypeRDAP = "application/rdap+json"
+
+	// ContentTypeJSON is the generic JSON media type, used for clients (like
+	// browsers) that don't accept the RDAP media type
+	ContentTypeJSON = "application/json"
+)
+
+// ObjectHandler retrieves the objects answered by the server. The query value
+// was already validated and normalized (e.g. lowercase A-label for domains).
+// When the object doesn't exist ErrNotFound must be returned, and when the
+// client can't access the object ErrForbidden. A protocol.Error can be
+// returned to answer with a specific HTTP status code, any other error is
+// answered as an internal server error
+type ObjectHandler interface {
+	ServeObject(r *http.Request, queryType QueryType, queryValue string) (interface{}, error)
+}
+
+// ObjectHandlerFunc is a function type that implements the ObjectHandler
+// interface
+type ObjectHandlerFunc func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error)
+
+// ServeObject calls f(r, queryType, queryValue)
+func (f ObjectHandlerFunc) ServeObject(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+	return f(r, queryType, queryValue)
+}
+
+// ResponseFilter changes the objects answered by the server before they are
+// sent to the client. The filter can change the object, replace it or return
+// an error, that is answered as the backend errors (see ObjectHandler)
+type ResponseFilter interface {
+	FilterResponse(r *http.Request, object interface{}) (interface{}, error)
+}
+
+// ResponseFilterFunc is a function type that implements the ResponseFilter
+// interface
+type ResponseFilterFunc func(r *http.Request, object interface{}) (interface{}, error)
+
+// FilterResponse calls f(r, object)
+func (f ResponseFilterFunc) FilterResponse(r *http.Request, object interface{}) (interface{}, error) {
+	return f(r, object)
+}
+
+// Redirect can be returned by the backends instead of an object to redirect
+// the client to the server that has the object, as described in RFC 7480,
+// section 5.2
+type Redirect struct {
+	URL string
+
+	// StatusCode is the HTTP status of the redirect. If zero 302 Found is
+	// used
+	StatusCode int
+}
+
+// Server is a HTTP handler that answers the RDAP lookup queries described in
+// RFC 9082, using a backend for each query type. The server expects the
+// paths starting in the query type (e.g. "/domain/example.com"), so use
+// http.StripPrefix when the RDAP service isn't in the root of the URL
+type Server struct {
+	// Handlers stores the backend of each query type. Queries without a
+	// backend are answered with 501 Not Implemented
+	Handlers map[QueryType]ObjectHandler
+
+	// Help is the response of help queries
+	Help *protocol.Help
+
+	// AllowOrigin is the value of the CORS header Access-Control-Allow-Origin.
+	// RFC 7480, section 5.6 recommends "*", as RDAP data is public. When
+	// empty no CORS header is sent
+	AllowOrigin string
+
+	// Filters are applied, in order, to the objects and help responses
+	// before they are sent to the client
+	Filters []ResponseFilter
+
+	// ErrorLog logs the backend errors answered as internal server errors. If
+	// nil the errors are not logged
+	ErrorLog *log.Logger
+}
+
+// NewServer returns a server without backends, that only answers help
+// queries. Use Handle to add the backends of each query type
+func NewServer() *Server {
+	return &Server{
+		Handlers:    make(map[QueryType]ObjectHandler),
+		Help:        &protocol.Help{},
+		AllowOrigin: "*",
+	}
+}
+
+// Handle registers the backend of the query type
+func (s *Server) Handle(queryType QueryType, handler ObjectHandler) {
+	if s.Handlers == nil {
+		s.Handlers = make(map[QueryType]ObjectHandler)
+	}
+
+	s.Handlers[queryType] = handler
+}
+
+// ServeHTTP implements the http.Handler interface
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.AllowOrigin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", s.AllowOrigin)
+	}
+
+	switch r.Method {
+	case "GET", "HEAD":
+	case "OPTIONS":
+		// CORS preflight request
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		s.writeError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	object, err := s.query(r)
+	if err != nil {
+		s.writeBackendError(w, r, err)
+		return
+	}
+
+	if redirect, ok := object.(*Redirect); ok {
+		code := redirect.StatusCode
+		if code == 0 {
+			code = http.StatusFound
+		}
+
+		// RFC 7480, section 5.2: only the Location header is needed
+		w.Header().Set("Location", redirect.URL)
+		w.WriteHeader(code)
+		return
+	}
+
+	s.writeObject(w, r, http.StatusOK, object)
+}
+
+// query retrieves the object of the query from the backends and applies the
+// filters. Redirects are returned without filtering
+func (s *Server) query(r *http.Request) (interface{}, error) {
+	object, err := s.serveObject(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := object.(*Redirect); ok {
+		return object, nil
+	}
+
+	for _, filter := range s.Filters {
+		if object, err = filter.FilterResponse(r, object); err != nil {
+			return nil, err
+		}
+	}
+
+	return object, nil
+}
+
+// serveObject retrieves the object of the query from the backends
+func (s *Server) serveObject(r *http.Request) (interface{}, error) {
+	queryType, queryValue := splitQueryPath(r.URL.Path)
+
+	if queryType == QueryTypeHelp && queryValue == "" {
+		// the help is copied, so filters don't change the server help
+		var help protocol.Help
+		if s.Help != nil {
+			help = *s.Help
+		}
+
+		if len(help.Levels) == 0 {
+			help.SetConformance([]string{protocol.ConformanceLevel0})
+		}
+
+		return &help, nil
+	}
+
+	queryValue, ok := normalizeQueryValue(queryType, queryValue)
+	if !ok {
+		return nil, protocol.Error{
+			ErrorCode:   http.StatusBadRequest,
+			Title:       "invalid query",
+			Description: []string{"the query is malformed"},
+		}
+	}
+
+	handler, ok := s.Handlers[queryType]
+	if !ok {
+		return nil, protocol.Error{
+			ErrorCode:   http.StatusNotImplemented,
+			Title:       "query not implemented",
+			Description: []string{"the server doesn't support this query type"},
+		}
+	}
+
+	return handler.ServeObject(r, queryType, queryValue)
+}
+
+func (s *Server) writeBackendError(w http.ResponseWriter, r *http.Request, err error) {
+	switch e := err.(type) {
+	case protocol.Error:
+		s.writeProtocolError(w, r, e)
+		return
+	case *protocol.Error:
+		s.writeProtocolError(w, r, *e)
+		return
+	case UnauthorizedError:
+		for _, challenge := range e.Challenges {
+			w.Header().Add("WWW-Authenticate", challenge)
+		}
+		s.writeProtocolError(w, r, e.Response)
+		return
+	case RateLimitError:
+		// the delay is rounded up to the next second
+		w.Header().Set("Retry-After", strconv.FormatInt(int64((e.RetryAfter+time.Second-1)/time.Second), 10))
+		s.writeProtocolError(w, r, e.Response)
+		return
+	}
+
+	switch err {
+	case ErrNotFound:
+		s.writeError(w, r, http.StatusNotFound, "not found")
+	case ErrForbidden:
+		s.writeError(w, r, http.StatusForbidden, "forbidden")
+	default:
+		if s.ErrorLog != nil {
+			s.ErrorLog.Printf("rdap: error answering %s: %s", r.URL.Path, err)
+		}
+		s.writeError(w, r, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, code int, title string, description ...string) {
+	s.writeProtocolError(w, r, protocol.Error{
+		ErrorCode:   code,
+		Title:       title,
+		Description: description,
+	})
+}
+
+func (s *Server) writeProtocolError(w http.ResponseWriter, r *http.Request, e protocol.Error) {
+	if e.ErrorCode == 0 {
+		e.ErrorCode = http.StatusInternalServerError
+	}
+
+	if len(e.Levels) == 0 {
+		e.SetConformance([]string{protocol.ConformanceLevel0})
+	}
+
+	s.writeObject(w, r, e.ErrorCode, e)
+}
+
+// writeObject sends the object as JSON, using the content type accepted by the
+// client. The body is omitted in HEAD requests
+func (s *Server) writeObject(w http.ResponseWriter, r *http.Request, code int, object interface{}) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		if s.ErrorLog != nil {
+			s.ErrorLog.Printf("rdap: error encoding %s: %s", r.URL.Path, err)
+		}
+		code = http.StatusInternalServerError
+		data = []byte(`{"errorCode":500,"title":"internal server error"}`)
+	}
+
+	w.Header().Set("Content-Type", negotiateContentType(r.Header.Get("Accept")))
+
+	if code == http.StatusOK && notModified(w, r, data, object) {
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(code)
+
+	if r.Method != "HEAD" {
+		w.Write(data)
+	}
+}
+
+// negotiateContentType returns the RDAP media type, unless the client only
+// accepts the generic JSON media type (RFC 7480, section 4.2)
+func negotiateContentType(accept string) string {
+	var acceptJSON bool
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.Split(mediaRange, ";")[0])
+
+		switch strings.ToLower(mediaType) {
+		case ContentTypeRDAP, "application/*", "*/*":
+			return ContentTypeRDAP
+		case ContentTypeJSON:
+			acceptJSON = true
+		}
+	}
+
+	if acceptJSON {
+		return ContentTypeJSON
+	}
+
+	return ContentTypeRDAP
+}
+
+// splitQueryPath splits the path in the query type and the query value. The
+// query value can contain slashes (e.g. "/ip/192.0.2.0/24")
+func splitQueryPath(path string) (QueryType, string) {
+	path = strings.TrimPrefix(path, "/")
+
+	i := strings.Index(path, "/")
+	if i == -1 {
+		return QueryType(path), ""
+	}
+
+	return QueryType(path[:i]), path[i+1:]
+}
+
+// normalizeQueryValue checks the query value format of the query type, and
+// returns it in the canonical format
+func normalizeQueryValue(queryType QueryType, queryValue string) (string, bool) {
+	switch queryType {
+	case QueryTypeDomains, QueryTypeNameservers, QueryTypeEntities:
+		// the search pattern is in the query string
+		return "", queryValue == ""
+	}
+
+	if queryValue == "" {
+		return "", false
+	}
+
+	switch queryType {
+	case QueryTypeDomain, QueryTypeNameserver:
+		fqdn, err := idna.ToASCII(strings.ToLower(queryValue))
+		if err != nil || !fqdnRX.MatchString(fqdn) {
+			return "", false
+		}
+		return strings.TrimSuffix(fqdn, "."), true
+
+	case QueryTypeIP:
+		if ip := net.ParseIP(queryValue); ip != nil {
+			return ip.String(), true
+		}
+
+		if _, ipnet, err := net.ParseCIDR(queryValue); err == nil {
+			return ipnet.String(), true
+		}
+
+		return "", false
+
+	case QueryTypeAutnum:
+		asn, err := strconv.ParseUint(queryValue, 10, 32)
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatUint(asn, 10), true
+
+	case QueryTypeTicket:
+		ticket, err := strconv.ParseUint(queryValue, 10, 64)
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatUint(ticket, 10), true
+	}
+
+	return queryValue, true
+}
@@ -0,0 +1,183 @@
+package rdap
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// DefaultConformanceExtensions maps the JSON members of the RDAP extensions
+// supported by the protocol package to their conformance identifiers. A name
+// ending with "_" matches all members with that prefix
+var DefaultConformanceExtensions = map[string]string{
+	"nicbr_":           protocol.NICBRConformance,
+	"redacted":         protocol.RedactedConformance,
+	"jscontact_card":   protocol.JSContactConformance,
+	"sorting_metadata": protocol.SortingConformance,
+	"paging_metadata":  protocol.PagingConformance,
+}
+
+// ResponseAnnotator is a ResponseFilter that fills the members that every
+// response should have: the rdapConformance (RFC 9083, section 4.1), computed
+// from the extension members present in the object, the port43 (RFC 9083,
+// section 4.7) and the server notices (RFC 9083, section 4.3), like the terms
+// of service. Only objects returned as pointers can be annotated
+type ResponseAnnotator struct {
+	// Port43 is the WHOIS server of the objects. Objects that already have a
+	// port43 are not changed
+	Port43 string
+
+	// Notices are appended to the notices of the top-level objects, unless
+	// the object already has a notice with the same title
+	Notices []protocol.Notice
+
+	// Extensions maps the extension members to their conformance
+	// identifiers. When nil DefaultConformanceExtensions is used
+	Extensions map[string]string
+}
+
+// FilterResponse implements the ResponseFilter interface
+func (a ResponseAnnotator) FilterResponse(r *http.Request, object interface{}) (interface{}, error) {
+	if setter, ok := object.(protocol.ConformanceSetter); ok {
+		levels, err := a.Conformance(object)
+		if err != nil {
+			return nil, err
+		}
+		setter.SetConformance(levels)
+	}
+
+	if port43 := objectPort43(object); port43 != nil && *port43 == "" {
+		*port43 = a.Port43
+	}
+
+	if notices := objectNotices(object); notices != nil {
+		for _, notice := range a.Notices {
+			if !hasNotice(*notices, notice.Title) {
+				*notices = append(*notices, notice)
+			}
+		}
+	}
+
+	return object, nil
+}
+
+// Conformance returns the rdapConformance of the object. The result contains
+// the levels already in the object, the RDAP level 0 and the extensions with
+// members in the object or in any of the nested objects
+func (a ResponseAnnotator) Conformance(object interface{}) ([]string, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	var content interface{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+
+	extensions := a.Extensions
+	if extensions == nil {
+		extensions = DefaultConformanceExtensions
+	}
+
+	found := make(map[string]bool)
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, member := range v {
+				if key == "rdapConformance" {
+					levels, _ := member.([]interface{})
+					for _, level := range levels {
+						if level, ok := level.(string); ok {
+							found[level] = true
+						}
+					}
+					continue
+				}
+
+				for name, level := range extensions {
+					if key == name || (strings.HasSuffix(name, "_") && strings.HasPrefix(key, name)) {
+						found[level] = true
+					}
+				}
+
+				walk(member)
+			}
+
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(content)
+
+	delete(found, protocol.ConformanceLevel0)
+	levels := make([]string, 0, len(found))
+	for level := range found {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+
+	return append([]string{protocol.ConformanceLevel0}, levels...), nil
+}
+
+// objectPort43 returns the port43 of the objects. The nameserver doesn't
+// embed protocol.Port43, so protocol.Port43Setter can't be used to check if
+// the object already has a port43
+func objectPort43(object interface{}) *string {
+	switch o := object.(type) {
+	case *protocol.Domain:
+		return &o.Port43.Port43
+	case *protocol.Entity:
+		return &o.Port43.Port43
+	case *protocol.Nameserver:
+		return &o.Port43
+	case *protocol.IPNetwork:
+		return &o.Port43.Port43
+	case *protocol.AS:
+		return &o.Port43.Port43
+	}
+
+	return nil
+}
+
+// objectNotices returns the notices of the top-level objects
+func objectNotices(object interface{}) *[]protocol.Notice {
+	switch o := object.(type) {
+	case *protocol.Domain:
+		return &o.Notices
+	case *protocol.Entity:
+		return &o.Notices
+	case *protocol.Nameserver:
+		return &o.Notices
+	case *protocol.IPNetwork:
+		return &o.Notices
+	case *protocol.AS:
+		return &o.Notices
+	case *protocol.Help:
+		return &o.Notices
+	case *protocol.DomainSearchResults:
+		return &o.Notices
+	case *protocol.NameserverSearchResults:
+		return &o.Notices
+	case *protocol.EntitySearchResults:
+		return &o.Notices
+	}
+
+	return nil
+}
+
+func hasNotice(notices []protocol.Notice, title string) bool {
+	for _, notice := range notices {
+		if notice.Title == title {
+			return true
+		}
+	}
+
+	return false
+}
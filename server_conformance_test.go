@@ -0,0 +1,165 @@
+package rdap
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestResponseAnnotatorFilterResponse(t *testing.T) {
+	tos := protocol.Notice{
+		Title:       "Terms of Use",
+		Description: []string{"Service subject to terms of use."},
+	}
+
+	data := []struct {
+		description string
+		annotator   ResponseAnnotator
+		object      interface{}
+		expected    interface{}
+	}{
+		{
+			description: "it should annotate a domain with extension members",
+			annotator:   ResponseAnnotator{Port43: "whois.registro.br", Notices: []protocol.Notice{tos}},
+			object: &protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "example.br",
+				Entities: []protocol.Entity{
+					{ObjectClassName: "entity", Handle: "XXX", DomainCount: 1},
+				},
+				Redacted: []protocol.Redacted{
+					{Name: protocol.RedactedName{Type: "Registrant Name"}},
+				},
+			},
+			expected: &protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "example.br",
+				Entities: []protocol.Entity{
+					{ObjectClassName: "entity", Handle: "XXX", DomainCount: 1},
+				},
+				Redacted: []protocol.Redacted{
+					{Name: protocol.RedactedName{Type: "Registrant Name"}},
+				},
+				Notices:     []protocol.Notice{tos},
+				Conformance: protocol.Conformance{Levels: []string{"rdap_level_0", "nicbr_level_0", "redacted"}},
+				Port43:      protocol.Port43{Port43: "whois.registro.br"},
+			},
+		},
+		{
+			description: "it should keep the object port43, levels and notices",
+			annotator:   ResponseAnnotator{Port43: "whois.registro.br", Notices: []protocol.Notice{tos}},
+			object: &protocol.Nameserver{
+				ObjectClassName: "nameserver",
+				LDHName:         "a.dns.br",
+				Port43:          "whois.example.br",
+				Notices:         []protocol.Notice{{Title: "Terms of Use"}},
+				Conformance:     protocol.Conformance{Levels: []string{"example_level_0"}},
+			},
+			expected: &protocol.Nameserver{
+				ObjectClassName: "nameserver",
+				LDHName:         "a.dns.br",
+				Port43:          "whois.example.br",
+				Notices:         []protocol.Notice{{Title: "Terms of Use"}},
+				Conformance:     protocol.Conformance{Levels: []string{"rdap_level_0", "example_level_0"}},
+			},
+		},
+		{
+			description: "it should use the configured extensions",
+			annotator:   ResponseAnnotator{Extensions: map[string]string{"nicbr_arbitration": "arbitration"}},
+			object: &protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "example.br",
+				Arbitration:     true,
+			},
+			expected: &protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "example.br",
+				Arbitration:     true,
+				Conformance:     protocol.Conformance{Levels: []string{"rdap_level_0", "arbitration"}},
+			},
+		},
+		{
+			description: "it should ignore objects that can't be annotated",
+			annotator:   ResponseAnnotator{Port43: "whois.registro.br", Notices: []protocol.Notice{tos}},
+			object:      protocol.Domain{ObjectClassName: "domain", LDHName: "example.br"},
+			expected:    protocol.Domain{ObjectClassName: "domain", LDHName: "example.br"},
+		},
+	}
+
+	for i, item := range data {
+		object, err := item.annotator.FilterResponse(nil, item.object)
+		if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(item.expected, object) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, object))
+		}
+	}
+}
+
+func TestServerFilters(t *testing.T) {
+	server := NewServer()
+	server.Help = &protocol.Help{}
+	server.Filters = []ResponseFilter{
+		ResponseAnnotator{Port43: "whois.registro.br"},
+		ResponseFilterFunc(func(r *http.Request, object interface{}) (interface{}, error) {
+			if r.URL.Query().Get("fail") != "" {
+				return nil, fmt.Errorf("filter failure")
+			}
+			return object, nil
+		}),
+	}
+
+	server.Handle(QueryTypeDomain, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		return &protocol.Domain{ObjectClassName: "domain", LDHName: queryValue}, nil
+	}))
+
+	data := []struct {
+		description    string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			description:    "it should filter the objects",
+			path:           "/domain/example.br",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"objectClassName":"domain","ldhName":"example.br","rdapConformance":["rdap_level_0"],"port43":"whois.registro.br"}`,
+		},
+		{
+			description:    "it should filter the help",
+			path:           "/help",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"rdapConformance":["rdap_level_0"]}`,
+		},
+		{
+			description:    "it should detect a filter error",
+			path:           "/domain/example.br?fail=1",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"errorCode":500,"title":"internal server error","rdapConformance":["rdap_level_0"]}`,
+		},
+	}
+
+	for i, item := range data {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest("GET", item.path, nil))
+
+		if w.Code != item.expectedStatus {
+			t.Errorf("[%d] %s: expected HTTP status “%d” and got “%d”", i, item.description, item.expectedStatus, w.Code)
+		}
+
+		if body := w.Body.String(); body != item.expectedBody {
+			t.Errorf("[%d] %s: expected body “%s” and got “%s”", i, item.description, item.expectedBody, body)
+		}
+	}
+
+	if server.Help.Levels != nil {
+		t.Errorf("Unexpected change in the server help. Got “%v”", server.Help.Levels)
+	}
+}
@@ -0,0 +1,222 @@
+package rdap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestServerServeHTTP(t *testing.T) {
+	server := NewServer()
+	server.Help = &protocol.Help{
+		Notices: []protocol.Notice{
+			{Title: "Terms of Use", Description: []string{"Service subject to terms of use."}},
+		},
+	}
+
+	server.Handle(QueryTypeDomain, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		switch queryValue {
+		case "example.br", "xn--caf-dma.br":
+			return &protocol.Domain{ObjectClassName: "domain", LDHName: queryValue}, nil
+		case "forbidden.br":
+			return nil, ErrForbidden
+		case "error.br":
+			return nil, fmt.Errorf("database is down")
+		case "custom.br":
+			return nil, protocol.Error{ErrorCode: http.StatusTooManyRequests, Title: "too many requests"}
+		case "moved.br":
+			return &Redirect{URL: "https://rdap.example.com/domain/moved.br"}, nil
+		}
+		return nil, ErrNotFound
+	}))
+
+	server.Handle(QueryTypeIP, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		return &protocol.IPNetwork{ObjectClassName: "ip network", Handle: queryValue}, nil
+	}))
+
+	data := []struct {
+		description         string
+		method              string
+		path                string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        interface{}
+	}{
+		{
+			description:         "it should answer a domain query",
+			method:              "GET",
+			path:                "/domain/EXAMPLE.br.",
+			accept:              "application/rdap+json",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/rdap+json",
+			expectedBody:        &protocol.Domain{ObjectClassName: "domain", LDHName: "example.br"},
+		},
+		{
+			description:         "it should convert an IDN query to A-label",
+			method:              "GET",
+			path:                "/domain/café.br",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/rdap+json",
+			expectedBody:        &protocol.Domain{ObjectClassName: "domain", LDHName: "xn--caf-dma.br"},
+		},
+		{
+			description:         "it should answer with the generic JSON media type",
+			method:              "GET",
+			path:                "/domain/example.br",
+			accept:              "text/html, application/json;q=0.9",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody:        &protocol.Domain{ObjectClassName: "domain", LDHName: "example.br"},
+		},
+		{
+			description:         "it should answer a CIDR query",
+			method:              "GET",
+			path:                "/ip/192.0.2.1/24",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/rdap+json",
+			expectedBody:        &protocol.IPNetwork{ObjectClassName: "ip network", Handle: "192.0.2.0/24"},
+		},
+		{
+			description:         "it should answer a help query",
+			method:              "GET",
+			path:                "/help",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/rdap+json",
+			expectedBody: &protocol.Help{
+				Notices: []protocol.Notice{
+					{Title: "Terms of Use", Description: []string{"Service subject to terms of use."}},
+				},
+				Conformance: protocol.Conformance{Levels: []string{"rdap_level_0"}},
+			},
+		},
+		{
+			description:         "it should answer a HEAD request without body",
+			method:              "HEAD",
+			path:                "/domain/example.br",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/rdap+json",
+		},
+		{
+			description:         "it should detect an object that doesn't exist",
+			method:              "GET",
+			path:                "/domain/notfound.br",
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: "application/rdap+json",
+			expectedBody:        errorBody(http.StatusNotFound, "not found"),
+		},
+		{
+			description:         "it should detect a forbidden object",
+			method:              "GET",
+			path:                "/domain/forbidden.br",
+			expectedStatus:      http.StatusForbidden,
+			expectedContentType: "application/rdap+json",
+			expectedBody:        errorBody(http.StatusForbidden, "forbidden"),
+		},
+		{
+			description:         "it should hide backend errors",
+			method:              "GET",
+			path:                "/domain/error.br",
+			expectedStatus:      http.StatusInternalServerError,
+			expectedContentType: "application/rdap+json",
+			expectedBody:        errorBody(http.StatusInternalServerError, "internal server error"),
+		},
+		{
+			description:         "it should answer with the backend protocol error",
+			method:              "GET",
+			path:                "/domain/custom.br",
+			expectedStatus:      http.StatusTooManyRequests,
+			expectedContentType: "application/rdap+json",
+			expectedBody:        errorBody(http.StatusTooManyRequests, "too many requests"),
+		},
+		{
+			description:    "it should redirect to the authoritative server",
+			method:         "GET",
+			path:           "/domain/moved.br",
+			expectedStatus: http.StatusFound,
+		},
+		{
+			description:         "it should detect an invalid query",
+			method:              "GET",
+			path:                "/autnum/AS65536",
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/rdap+json",
+			expectedBody:        errorBody(http.StatusBadRequest, "invalid query", "the query is malformed"),
+		},
+		{
+			description:         "it should detect a query type without backend",
+			method:              "GET",
+			path:                "/autnum/65536",
+			expectedStatus:      http.StatusNotImplemented,
+			expectedContentType: "application/rdap+json",
+			expectedBody:        errorBody(http.StatusNotImplemented, "query not implemented", "the server doesn't support this query type"),
+		},
+		{
+			description:         "it should detect an invalid method",
+			method:              "POST",
+			path:                "/domain/example.br",
+			expectedStatus:      http.StatusMethodNotAllowed,
+			expectedContentType: "application/rdap+json",
+			expectedBody:        errorBody(http.StatusMethodNotAllowed, "method not allowed"),
+		},
+		{
+			description:    "it should answer a CORS preflight request",
+			method:         "OPTIONS",
+			path:           "/domain/example.br",
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for i, item := range data {
+		r := httptest.NewRequest(item.method, item.path, nil)
+		if item.accept != "" {
+			r.Header.Set("Accept", item.accept)
+		}
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+
+		if w.Code != item.expectedStatus {
+			t.Errorf("[%d] %s: expected HTTP status “%d” and got “%d”", i, item.description, item.expectedStatus, w.Code)
+		}
+
+		if contentType := w.Header().Get("Content-Type"); contentType != item.expectedContentType {
+			t.Errorf("[%d] %s: expected content type “%s” and got “%s”", i, item.description, item.expectedContentType, contentType)
+		}
+
+		if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "*" {
+			t.Errorf("[%d] %s: expected CORS origin “*” and got “%s”", i, item.description, origin)
+		}
+
+		if item.expectedBody == nil {
+			if w.Body.Len() > 0 {
+				t.Errorf("[%d] %s: unexpected body “%s”", i, item.description, w.Body.String())
+			}
+			continue
+		}
+
+		body := reflect.New(reflect.TypeOf(item.expectedBody).Elem()).Interface()
+		if err := json.Unmarshal(w.Body.Bytes(), body); err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(item.expectedBody, body) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expectedBody, body))
+		}
+	}
+}
+
+func errorBody(code int, title string, description ...string) *protocol.Error {
+	return &protocol.Error{
+		ErrorCode:   code,
+		Title:       title,
+		Description: description,
+		Conformance: protocol.Conformance{Levels: []string{"rdap_level_0"}},
+	}
+}
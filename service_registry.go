@@ -15,7 +15,7 @@ const version = "1.0"
 // ServiceRegistry reflects the structure of a RDAP Bootstrap Service
 // Registry.
 //
-// See http://tools.ietf.org/html/rfc7484#section-10.2
+// See http://tools.ietf.org/html/rfc9224#section-3
 type serviceRegistry struct {
 	Version     string    `json:"version"`
 	Publication time.Time `json:"publication"`
@@ -46,7 +46,7 @@ func (s service) uris() []string {
 // MatchAS iterates through a list of services looking for the more
 // specific range to which an AS number "asn" belongs.
 //
-// See http://tools.ietf.org/html/rfc7484#section-5.3
+// See http://tools.ietf.org/html/rfc9224#section-5.3
 func (s serviceRegistry) matchAS(asn uint32) (uris []string, err error) {
 	size := uint64(math.MaxUint32)
 
@@ -90,8 +90,8 @@ func (s serviceRegistry) matchAS(asn uint32) (uris []string, err error) {
 // MatchIPNetwork iterates through a list of services looking for the more
 // specific IP network to which the IP network "network" belongs.
 //
-// See http://tools.ietf.org/html/rfc7484#section-5.1
-//     http://tools.ietf.org/html/rfc7484#section-5.2
+// See http://tools.ietf.org/html/rfc9224#section-5.1
+//     http://tools.ietf.org/html/rfc9224#section-5.2
 func (s serviceRegistry) matchIPNetwork(network *net.IPNet) (uris []string, err error) {
 	size := 0
 
@@ -124,8 +124,8 @@ func (s serviceRegistry) matchIPNetwork(network *net.IPNet) (uris []string, err
 // MatchIP iterates through a list of services looking for the more
 // specific IP network to which the IP belongs.
 //
-// See http://tools.ietf.org/html/rfc7484#section-5.1
-//     http://tools.ietf.org/html/rfc7484#section-5.2
+// See http://tools.ietf.org/html/rfc9224#section-5.1
+//     http://tools.ietf.org/html/rfc9224#section-5.2
 func (s serviceRegistry) matchIP(ip net.IP) (uris []string, err error) {
 	size := 0
 
@@ -148,21 +148,22 @@ func (s serviceRegistry) matchIP(ip net.IP) (uris []string, err error) {
 }
 
 // MatchDomain iterates through a list of services looking for the label-wise
-// longest match of the target domain name "fqdn".
+// longest match of the target domain name "fqdn". The comparison is case
+// insensitive and the trailing dot of absolute names is ignored.
 //
-// See http://tools.ietf.org/html/rfc7484#section-4
+// See http://tools.ietf.org/html/rfc9224#section-4
 func (s serviceRegistry) matchDomain(fqdn string) (uris []string, err error) {
 	var size int
 
 	if fqdn, err = idna.ToASCII(fqdn); err != nil {
 		return nil, err
 	}
-	fqdnParts := strings.Split(fqdn, ".")
+	fqdnParts := strings.Split(strings.ToLower(strings.TrimSuffix(fqdn, ".")), ".")
 
 	for _, service := range s.Services {
 	Entries:
 		for _, entry := range service.entries() {
-			entryParts := strings.Split(entry, ".")
+			entryParts := strings.Split(strings.ToLower(strings.TrimSuffix(entry, ".")), ".")
 
 			if len(fqdnParts) < len(entryParts) {
 				continue
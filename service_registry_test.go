@@ -266,6 +266,19 @@ func TestServiceRegistryMatchDomain(t *testing.T) {
 			},
 			expected: []string{"https://example.com/myrdap"},
 		},
+		{
+			description: "it should match ignoring case and the trailing dot",
+			fqdn:        "WWW.Example.COM.",
+			registry: serviceRegistry{
+				Services: []service{
+					{
+						{"Com."},
+						{"https://example.com/myrdap/"},
+					},
+				},
+			},
+			expected: []string{"https://example.com/myrdap"},
+		},
 		{
 			description: "it should match no fqdn",
 			fqdn:        "a.example.com",
@@ -0,0 +1,523 @@
+package rdap
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// ObjectStore retrieves the RDAP objects by their keys. When the object
+// doesn't exist ErrNotFound is returned. The objects returned are copies, so
+// the server filters can change the top-level members (e.g. notices, remarks
+// and entities) without affecting the stored objects
+type ObjectStore interface {
+	// Domain retrieves the domain by the LDH name
+	Domain(fqdn string) (*protocol.Domain, error)
+
+	// Nameserver retrieves the nameserver by the LDH name
+	Nameserver(fqdn string) (*protocol.Nameserver, error)
+
+	// Entity retrieves the entity by the handle
+	Entity(handle string) (*protocol.Entity, error)
+
+	// IPNetwork retrieves the most specific IP network that contains the
+	// whole network given (longest prefix match)
+	IPNetwork(network *net.IPNet) (*protocol.IPNetwork, error)
+
+	// AS retrieves the smallest AS range that contains the AS number
+	AS(asn uint32) (*protocol.AS, error)
+}
+
+// StoreHandler returns a backend that answers the domain, nameserver,
+// entity, IP and autnum queries with the objects of the store
+func StoreHandler(store ObjectStore) ObjectHandler {
+	return ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		var object interface{}
+		var err error
+
+		switch queryType {
+		case QueryTypeDomain:
+			object, err = store.Domain(queryValue)
+		case QueryTypeNameserver:
+			object, err = store.Nameserver(queryValue)
+		case QueryTypeEntity:
+			object, err = store.Entity(queryValue)
+
+		case QueryTypeIP:
+			network := parseIPQuery(queryValue)
+			if network == nil {
+				return nil, ErrNotFound
+			}
+			object, err = store.IPNetwork(network)
+
+		case QueryTypeAutnum:
+			asn, parseErr := strconv.ParseUint(queryValue, 10, 32)
+			if parseErr != nil {
+				return nil, ErrNotFound
+			}
+			object, err = store.AS(uint32(asn))
+
+		default:
+			return nil, ErrNotFound
+		}
+
+		// the explicit nil avoids returning a nil pointer inside the interface
+		if err != nil {
+			return nil, err
+		}
+
+		return object, nil
+	})
+}
+
+// HandleStore registers the store as the backend of the domain, nameserver,
+// entity, IP and autnum queries
+func (s *Server) HandleStore(store ObjectStore) {
+	handler := StoreHandler(store)
+
+	for _, queryType := range []QueryType{QueryTypeDomain, QueryTypeNameserver, QueryTypeEntity, QueryTypeIP, QueryTypeAutnum} {
+		s.Handle(queryType, handler)
+	}
+}
+
+// parseIPQuery converts the IP query value, that can be an address or a CIDR,
+// to a network
+func parseIPQuery(value string) *net.IPNet {
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(8*net.IPv4len, 8*net.IPv4len)}
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(8*net.IPv6len, 8*net.IPv6len)}
+}
+
+// MemoryStore is an ObjectStore that keeps the objects in memory. The IP
+// networks are stored in a binary trie for the longest prefix match. It's
+// safe for concurrent use
+type MemoryStore struct {
+	mu          sync.RWMutex
+	domains     map[string]protocol.Domain
+	nameservers map[string]protocol.Nameserver
+	entities    map[string]protocol.Entity
+	networks    []storedIPNetwork
+	ipv4        *ipTrieNode
+	ipv6        *ipTrieNode
+	asns        []protocol.AS
+}
+
+// storedIPNetwork is an IP network with the parsed address range
+type storedIPNetwork struct {
+	network    protocol.IPNetwork
+	start, end net.IP
+}
+
+// ipTrieNode is a node of the binary trie, where each level is a bit of the
+// IP address. The networks are stored in the node of the smallest CIDR that
+// contains them
+type ipTrieNode struct {
+	children [2]*ipTrieNode
+	networks []int
+}
+
+// NewMemoryStore returns an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		domains:     make(map[string]protocol.Domain),
+		nameservers: make(map[string]protocol.Nameserver),
+		entities:    make(map[string]protocol.Entity),
+		ipv4:        &ipTrieNode{},
+		ipv6:        &ipTrieNode{},
+	}
+}
+
+// Add stores a domain, nameserver, entity, IP network or AS, as a value or as
+// a pointer. The store keeps a copy of the object. Domains, nameservers and
+// entities with the same key are replaced
+func (m *MemoryStore) Add(object interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch o := object.(type) {
+	case *protocol.Domain:
+		m.domains[storeName(o.LDHName)] = copyDomain(*o)
+	case protocol.Domain:
+		m.domains[storeName(o.LDHName)] = copyDomain(o)
+	case *protocol.Nameserver:
+		m.nameservers[storeName(o.LDHName)] = copyNameserver(*o)
+	case protocol.Nameserver:
+		m.nameservers[storeName(o.LDHName)] = copyNameserver(o)
+	case *protocol.Entity:
+		m.entities[o.Handle] = copyEntity(*o)
+	case protocol.Entity:
+		m.entities[o.Handle] = copyEntity(o)
+	case *protocol.IPNetwork:
+		return m.addIPNetwork(*o)
+	case protocol.IPNetwork:
+		return m.addIPNetwork(o)
+	case *protocol.AS:
+		return m.addAS(*o)
+	case protocol.AS:
+		return m.addAS(o)
+	default:
+		return fmt.Errorf("unsupported store object %T", object)
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) addIPNetwork(network protocol.IPNetwork) error {
+	prefix := ipNetworkRange(&network)
+	if prefix == nil {
+		return fmt.Errorf("invalid IP network range %q - %q", network.StartAddress, network.EndAddress)
+	}
+
+	stored := storedIPNetwork{
+		network: copyIPNetwork(network),
+		start:   net.ParseIP(network.StartAddress),
+		end:     net.ParseIP(network.EndAddress),
+	}
+
+	if len(prefix.IP) == net.IPv4len {
+		stored.start, stored.end = stored.start.To4(), stored.end.To4()
+	}
+
+	if bytes.Compare(stored.start, stored.end) > 0 {
+		return fmt.Errorf("invalid IP network range %q - %q", network.StartAddress, network.EndAddress)
+	}
+
+	node := m.ipv6
+	if len(prefix.IP) == net.IPv4len {
+		node = m.ipv4
+	}
+
+	size, _ := prefix.Mask.Size()
+	for i := 0; i < size; i++ {
+		bit := ipBit(prefix.IP, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &ipTrieNode{}
+		}
+		node = node.children[bit]
+	}
+
+	node.networks = append(node.networks, len(m.networks))
+	m.networks = append(m.networks, stored)
+	return nil
+}
+
+func (m *MemoryStore) addAS(as protocol.AS) error {
+	if as.EndAutnum == 0 {
+		as.EndAutnum = as.StartAutnum
+	}
+
+	if as.StartAutnum > as.EndAutnum {
+		return fmt.Errorf("invalid AS range %d - %d", as.StartAutnum, as.EndAutnum)
+	}
+
+	m.asns = append(m.asns, copyAS(as))
+	return nil
+}
+
+// Domain implements the ObjectStore interface
+func (m *MemoryStore) Domain(fqdn string) (*protocol.Domain, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	domain, ok := m.domains[storeName(fqdn)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	domain = copyDomain(domain)
+	return &domain, nil
+}
+
+// Nameserver implements the ObjectStore interface
+func (m *MemoryStore) Nameserver(fqdn string) (*protocol.Nameserver, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nameserver, ok := m.nameservers[storeName(fqdn)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	nameserver = copyNameserver(nameserver)
+	return &nameserver, nil
+}
+
+// Entity implements the ObjectStore interface
+func (m *MemoryStore) Entity(handle string) (*protocol.Entity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entity, ok := m.entities[handle]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	entity = copyEntity(entity)
+	return &entity, nil
+}
+
+// IPNetwork implements the ObjectStore interface
+func (m *MemoryStore) IPNetwork(network *net.IPNet) (*protocol.IPNetwork, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	start := network.IP.Mask(network.Mask)
+	if start == nil {
+		return nil, ErrNotFound
+	}
+
+	// IPv4 networks can have a 16 bytes mask
+	mask := network.Mask[len(network.Mask)-len(start):]
+
+	end := make(net.IP, len(start))
+	for i := range start {
+		end[i] = start[i] | ^mask[i]
+	}
+
+	node := m.ipv6
+	if len(start) == net.IPv4len {
+		node = m.ipv4
+	}
+
+	// the nodes in the path of the network prefix are visited from the most
+	// specific to the least specific
+	size, _ := mask.Size()
+	path := []*ipTrieNode{node}
+	for i := 0; i < size && node != nil; i++ {
+		if node = node.children[ipBit(start, i)]; node != nil {
+			path = append(path, node)
+		}
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		var found *storedIPNetwork
+
+		for _, id := range path[i].networks {
+			candidate := &m.networks[id]
+			if bytes.Compare(candidate.start, start) > 0 || bytes.Compare(candidate.end, end) < 0 {
+				continue
+			}
+
+			if found == nil || bytes.Compare(candidate.start, found.start) > 0 ||
+				(bytes.Equal(candidate.start, found.start) && bytes.Compare(candidate.end, found.end) < 0) {
+				found = candidate
+			}
+		}
+
+		if found != nil {
+			network := copyIPNetwork(found.network)
+			return &network, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// AS implements the ObjectStore interface
+func (m *MemoryStore) AS(asn uint32) (*protocol.AS, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var found *protocol.AS
+	for i, as := range m.asns {
+		if asn < as.StartAutnum || asn > as.EndAutnum {
+			continue
+		}
+
+		if found == nil || as.EndAutnum-as.StartAutnum < found.EndAutnum-found.StartAutnum {
+			found = &m.asns[i]
+		}
+	}
+
+	if found == nil {
+		return nil, ErrNotFound
+	}
+
+	as := copyAS(*found)
+	return &as, nil
+}
+
+// DirectoryStore is an ObjectStore that loads the objects from the JSON files
+// (with the ".json" extension) of a directory and its subdirectories. The
+// object type is detected from the objectClassName member. Use Reload to
+// load the files again after changes
+type DirectoryStore struct {
+	Dir string
+
+	mu    sync.RWMutex
+	store *MemoryStore
+}
+
+// NewDirectoryStore returns a store with the objects of the directory
+func NewDirectoryStore(dir string) (*DirectoryStore, error) {
+	d := &DirectoryStore{Dir: dir}
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Reload loads the objects from the directory. On error the objects loaded
+// before are kept
+func (d *DirectoryStore) Reload() error {
+	store := NewMemoryStore()
+
+	err := filepath.Walk(d.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		object, err := protocol.DecodeObject(data)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		if err := store.Add(object); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.store = store
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *DirectoryStore) current() *MemoryStore {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.store == nil {
+		return NewMemoryStore()
+	}
+
+	return d.store
+}
+
+// Domain implements the ObjectStore interface
+func (d *DirectoryStore) Domain(fqdn string) (*protocol.Domain, error) {
+	return d.current().Domain(fqdn)
+}
+
+// Nameserver implements the ObjectStore interface
+func (d *DirectoryStore) Nameserver(fqdn string) (*protocol.Nameserver, error) {
+	return d.current().Nameserver(fqdn)
+}
+
+// Entity implements the ObjectStore interface
+func (d *DirectoryStore) Entity(handle string) (*protocol.Entity, error) {
+	return d.current().Entity(handle)
+}
+
+// IPNetwork implements the ObjectStore interface
+func (d *DirectoryStore) IPNetwork(network *net.IPNet) (*protocol.IPNetwork, error) {
+	return d.current().IPNetwork(network)
+}
+
+// AS implements the ObjectStore interface
+func (d *DirectoryStore) AS(asn uint32) (*protocol.AS, error) {
+	return d.current().AS(asn)
+}
+
+// storeName is the key of domains and nameservers
+func storeName(fqdn string) string {
+	return strings.TrimSuffix(strings.ToLower(fqdn), ".")
+}
+
+func ipBit(ip net.IP, i int) int {
+	return int(ip[i/8]>>uint(7-i%8)) & 1
+}
+
+// The copy functions duplicate the top-level slices of the objects, that are
+// the members changed by the server filters. Nested objects are shared
+
+func copyDomain(d protocol.Domain) protocol.Domain {
+	d.Entities = append([]protocol.Entity(nil), d.Entities...)
+	d.Links = append([]protocol.Link(nil), d.Links...)
+	d.Events = append([]protocol.Event(nil), d.Events...)
+	d.Remarks = append([]protocol.Remark(nil), d.Remarks...)
+	d.Notices = append([]protocol.Notice(nil), d.Notices...)
+	d.Redacted = append([]protocol.Redacted(nil), d.Redacted...)
+	d.Levels = append([]string(nil), d.Levels...)
+	return d
+}
+
+func copyNameserver(n protocol.Nameserver) protocol.Nameserver {
+	n.Entities = append([]protocol.Entity(nil), n.Entities...)
+	n.Links = append([]protocol.Link(nil), n.Links...)
+	n.Events = append([]protocol.Event(nil), n.Events...)
+	n.Remarks = append([]protocol.Remark(nil), n.Remarks...)
+	n.Notices = append([]protocol.Notice(nil), n.Notices...)
+	n.Redacted = append([]protocol.Redacted(nil), n.Redacted...)
+	n.Levels = append([]string(nil), n.Levels...)
+	return n
+}
+
+func copyEntity(e protocol.Entity) protocol.Entity {
+	e.Entities = append([]protocol.Entity(nil), e.Entities...)
+	e.Links = append([]protocol.Link(nil), e.Links...)
+	e.Events = append([]protocol.Event(nil), e.Events...)
+	e.Remarks = append([]protocol.Remark(nil), e.Remarks...)
+	e.Notices = append([]protocol.Notice(nil), e.Notices...)
+	e.Redacted = append([]protocol.Redacted(nil), e.Redacted...)
+	e.Levels = append([]string(nil), e.Levels...)
+	return e
+}
+
+func copyIPNetwork(n protocol.IPNetwork) protocol.IPNetwork {
+	n.Entities = append([]protocol.Entity(nil), n.Entities...)
+	n.Links = append([]protocol.Link(nil), n.Links...)
+	n.Events = append([]protocol.Event(nil), n.Events...)
+	n.Remarks = append([]protocol.Remark(nil), n.Remarks...)
+	n.Notices = append([]protocol.Notice(nil), n.Notices...)
+	n.Redacted = append([]protocol.Redacted(nil), n.Redacted...)
+	n.Levels = append([]string(nil), n.Levels...)
+	return n
+}
+
+func copyAS(a protocol.AS) protocol.AS {
+	a.Entities = append([]protocol.Entity(nil), a.Entities...)
+	a.Links = append([]protocol.Link(nil), a.Links...)
+	a.Events = append([]protocol.Event(nil), a.Events...)
+	a.Remarks = append([]protocol.Remark(nil), a.Remarks...)
+	a.Notices = append([]protocol.Notice(nil), a.Notices...)
+	a.Redacted = append([]protocol.Redacted(nil), a.Redacted...)
+	a.Levels = append([]string(nil), a.Levels...)
+	return a
+}
@@ -0,0 +1,237 @@
+package rdap
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	objects := []interface{}{
+		&protocol.Domain{ObjectClassName: "domain", LDHName: "Example.BR."},
+		protocol.Nameserver{ObjectClassName: "nameserver", LDHName: "a.dns.br"},
+		&protocol.Entity{ObjectClassName: "entity", Handle: "XXXX"},
+		&protocol.IPNetwork{ObjectClassName: "ip network", Handle: "LACNIC", StartAddress: "200.0.0.0", EndAddress: "200.255.255.255"},
+		&protocol.IPNetwork{ObjectClassName: "ip network", Handle: "NICBR", StartAddress: "200.160.0.0", EndAddress: "200.160.15.255"},
+		&protocol.IPNetwork{ObjectClassName: "ip network", Handle: "RANGE", StartAddress: "200.160.2.3", EndAddress: "200.160.2.200"},
+		&protocol.IPNetwork{ObjectClassName: "ip network", Handle: "V6", StartAddress: "2001:db8::", EndAddress: "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"},
+		&protocol.AS{ObjectClassName: "autnum", Handle: "BLOCK", StartAutnum: 64512, EndAutnum: 65534},
+		&protocol.AS{ObjectClassName: "autnum", Handle: "AS65000", StartAutnum: 65000},
+	}
+
+	for _, object := range objects {
+		if err := store.Add(object); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	invalid := []interface{}{
+		&protocol.Help{},
+		&protocol.IPNetwork{StartAddress: "200.0.0.10", EndAddress: "200.0.0.1"},
+		&protocol.AS{StartAutnum: 10, EndAutnum: 1},
+	}
+
+	for i, object := range invalid {
+		if err := store.Add(object); err == nil {
+			t.Errorf("[%d] Expected an error when adding “%#v”", i, object)
+		}
+	}
+
+	handle := func(object interface{}) string {
+		switch o := object.(type) {
+		case *protocol.Domain:
+			return o.LDHName
+		case *protocol.Nameserver:
+			return o.LDHName
+		case *protocol.Entity:
+			return o.Handle
+		case *protocol.IPNetwork:
+			return o.Handle
+		case *protocol.AS:
+			return o.Handle
+		}
+		return ""
+	}
+
+	cidr := func(value string) *net.IPNet {
+		return parseIPQuery(value)
+	}
+
+	data := []struct {
+		description   string
+		lookup        func() (interface{}, error)
+		expected      string
+		expectedError error
+	}{
+		{
+			description: "it should find a domain ignoring the case",
+			lookup:      func() (interface{}, error) { return store.Domain("example.br") },
+			expected:    "Example.BR.",
+		},
+		{
+			description: "it should find a nameserver",
+			lookup:      func() (interface{}, error) { return store.Nameserver("a.dns.br.") },
+			expected:    "a.dns.br",
+		},
+		{
+			description: "it should find an entity",
+			lookup:      func() (interface{}, error) { return store.Entity("XXXX") },
+			expected:    "XXXX",
+		},
+		{
+			description: "it should find the most specific network of an address",
+			lookup:      func() (interface{}, error) { return store.IPNetwork(cidr("200.160.2.100")) },
+			expected:    "RANGE",
+		},
+		{
+			description: "it should ignore a range with the same prefix that doesn't contain the address",
+			lookup:      func() (interface{}, error) { return store.IPNetwork(cidr("200.160.2.1")) },
+			expected:    "NICBR",
+		},
+		{
+			description: "it should find the network that contains a CIDR",
+			lookup:      func() (interface{}, error) { return store.IPNetwork(cidr("200.160.0.0/16")) },
+			expected:    "LACNIC",
+		},
+		{
+			description: "it should find an IPv6 network",
+			lookup:      func() (interface{}, error) { return store.IPNetwork(cidr("2001:db8::1")) },
+			expected:    "V6",
+		},
+		{
+			description: "it should find the smallest AS range",
+			lookup:      func() (interface{}, error) { return store.AS(65000) },
+			expected:    "AS65000",
+		},
+		{
+			description: "it should find an AS in a range",
+			lookup:      func() (interface{}, error) { return store.AS(65001) },
+			expected:    "BLOCK",
+		},
+		{
+			description:   "it should detect a domain that doesn't exist",
+			lookup:        func() (interface{}, error) { return store.Domain("example.com") },
+			expectedError: ErrNotFound,
+		},
+		{
+			description:   "it should detect a network that doesn't exist",
+			lookup:        func() (interface{}, error) { return store.IPNetwork(cidr("192.0.2.1")) },
+			expectedError: ErrNotFound,
+		},
+		{
+			description:   "it should detect an AS that doesn't exist",
+			lookup:        func() (interface{}, error) { return store.AS(1) },
+			expectedError: ErrNotFound,
+		},
+	}
+
+	for i, item := range data {
+		object, err := item.lookup()
+		if err != item.expectedError {
+			t.Errorf("[%d] %s: expected error “%v”, got “%v”", i, item.description, item.expectedError, err)
+			continue
+		}
+
+		if err == nil && handle(object) != item.expected {
+			t.Errorf("[%d] %s: expected object “%s” and got “%s”", i, item.description, item.expected, handle(object))
+		}
+	}
+
+	domain, _ := store.Domain("example.br")
+	domain.Notices = append(domain.Notices, protocol.Notice{Title: "Terms of Use"})
+
+	if domain, _ = store.Domain("example.br"); len(domain.Notices) > 0 {
+		t.Error("The stored object was changed by the caller")
+	}
+}
+
+func TestDirectoryStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rdap-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"domain/example.br.json": `{"objectClassName":"domain","ldhName":"example.br"}`,
+		"autnum/65000.json":      `{"objectClassName":"autnum","handle":"AS65000","startAutnum":65000,"endAutnum":65000}`,
+		"README":                 `not an object`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := NewDirectoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer()
+	server.HandleStore(store)
+
+	data := []struct {
+		description    string
+		path           string
+		expectedStatus int
+	}{
+		{
+			description:    "it should answer a domain of the directory",
+			path:           "/domain/example.br",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "it should answer an AS of the directory",
+			path:           "/autnum/65000",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "it should detect an object that isn't in the directory",
+			path:           "/entity/XXXX",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for i, item := range data {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest("GET", item.path, nil))
+
+		if w.Code != item.expectedStatus {
+			t.Errorf("[%d] %s: expected HTTP status “%d” and got “%d”", i, item.description, item.expectedStatus, w.Code)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{"objectClassName":"unknown"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Reload(); err == nil {
+		t.Error("Expected an error when loading an invalid object")
+	}
+
+	domain, err := store.Domain("example.br")
+	if err != nil {
+		t.Fatalf("Unexpected error after a failed reload. Got “%s”", err)
+	}
+
+	expected := &protocol.Domain{ObjectClassName: "domain", LDHName: "example.br"}
+	if !reflect.DeepEqual(expected, domain) {
+		t.Errorf("Unexpected domain.\n%v", diff(expected, domain))
+	}
+}
@@ -15,7 +15,7 @@ import (
 )
 
 // List of resource type path segments for exact match lookup as described in
-// RFC 7482, section 3.1
+// RFC 9082, section 3.1
 const (
 	// QueryTypeDomain used to identify reverse DNS (RIR) or domain name (DNR)
 	// information and associated data referenced using a fully qualified domain
@@ -38,6 +38,30 @@ const (
 	// QueryTypeEntity used to identify an entity information query using a
 	// string identifier
 	QueryTypeEntity QueryType = "entity"
+
+	// QueryTypeNameserver used to identify a nameserver information query
+	// using a host name
+	QueryTypeNameserver QueryType = "nameserver"
+
+	// QueryTypeHelp used to retrieve the server policy, supported features
+	// and other information about the RDAP server
+	QueryTypeHelp QueryType = "help"
+)
+
+// List of resource type path segments for searches as described in RFC 9082,
+// section 3.2. The search pattern is sent in the query string
+const (
+	// QueryTypeDomains used to search domains by name ("name"), nameserver
+	// name ("nsLdhName") or nameserver IP address ("nsIp")
+	QueryTypeDomains QueryType = "domains"
+
+	// QueryTypeNameservers used to search nameservers by name ("name") or IP
+	// address ("ip")
+	QueryTypeNameservers QueryType = "nameservers"
+
+	// QueryTypeEntities used to search entities by full name ("fn") or handle
+	// ("handle")
+	QueryTypeEntities QueryType = "entities"
 )
 
 // QueryType stores the query type when sending a query to an RDAP server
@@ -98,6 +122,10 @@ var (
 	// information of the requested object
 	ErrNotFound  = errors.New("not found")
 	ErrForbidden = errors.New("forbidden")
+
+	// ErrNotModified is used when the RDAP server answers a conditional
+	// request with 304 Not Modified. The response is also returned
+	ErrNotModified = errors.New("not modified")
 )
 
 // Fetcher represents the network layer responsible for retrieving the
@@ -156,7 +184,11 @@ func (d *defaultFetcher) Fetch(uris []string, queryType QueryType, queryValue st
 
 	for _, uri := range uris {
 		resp, err = d.fetchURI(uri, queryType, queryValue, header, queryString)
-		if err != nil {
+		if err == ErrNotModified {
+			// the server confirmed the cached object, there's no reason to
+			// query the other servers
+			return
+		} else if err != nil {
 			continue
 		}
 		return
@@ -204,6 +236,8 @@ func (d *defaultFetcher) fetchURI(uri string, queryType QueryType, queryValue st
 		return resp, ErrNotFound
 	} else if resp.StatusCode == http.StatusForbidden {
 		return resp, ErrForbidden
+	} else if resp.StatusCode == http.StatusNotModified {
+		return resp, ErrNotModified
 	}
 
 	contentType := resp.Header.Get("Content-Type")
@@ -290,7 +324,7 @@ func bootstrap(bootstrapURI string, httpClient httpClient, cacheDetector CacheDe
 			}
 
 			if len(uris) == 0 {
-				return nil, fmt.Errorf("no matches for %v", queryValue)
+				return nil, bootstrapNoMatchError(queryValue)
 			}
 
 			sort.Sort(prioritizeHTTPS(uris))
@@ -299,6 +333,14 @@ func bootstrap(bootstrapURI string, httpClient httpClient, cacheDetector CacheDe
 	}
 }
 
+// bootstrapNoMatchError is returned when the bootstrap registry doesn't have
+// a RDAP server for the query value
+type bootstrapNoMatchError string
+
+func (e bootstrapNoMatchError) Error() string {
+	return fmt.Sprintf("no matches for %v", string(e))
+}
+
 func bootstrapFetch(httpClient httpClient, uri string, reloadCache bool, cacheDetector CacheDetector) (*serviceRegistry, bool, error) {
 	req, err := http.NewRequest("GET", uri, nil)
 	if err != nil {
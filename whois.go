@@ -0,0 +1,280 @@
+package rdap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// IANAWHOIS is the IANA WHOIS server, that refers the queries to the
+	// authoritative WHOIS server of the resource
+	IANAWHOIS = "whois.iana.org"
+
+	// DefaultWHOISTimeout is the maximum time spent in each WHOIS query,
+	// including the connection and the response
+	DefaultWHOISTimeout = 10 * time.Second
+
+	// DefaultWHOISReferrals is the maximum number of WHOIS referrals followed
+	// in a query
+	DefaultWHOISReferrals = 3
+
+	// DefaultWHOISMaxResponseSize is the maximum number of bytes read from a
+	// WHOIS server in each query
+	DefaultWHOISMaxResponseSize = 1 << 20
+)
+
+// whoisReferralKeys are the fields used by WHOIS servers to refer the query
+// to another server. IANA uses "refer" and "whois", thin registries use
+// "registrar whois server" and ARIN uses "referralserver"
+var whoisReferralKeys = []string{
+	"refer",
+	"whois",
+	"registrar whois server",
+	"referralserver",
+}
+
+// WHOISClient queries WHOIS servers (port 43) as described in RFC 3912. It is
+// used as a fallback for resources that aren't available in RDAP yet
+type WHOISClient struct {
+	// Server is the first WHOIS server queried. The port 43 is used when the
+	// server address has no port
+	Server string
+
+	// Timeout is the maximum time spent in each WHOIS query
+	Timeout time.Duration
+
+	// MaxReferrals is the maximum number of referrals followed. Use zero to
+	// query only the first server
+	MaxReferrals int
+}
+
+// NewWHOISClient returns a WHOIS client that starts the queries in the IANA
+// server and follows the referrals
+func NewWHOISClient() *WHOISClient {
+	return &WHOISClient{
+		Server:       IANAWHOIS,
+		Timeout:      DefaultWHOISTimeout,
+		MaxReferrals: DefaultWHOISReferrals,
+	}
+}
+
+// WHOISResponse stores the response of a WHOIS server
+type WHOISResponse struct {
+	// Server is the WHOIS server that sent the response
+	Server string
+
+	// Text is the raw response
+	Text string
+
+	// Fields is the best-effort parse of the "key: value" lines of the
+	// response
+	Fields WHOISFields
+
+	// Referrer is the response that referred the query to this server, or
+	// nil for the first server
+	Referrer *WHOISResponse
+}
+
+// WHOISFields stores the values of the fields of a WHOIS response. The keys
+// are stored in lowercase, and a key can have many values when it repeats
+// in the response
+type WHOISFields map[string][]string
+
+// Get returns the first value of the field, or an empty string if the field
+// doesn't exist. The key is case insensitive
+func (f WHOISFields) Get(key string) string {
+	if values := f[strings.ToLower(key)]; len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// ParseWHOISFields parses the "key: value" lines of a WHOIS response.
+// Comments (lines starting with "%" or "#"), notes (lines starting with
+// ">>>") and lines without a key are ignored
+func ParseWHOISFields(text string) WHOISFields {
+	fields := make(WHOISFields)
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ">>>") {
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			continue
+		}
+
+		// the colon must be followed by a space or the end of the line, to
+		// avoid detecting URLs as fields
+		if i+1 < len(line) && line[i+1] != ' ' && line[i+1] != '\t' {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		if value == "" {
+			continue
+		}
+
+		fields[key] = append(fields[key], value)
+	}
+
+	return fields
+}
+
+// Query sends the query to the WHOIS server, following the referrals to
+// other servers. The response of the last server is returned, and the
+// previous responses can be found with the Referrer field. If a referred
+// server fails, the error is returned with the last successful response
+func (w *WHOISClient) Query(query string) (*WHOISResponse, error) {
+	server := w.Server
+	if server == "" {
+		server = IANAWHOIS
+	}
+
+	response, err := w.query(server, query)
+	if err != nil {
+		return nil, err
+	}
+
+	visited := map[string]bool{whoisAddress(server): true}
+
+	for i := 0; i < w.MaxReferrals; i++ {
+		referral := whoisReferral(response.Fields)
+		if referral == "" || visited[whoisAddress(referral)] {
+			break
+		}
+		visited[whoisAddress(referral)] = true
+
+		referred, err := w.query(referral, query)
+		if err != nil {
+			return response, err
+		}
+
+		referred.Referrer = response
+		response = referred
+	}
+
+	return response, nil
+}
+
+func (w *WHOISClient) query(server, query string) (*WHOISResponse, error) {
+	timeout := w.Timeout
+	if timeout == 0 {
+		timeout = DefaultWHOISTimeout
+	}
+
+	conn, err := net.DialTimeout("tcp", whoisAddress(server), timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	if _, err := fmt.Fprintf(conn, "%s\r\n", query); err != nil {
+		return nil, err
+	}
+
+	// the server closes the connection after sending the response
+	data, err := ioutil.ReadAll(io.LimitReader(conn, DefaultWHOISMaxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > DefaultWHOISMaxResponseSize {
+		return nil, fmt.Errorf("WHOIS response of %s exceeds %d bytes", server, DefaultWHOISMaxResponseSize)
+	}
+
+	text := string(data)
+	return &WHOISResponse{
+		Server: server,
+		Text:   text,
+		Fields: ParseWHOISFields(text),
+	}, nil
+}
+
+// whoisReferral returns the WHOIS server referred in the response, or an
+// empty string if there's no referral to another WHOIS server
+func whoisReferral(fields WHOISFields) string {
+	for _, key := range whoisReferralKeys {
+		referral := fields.Get(key)
+		if referral == "" {
+			continue
+		}
+
+		if strings.Contains(referral, "://") {
+			if !strings.HasPrefix(referral, "whois://") {
+				// only WHOIS referrals are followed (e.g. ignore rwhois://)
+				continue
+			}
+			referral = strings.TrimPrefix(referral, "whois://")
+		}
+
+		return strings.TrimSuffix(referral, "/")
+	}
+
+	return ""
+}
+
+// whoisAddress adds the default WHOIS port to the server when the server has
+// no port
+func whoisAddress(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+
+	return net.JoinHostPort(server, "43")
+}
+
+// QueryWithFallback works like Query, but when the RDAP query fails the
+// object is queried using WHOIS. Objects that were not found in RDAP
+// (ErrNotFound) are not queried again, as the RDAP answer is authoritative.
+// When the WHOIS fallback is used the WHOIS response is also returned, and
+// the object is the best-effort conversion of the response (see ParseWHOIS).
+// If a referred WHOIS server fails or the response couldn't be converted, the
+// error is returned with the last WHOIS response received
+func (c *Client) QueryWithFallback(object string, header http.Header, queryString url.Values) (interface{}, http.Header, *WHOISResponse, error) {
+	result, responseHeader, err := c.Query(object, header, queryString)
+	if err == nil {
+		return result, responseHeader, nil, nil
+	} else if err == ErrNotFound {
+		return nil, responseHeader, nil, err
+	}
+
+	whoisClient := c.WHOIS
+	if whoisClient == nil {
+		whoisClient = NewWHOISClient()
+	}
+
+	// WHOIS servers identify AS numbers with the "AS" prefix
+	if _, asnErr := strconv.ParseUint(object, 10, 32); asnErr == nil {
+		object = "AS" + object
+	}
+
+	response, whoisErr := whoisClient.Query(object)
+	if whoisErr != nil {
+		return nil, responseHeader, response, fmt.Errorf("RDAP query failed (%s) and WHOIS fallback failed (%s)", err, whoisErr)
+	}
+
+	whoisObject, err := ParseWHOIS(response)
+	if err != nil {
+		return nil, responseHeader, response, err
+	}
+
+	return whoisObject, responseHeader, response, nil
+}
@@ -0,0 +1,581 @@
+package rdap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+var (
+	// ErrUnknownWHOISObject is used when the WHOIS response doesn't contain
+	// any of the objects described in the template
+	ErrUnknownWHOISObject = errors.New("unknown WHOIS object")
+)
+
+// WHOISTemplate describes the fields of the WHOIS responses of a server, so
+// they can be converted to RDAP objects. Each key is the lowercase name of the
+// field in the response. Empty keys are ignored, and the object class is
+// identified by the first key (domain, network, AS or entity handle) found in
+// the response
+type WHOISTemplate struct {
+	DomainKey     string
+	NameserverKey string
+
+	// NetworkKey is the field with the network range (e.g. "inetnum"), and
+	// NetworkIPv6Key is the field of the IPv6 networks (e.g. "inet6num").
+	// When NetworkIPv6Key is empty NetworkKey is used for both IP versions
+	NetworkKey     string
+	NetworkIPv6Key string
+	NetworkNameKey string
+	CountryKey     string
+
+	ASKey     string
+	ASNameKey string
+
+	// HandleKey identifies the contact blocks of the response, and also the
+	// handle of the object when the object has one
+	HandleKey string
+
+	// NameKeys are the fields with the contact name, the first one found in
+	// the contact block is used
+	NameKeys []string
+	EmailKey string
+	PhoneKey string
+
+	StatusKey string
+
+	// Statuses maps the WHOIS statuses to RDAP. Statuses that aren't in the
+	// map are converted as EPP status codes, and ignored when they aren't
+	// EPP status codes
+	Statuses map[string]protocol.Status
+
+	CreatedKey string
+	ChangedKey string
+	ExpiresKey string
+
+	// DateLayouts are the time layouts tried, in order, to parse the dates
+	DateLayouts []string
+
+	// Contacts maps the fields that reference the contacts of the object to
+	// their roles (e.g. "admin-c" to administrative). The field value is the
+	// contact handle, that is used to find the contact block in the response
+	Contacts map[string]protocol.Role
+
+	// ContactNames maps the fields with the name of the contacts of the
+	// object to their roles (e.g. "admin name" to administrative). The name
+	// is added to the contact with the same role, or to a new contact
+	// without handle
+	ContactNames map[string]protocol.Role
+}
+
+// WHOISRIPETemplate describes the RPSL format (RFC 2622) used by the RIRs
+// that share the RIPE database software (RIPE NCC, AFRINIC and APNIC)
+var WHOISRIPETemplate = WHOISTemplate{
+	DomainKey:      "domain",
+	NameserverKey:  "nserver",
+	NetworkKey:     "inetnum",
+	NetworkIPv6Key: "inet6num",
+	NetworkNameKey: "netname",
+	CountryKey:     "country",
+	ASKey:          "aut-num",
+	ASNameKey:      "as-name",
+	HandleKey:      "nic-hdl",
+	NameKeys:       []string{"person", "role", "org-name"},
+	EmailKey:       "e-mail",
+	PhoneKey:       "phone",
+	CreatedKey:     "created",
+	ChangedKey:     "last-modified",
+	DateLayouts:    []string{time.RFC3339},
+	Contacts: map[string]protocol.Role{
+		"org":     protocol.RoleRegistrant,
+		"admin-c": protocol.RoleAdministrative,
+		"tech-c":  protocol.RoleTechnical,
+		"zone-c":  protocol.RoleTechnical,
+		"abuse-c": protocol.RoleAbuse,
+	},
+}
+
+// WHOISRegistroBRTemplate describes the format of whois.registro.br, that is
+// also used by LACNIC
+var WHOISRegistroBRTemplate = WHOISTemplate{
+	DomainKey:      "domain",
+	NameserverKey:  "nserver",
+	NetworkKey:     "inetnum",
+	NetworkIPv6Key: "inet6num",
+	NetworkNameKey: "owner",
+	CountryKey:     "country",
+	ASKey:          "aut-num",
+	ASNameKey:      "owner",
+	HandleKey:      "nic-hdl-br",
+	NameKeys:       []string{"person"},
+	EmailKey:       "e-mail",
+	StatusKey:      "status",
+	Statuses: map[string]protocol.Status{
+		"published":         protocol.StatusActive,
+		"on-hold":           protocol.StatusInactive,
+		"waiting":           protocol.StatusWaitingActivation,
+		"frozen":            protocol.StatusLocked,
+		"released":          protocol.StatusPendingDelete,
+		"not-published":     protocol.StatusInactive,
+		"publication-hold":  protocol.StatusInactive,
+		"ticket-published":  protocol.StatusPendingCreate,
+		"ticket-waiting":    protocol.StatusPendingCreate,
+		"ticket-processing": protocol.StatusPendingCreate,
+	},
+	CreatedKey:  "created",
+	ChangedKey:  "changed",
+	ExpiresKey:  "expires",
+	DateLayouts: []string{"20060102"},
+	Contacts: map[string]protocol.Role{
+		"owner-c":   protocol.RoleRegistrant,
+		"admin-c":   protocol.RoleAdministrative,
+		"tech-c":    protocol.RoleTechnical,
+		"billing-c": protocol.RoleBilling,
+		"abuse-c":   protocol.RoleAbuse,
+	},
+}
+
+// WHOISLACNICTemplate describes the format of whois.lacnic.net, that uses
+// "nic-hdl" instead of the "nic-hdl-br" of whois.registro.br
+var WHOISLACNICTemplate = func() WHOISTemplate {
+	template := WHOISRegistroBRTemplate
+	template.HandleKey = "nic-hdl"
+	return template
+}()
+
+// WHOISARINTemplate describes the format of whois.arin.net
+var WHOISARINTemplate = WHOISTemplate{
+	NetworkKey:     "netrange",
+	NetworkNameKey: "netname",
+	CountryKey:     "country",
+	ASKey:          "asnumber",
+	ASNameKey:      "asname",
+	HandleKey:      "orgid",
+	NameKeys:       []string{"orgname"},
+	CreatedKey:     "regdate",
+	ChangedKey:     "updated",
+	DateLayouts:    []string{"2006-01-02"},
+	Contacts: map[string]protocol.Role{
+		"organization": protocol.RoleRegistrant,
+	},
+}
+
+// WHOISICANNTemplate describes the format of the gTLD registries and
+// registrars defined in the ICANN registry agreement
+var WHOISICANNTemplate = WHOISTemplate{
+	DomainKey:     "domain name",
+	NameserverKey: "name server",
+	HandleKey:     "registry domain id",
+	StatusKey:     "domain status",
+	CreatedKey:    "creation date",
+	ChangedKey:    "updated date",
+	ExpiresKey:    "registry expiry date",
+	DateLayouts:   []string{time.RFC3339},
+	Contacts: map[string]protocol.Role{
+		"registry registrant id": protocol.RoleRegistrant,
+		"registry admin id":      protocol.RoleAdministrative,
+		"registry tech id":       protocol.RoleTechnical,
+	},
+	ContactNames: map[string]protocol.Role{
+		"registrar":       protocol.RoleRegistrar,
+		"registrant name": protocol.RoleRegistrant,
+		"admin name":      protocol.RoleAdministrative,
+		"tech name":       protocol.RoleTechnical,
+	},
+}
+
+// WHOISTemplates maps the WHOIS servers to their templates. Servers that
+// aren't in the map use the WHOISICANNTemplate
+var WHOISTemplates = map[string]WHOISTemplate{
+	"whois.registro.br": WHOISRegistroBRTemplate,
+	"whois.lacnic.net":  WHOISLACNICTemplate,
+	"whois.ripe.net":    WHOISRIPETemplate,
+	"whois.afrinic.net": WHOISRIPETemplate,
+	"whois.apnic.net":   WHOISRIPETemplate,
+	"whois.arin.net":    WHOISARINTemplate,
+}
+
+// ParseWHOIS converts the WHOIS response to a RDAP object using the template
+// of the server that sent the response. The object can be a
+// *protocol.Domain, *protocol.IPNetwork, *protocol.AS or *protocol.Entity
+func ParseWHOIS(response *WHOISResponse) (interface{}, error) {
+	server := response.Server
+	if host, _, err := net.SplitHostPort(server); err == nil {
+		server = host
+	}
+
+	template, found := WHOISTemplates[strings.ToLower(server)]
+	if !found {
+		template = WHOISICANNTemplate
+	}
+
+	object, err := template.Parse(response.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	// the server that answered is the port 43 of the object
+	switch o := object.(type) {
+	case *protocol.Domain:
+		o.SetPort43(server)
+	case *protocol.IPNetwork:
+		o.SetPort43(server)
+	case *protocol.AS:
+		o.SetPort43(server)
+	case *protocol.Entity:
+		o.SetPort43(server)
+	}
+
+	return object, nil
+}
+
+// Parse converts the WHOIS text to a RDAP object. The response is split in
+// blocks separated by empty lines, the first block with an object key is
+// converted and the other blocks are used as the contacts of the object
+func (t WHOISTemplate) Parse(text string) (interface{}, error) {
+	blocks := whoisBlocks(text)
+
+	for i, block := range blocks {
+		contacts := append(append([]WHOISFields{}, blocks[:i]...), blocks[i+1:]...)
+
+		switch {
+		case t.DomainKey != "" && block.Get(t.DomainKey) != "":
+			return t.domain(block, contacts)
+		case t.networkKey(block) != "":
+			return t.ipNetwork(block, contacts)
+		case t.ASKey != "" && block.Get(t.ASKey) != "":
+			return t.as(block, contacts)
+		}
+	}
+
+	for _, block := range blocks {
+		if t.HandleKey != "" && block.Get(t.HandleKey) != "" {
+			entity := t.entity(block)
+			return &entity, nil
+		}
+	}
+
+	return nil, ErrUnknownWHOISObject
+}
+
+func (t WHOISTemplate) domain(block WHOISFields, contacts []WHOISFields) (*protocol.Domain, error) {
+	domain := &protocol.Domain{
+		ObjectClassName: protocol.ObjectClassDomain,
+		LDHName:         strings.ToLower(block.Get(t.DomainKey)),
+		Handle:          block.Get(t.HandleKey),
+		Status:          t.status(block),
+		Events:          t.events(block),
+		Entities:        t.entities(block, contacts),
+	}
+
+	for _, nameserver := range block[t.NameserverKey] {
+		// some servers add the IP addresses after the nameserver name
+		domain.Nameservers = append(domain.Nameservers, protocol.Nameserver{
+			ObjectClassName: protocol.ObjectClassNameserver,
+			LDHName:         strings.ToLower(strings.Fields(nameserver)[0]),
+		})
+	}
+
+	return domain, nil
+}
+
+// networkKey returns the network key of the template found in the block, or
+// an empty string if the block isn't a network
+func (t WHOISTemplate) networkKey(block WHOISFields) string {
+	for _, key := range []string{t.NetworkKey, t.NetworkIPv6Key} {
+		if key != "" && block.Get(key) != "" {
+			return key
+		}
+	}
+
+	return ""
+}
+
+func (t WHOISTemplate) ipNetwork(block WHOISFields, contacts []WHOISFields) (*protocol.IPNetwork, error) {
+	network := block.Get(t.networkKey(block))
+
+	start, end, err := whoisNetworkRange(network)
+	if err != nil {
+		return nil, err
+	}
+
+	ipNetwork := &protocol.IPNetwork{
+		ObjectClassName: protocol.ObjectClassIPNetwork,
+		Handle:          network,
+		StartAddress:    start.String(),
+		EndAddress:      end.String(),
+		IPVersion:       "v6",
+		Name:            block.Get(t.NetworkNameKey),
+		Country:         block.Get(t.CountryKey),
+		Status:          t.status(block),
+		Events:          t.events(block),
+		Entities:        t.entities(block, contacts),
+	}
+
+	if start.To4() != nil {
+		ipNetwork.IPVersion = "v4"
+	}
+
+	return ipNetwork, nil
+}
+
+func (t WHOISTemplate) as(block WHOISFields, contacts []WHOISFields) (*protocol.AS, error) {
+	handle := block.Get(t.ASKey)
+
+	number := handle
+	if len(number) > 2 && strings.EqualFold(number[:2], "AS") {
+		number = number[2:]
+	}
+
+	asn, err := strconv.ParseUint(number, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WHOIS AS number %q", handle)
+	}
+
+	return &protocol.AS{
+		ObjectClassName: protocol.ObjectClassAutnum,
+		Handle:          handle,
+		StartAutnum:     uint32(asn),
+		EndAutnum:       uint32(asn),
+		Name:            block.Get(t.ASNameKey),
+		Country:         block.Get(t.CountryKey),
+		Status:          t.status(block),
+		Events:          t.events(block),
+		Entities:        t.entities(block, contacts),
+	}, nil
+}
+
+func (t WHOISTemplate) entity(block WHOISFields) protocol.Entity {
+	entity := protocol.Entity{
+		ObjectClassName: protocol.ObjectClassEntity,
+		Handle:          block.Get(t.HandleKey),
+		Events:          t.events(block),
+	}
+
+	vcard := protocol.NewVCard()
+	for _, key := range t.NameKeys {
+		if name := block.Get(key); name != "" {
+			vcard.AddFN(name)
+			break
+		}
+	}
+
+	for _, email := range block[t.EmailKey] {
+		vcard.AddEmail(protocol.VCardEmail{Address: email})
+	}
+
+	for _, phone := range block[t.PhoneKey] {
+		vcard.AddTel(protocol.VCardTel{Number: phone, Types: []string{"voice"}})
+	}
+
+	if len(vcard.Properties) > 1 {
+		entity.SetVCard(vcard)
+	}
+
+	return entity
+}
+
+// entities builds the contacts of the object. When the contact block is
+// found in the response the complete entity is returned, otherwise the field
+// value is used as the handle of the entity. The contact names are added
+// after, to the entities with the same role
+func (t WHOISTemplate) entities(block WHOISFields, contacts []WHOISFields) []protocol.Entity {
+	var entities []protocol.Entity
+
+	for _, key := range sortedWHOISKeys(t.Contacts) {
+		role := t.Contacts[key]
+
+	Values:
+		for _, value := range block[key] {
+			for i := range entities {
+				if entities[i].Handle == value {
+					entities[i].Roles = append(entities[i].Roles, string(role))
+					continue Values
+				}
+			}
+
+			entity := protocol.Entity{
+				ObjectClassName: protocol.ObjectClassEntity,
+				Handle:          value,
+			}
+
+			for _, contact := range contacts {
+				if t.HandleKey != "" && contact.Get(t.HandleKey) == value {
+					entity = t.entity(contact)
+					break
+				}
+			}
+
+			entity.Roles = []string{string(role)}
+			entities = append(entities, entity)
+		}
+	}
+
+	for _, key := range sortedWHOISKeys(t.ContactNames) {
+		role := t.ContactNames[key]
+
+		name := block.Get(key)
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for i := range entities {
+			if !entities[i].HasRole(role) {
+				continue
+			}
+
+			found = true
+			if len(entities[i].VCardArray) == 0 {
+				entities[i].SetVCard(protocol.NewVCard().AddFN(name))
+			}
+		}
+
+		if !found {
+			entity := protocol.Entity{
+				ObjectClassName: protocol.ObjectClassEntity,
+				Roles:           []string{string(role)},
+			}
+			entity.SetVCard(protocol.NewVCard().AddFN(name))
+			entities = append(entities, entity)
+		}
+	}
+
+	return entities
+}
+
+func (t WHOISTemplate) status(block WHOISFields) protocol.StatusSet {
+	var statuses protocol.StatusSet
+
+	for _, value := range block[t.StatusKey] {
+		// ICANN statuses are followed by the URL with the status description
+		value = strings.Fields(value)[0]
+
+		if status, found := t.Statuses[strings.ToLower(value)]; found {
+			statuses.Add(status)
+		} else if status, found := protocol.StatusFromEPP(value); found {
+			statuses.Add(status)
+		}
+	}
+
+	return statuses
+}
+
+func (t WHOISTemplate) events(block WHOISFields) []protocol.Event {
+	var events []protocol.Event
+
+	actions := []struct {
+		key    string
+		action protocol.EventAction
+	}{
+		{t.CreatedKey, protocol.EventActionRegistration},
+		{t.ChangedKey, protocol.EventActionLastChanged},
+		{t.ExpiresKey, protocol.EventActionExpiration},
+	}
+
+	for _, item := range actions {
+		if item.key == "" {
+			continue
+		}
+
+		if date, ok := t.date(block.Get(item.key)); ok {
+			events = append(events, protocol.Event{Action: item.action, Date: date})
+		}
+	}
+
+	return events
+}
+
+func (t WHOISTemplate) date(value string) (protocol.EventDate, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return protocol.EventDate{}, false
+	}
+
+	// registro.br adds a sequence number after the date (e.g. "19990221 #4")
+	for _, layout := range t.DateLayouts {
+		if date, err := time.Parse(layout, fields[0]); err == nil {
+			return protocol.NewEventDate(date), true
+		}
+	}
+
+	return protocol.EventDate{}, false
+}
+
+// whoisBlocks splits the WHOIS response in blocks of fields separated by
+// empty lines
+func whoisBlocks(text string) []WHOISFields {
+	var blocks []WHOISFields
+	var block []string
+
+	flush := func() {
+		if fields := ParseWHOISFields(strings.Join(block, "\n")); len(fields) > 0 {
+			blocks = append(blocks, fields)
+		}
+		block = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			flush()
+			continue
+		}
+		block = append(block, scanner.Text())
+	}
+	flush()
+
+	return blocks
+}
+
+// whoisNetworkRange parses the network in CIDR notation or as a range of
+// addresses ("192.0.2.0 - 192.0.2.255"). Abbreviated IPv4 networks used by
+// LACNIC (e.g. "200.160/20") are also supported
+func whoisNetworkRange(value string) (start, end net.IP, err error) {
+	if parts := strings.Split(value, "-"); len(parts) == 2 {
+		start = net.ParseIP(strings.TrimSpace(parts[0]))
+		end = net.ParseIP(strings.TrimSpace(parts[1]))
+		if start == nil || end == nil {
+			return nil, nil, fmt.Errorf("invalid WHOIS network %q", value)
+		}
+		return start, end, nil
+	}
+
+	cidr := strings.TrimSpace(value)
+	if i := strings.Index(cidr, "/"); i > 0 && !strings.Contains(cidr, ":") {
+		if octets := strings.Count(cidr[:i], "."); octets < 3 {
+			cidr = cidr[:i] + strings.Repeat(".0", 3-octets) + cidr[i:]
+		}
+	}
+
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid WHOIS network %q", value)
+	}
+
+	start = network.IP
+	end = make(net.IP, len(start))
+	for i := range start {
+		end[i] = start[i] | ^network.Mask[i]
+	}
+
+	return start, end, nil
+}
+
+// sortedWHOISKeys returns the contact keys in a deterministic order, so the
+// entities are always built in the same order
+func sortedWHOISKeys(contacts map[string]protocol.Role) []string {
+	keys := make([]string, 0, len(contacts))
+	for key := range contacts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,309 @@
+package rdap
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+const whoisRegistroBRDomain = `% Copyright (c) Nic.br
+%  The use of the data below is only permitted as described in
+%  full by the terms of use at https://registro.br/termo/en.html ,
+%  being prohibited its distribution, commercialization or
+%  reproduction, in particular, to use it for advertising or
+%  any similar purpose.
+%  2017-07-20 12:09:48 (BRT -03:00)
+
+domain:      registro.br
+owner:       Núcleo de Inf. e Coord. do Ponto BR - NIC.BR
+owner-c:     FAN
+tech-c:      FAN
+nserver:     a.dns.br
+nsstat:      20170719 AA
+nserver:     b.dns.br
+nsstat:      20170719 AA
+created:     19990221 #4
+changed:     20170511
+expires:     20180221
+status:      published
+
+nic-hdl-br:  FAN
+person:      Frederico A C Neves
+e-mail:      fneves@registro.br
+created:     20010101
+changed:     20160822
+
+% Security and mail abuse issues should also be addressed to
+% cert.br, http://www.cert.br/ , respectivelly to cert@cert.br
+% and mail-abuse@cert.br
+`
+
+const whoisRIPENetwork = `% This is the RIPE Database query service.
+
+% Information related to '193.0.0.0 - 193.0.7.255'
+
+inetnum:        193.0.0.0 - 193.0.7.255
+netname:        RIPE-NCC
+country:        NL
+admin-c:        BRD-RIPE
+tech-c:         OPS4-RIPE
+status:         ASSIGNED PA
+created:        2003-03-17T12:15:57Z
+last-modified:  2017-12-04T14:42:31Z
+source:         RIPE
+
+role:           RIPE NCC Operations
+e-mail:         ops@ripe.net
+nic-hdl:        OPS4-RIPE
+source:         RIPE
+`
+
+const whoisRIPEIPv6Network = `inet6num:       2001:67c:2e8::/48
+netname:        RIPE-NCC
+country:        NL
+admin-c:        BRD-RIPE
+created:        2003-03-17T12:15:57Z
+source:         RIPE
+`
+
+const whoisLACNICAS = `% Joint Whois - whois.lacnic.net
+
+aut-num:     AS22548
+owner:       Núcleo de Inf. e Coord. do Ponto BR - NIC.BR
+ownerid:     BR-NICB-LACNIC
+country:     BR
+created:     20011112
+changed:     20170208
+`
+
+const whoisICANNDomain = `Domain Name: EXAMPLE.COM
+Registry Domain ID: 2336799_DOMAIN_COM-VRSN
+Registrar WHOIS Server: whois.iana.org
+Updated Date: 2023-08-14T07:01:31Z
+Creation Date: 1995-08-14T04:00:00Z
+Registry Expiry Date: 2024-08-13T04:00:00Z
+Registrar: RESERVED-Internet Assigned Numbers Authority
+Registry Registrant ID: C2336799-VRSN
+Registrant Name: Jane Doe
+Admin Name: John Doe
+Domain Status: clientDeleteProhibited https://icann.org/epp#clientDeleteProhibited
+Domain Status: clientTransferProhibited https://icann.org/epp#clientTransferProhibited
+Name Server: A.IANA-SERVERS.NET
+Name Server: B.IANA-SERVERS.NET
+DNSSEC: signedDelegation
+>>> Last update of whois database: 2024-01-01T00:00:00Z <<<
+`
+
+func TestParseWHOIS(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, min, sec int) protocol.EventDate {
+		return protocol.NewEventDate(time.Date(year, month, day, hour, min, sec, 0, time.UTC))
+	}
+
+	fan := protocol.NewVCard().
+		AddFN("Frederico A C Neves").
+		AddEmail(protocol.VCardEmail{Address: "fneves@registro.br"})
+
+	ops := protocol.NewVCard().
+		AddFN("RIPE NCC Operations").
+		AddEmail(protocol.VCardEmail{Address: "ops@ripe.net"})
+
+	data := []struct {
+		description   string
+		response      *WHOISResponse
+		expected      interface{}
+		expectedError error
+	}{
+		{
+			description: "it should parse a registro.br domain",
+			response:    &WHOISResponse{Server: "whois.registro.br", Text: whoisRegistroBRDomain},
+			expected: &protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "registro.br",
+				Status:          protocol.StatusSet{protocol.StatusActive},
+				Nameservers: []protocol.Nameserver{
+					{ObjectClassName: "nameserver", LDHName: "a.dns.br"},
+					{ObjectClassName: "nameserver", LDHName: "b.dns.br"},
+				},
+				Events: []protocol.Event{
+					{Action: protocol.EventActionRegistration, Date: date(1999, 2, 21, 0, 0, 0)},
+					{Action: protocol.EventActionLastChanged, Date: date(2017, 5, 11, 0, 0, 0)},
+					{Action: protocol.EventActionExpiration, Date: date(2018, 2, 21, 0, 0, 0)},
+				},
+				Entities: []protocol.Entity{
+					{
+						ObjectClassName: "entity",
+						Handle:          "FAN",
+						VCardArray:      fan.Array(),
+						Roles:           []string{protocol.RoleRegistrant, protocol.RoleTechnical},
+						Events: []protocol.Event{
+							{Action: protocol.EventActionRegistration, Date: date(2001, 1, 1, 0, 0, 0)},
+							{Action: protocol.EventActionLastChanged, Date: date(2016, 8, 22, 0, 0, 0)},
+						},
+					},
+				},
+				Port43: protocol.Port43{Port43: "whois.registro.br"},
+			},
+		},
+		{
+			description: "it should parse a RIPE IP network",
+			response:    &WHOISResponse{Server: "whois.ripe.net:43", Text: whoisRIPENetwork},
+			expected: &protocol.IPNetwork{
+				ObjectClassName: "ip network",
+				Handle:          "193.0.0.0 - 193.0.7.255",
+				StartAddress:    "193.0.0.0",
+				EndAddress:      "193.0.7.255",
+				IPVersion:       "v4",
+				Name:            "RIPE-NCC",
+				Country:         "NL",
+				Events: []protocol.Event{
+					{Action: protocol.EventActionRegistration, Date: date(2003, 3, 17, 12, 15, 57)},
+					{Action: protocol.EventActionLastChanged, Date: date(2017, 12, 4, 14, 42, 31)},
+				},
+				Entities: []protocol.Entity{
+					{ObjectClassName: "entity", Handle: "BRD-RIPE", Roles: []string{protocol.RoleAdministrative}},
+					{ObjectClassName: "entity", Handle: "OPS4-RIPE", VCardArray: ops.Array(), Roles: []string{protocol.RoleTechnical}},
+				},
+				Port43: protocol.Port43{Port43: "whois.ripe.net"},
+			},
+		},
+		{
+			description: "it should parse a RIPE IPv6 network",
+			response:    &WHOISResponse{Server: "whois.ripe.net", Text: whoisRIPEIPv6Network},
+			expected: &protocol.IPNetwork{
+				ObjectClassName: "ip network",
+				Handle:          "2001:67c:2e8::/48",
+				StartAddress:    "2001:67c:2e8::",
+				EndAddress:      "2001:67c:2e8:ffff:ffff:ffff:ffff:ffff",
+				IPVersion:       "v6",
+				Name:            "RIPE-NCC",
+				Country:         "NL",
+				Events: []protocol.Event{
+					{Action: protocol.EventActionRegistration, Date: date(2003, 3, 17, 12, 15, 57)},
+				},
+				Entities: []protocol.Entity{
+					{ObjectClassName: "entity", Handle: "BRD-RIPE", Roles: []string{protocol.RoleAdministrative}},
+				},
+				Port43: protocol.Port43{Port43: "whois.ripe.net"},
+			},
+		},
+		{
+			description: "it should parse a LACNIC AS",
+			response:    &WHOISResponse{Server: "whois.lacnic.net", Text: whoisLACNICAS},
+			expected: &protocol.AS{
+				ObjectClassName: "autnum",
+				Handle:          "AS22548",
+				StartAutnum:     22548,
+				EndAutnum:       22548,
+				Name:            "Núcleo de Inf. e Coord. do Ponto BR - NIC.BR",
+				Country:         "BR",
+				Events: []protocol.Event{
+					{Action: protocol.EventActionRegistration, Date: date(2001, 11, 12, 0, 0, 0)},
+					{Action: protocol.EventActionLastChanged, Date: date(2017, 2, 8, 0, 0, 0)},
+				},
+				Port43: protocol.Port43{Port43: "whois.lacnic.net"},
+			},
+		},
+		{
+			description: "it should parse an ICANN domain with the default template",
+			response:    &WHOISResponse{Server: "whois.verisign-grs.com", Text: whoisICANNDomain},
+			expected: &protocol.Domain{
+				ObjectClassName: "domain",
+				Handle:          "2336799_DOMAIN_COM-VRSN",
+				LDHName:         "example.com",
+				Status: protocol.StatusSet{
+					protocol.StatusClientDeleteProhibited,
+					protocol.StatusClientTransferProhibited,
+				},
+				Nameservers: []protocol.Nameserver{
+					{ObjectClassName: "nameserver", LDHName: "a.iana-servers.net"},
+					{ObjectClassName: "nameserver", LDHName: "b.iana-servers.net"},
+				},
+				Events: []protocol.Event{
+					{Action: protocol.EventActionRegistration, Date: date(1995, 8, 14, 4, 0, 0)},
+					{Action: protocol.EventActionLastChanged, Date: date(2023, 8, 14, 7, 1, 31)},
+					{Action: protocol.EventActionExpiration, Date: date(2024, 8, 13, 4, 0, 0)},
+				},
+				Entities: []protocol.Entity{
+					{
+						ObjectClassName: "entity",
+						Handle:          "C2336799-VRSN",
+						VCardArray:      protocol.NewVCard().AddFN("Jane Doe").Array(),
+						Roles:           []string{protocol.RoleRegistrant},
+					},
+					{
+						ObjectClassName: "entity",
+						VCardArray:      protocol.NewVCard().AddFN("John Doe").Array(),
+						Roles:           []string{protocol.RoleAdministrative},
+					},
+					{
+						ObjectClassName: "entity",
+						VCardArray:      protocol.NewVCard().AddFN("RESERVED-Internet Assigned Numbers Authority").Array(),
+						Roles:           []string{protocol.RoleRegistrar},
+					},
+				},
+				Port43: protocol.Port43{Port43: "whois.verisign-grs.com"},
+			},
+		},
+		{
+			description:   "it should detect an unknown object",
+			response:      &WHOISResponse{Server: "whois.registro.br", Text: "% No match for domain \"example.br\"\n"},
+			expectedError: ErrUnknownWHOISObject,
+		},
+	}
+
+	for i, item := range data {
+		object, err := ParseWHOIS(item.response)
+
+		if err != item.expectedError {
+			t.Errorf("[%d] %s: expected error “%v”, got “%v”", i, item.description, item.expectedError, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(item.expected, object) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expected, object))
+		}
+	}
+}
+
+func TestWHOISNetworkRange(t *testing.T) {
+	data := []struct {
+		description   string
+		network       string
+		expectedStart string
+		expectedEnd   string
+	}{
+		{
+			description:   "it should parse a range",
+			network:       "193.0.0.0 - 193.0.7.255",
+			expectedStart: "193.0.0.0",
+			expectedEnd:   "193.0.7.255",
+		},
+		{
+			description:   "it should parse an IPv6 CIDR",
+			network:       "2001:12ff::/32",
+			expectedStart: "2001:12ff::",
+			expectedEnd:   "2001:12ff:ffff:ffff:ffff:ffff:ffff:ffff",
+		},
+		{
+			description:   "it should parse an abbreviated IPv4 CIDR",
+			network:       "200.160/20",
+			expectedStart: "200.160.0.0",
+			expectedEnd:   "200.160.15.255",
+		},
+	}
+
+	for i, item := range data {
+		start, end, err := whoisNetworkRange(item.network)
+		if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%s”", i, item.description, err)
+			continue
+		}
+
+		if start.String() != item.expectedStart || end.String() != item.expectedEnd {
+			t.Errorf("[%d] %s: expected “%s - %s” and got “%s - %s”", i, item.description,
+				item.expectedStart, item.expectedEnd, start, end)
+		}
+	}
+}
@@ -0,0 +1,682 @@
+package rdap
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+	"golang.org/x/net/idna"
+)
+
+// DefaultWHOISMaxConnections is the maximum number of connections answered at
+// the same time by the WHOIS server
+const DefaultWHOISMaxConnections = 100
+
+// ErrWHOISServerClosed is returned by WHOISServer.Serve and
+// WHOISServer.ListenAndServe after a call to Shutdown or Close
+var ErrWHOISServerClosed = errors.New("whois: server closed")
+
+// WHOISServer answers the WHOIS queries (port 43) described in RFC 3912 with
+// the objects of a RDAP server, so the WHOIS and RDAP services share the same
+// backends and filters (e.g. AccessControl). The objects are rendered as
+// text with the template, and the responses can be parsed back with
+// WHOISTemplate.Parse
+type WHOISServer struct {
+	// Server is the RDAP server that answers the queries
+	Server *Server
+
+	// Template defines the fields of the responses
+	Template WHOISTemplate
+
+	// Header is sent in the beginning of each response, each line as a
+	// comment (e.g. the terms of use)
+	Header []string
+
+	// Timeout is the maximum time spent in each connection, including the
+	// query and the response
+	Timeout time.Duration
+
+	// MaxConnections is the maximum number of connections answered at the
+	// same time. New connections aren't accepted while the limit is reached.
+	// If zero there's no limit
+	MaxConnections int
+
+	// ErrorLog logs the backend errors. If nil the errors are not logged
+	ErrorLog *log.Logger
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	active    sync.WaitGroup
+	closed    bool
+}
+
+// NewWHOISServer returns a WHOIS server that answers with the objects of the
+// RDAP server in the whois.registro.br format
+func NewWHOISServer(server *Server) *WHOISServer {
+	return &WHOISServer{
+		Server:         server,
+		Template:       WHOISRegistroBRTemplate,
+		Timeout:        DefaultWHOISTimeout,
+		MaxConnections: DefaultWHOISMaxConnections,
+	}
+}
+
+// ListenAndServe listens on the TCP address (":43" when empty) and answers
+// the WHOIS queries
+func (s *WHOISServer) ListenAndServe(addr string) error {
+	if addr == "" {
+		addr = ":43"
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	return s.Serve(listener)
+}
+
+// Serve answers the WHOIS queries of the connections accepted by the
+// listener. It returns when the listener is closed, or ErrWHOISServerClosed
+// after a call to Shutdown or Close
+func (s *WHOISServer) Serve(listener net.Listener) error {
+	if !s.trackListener(listener, true) {
+		return ErrWHOISServerClosed
+	}
+	defer s.trackListener(listener, false)
+
+	var slots chan struct{}
+	if s.MaxConnections > 0 {
+		slots = make(chan struct{}, s.MaxConnections)
+	}
+
+	var delay time.Duration
+
+	for {
+		// waits for a free slot before accepting, so the pending connections
+		// stay in the listener backlog
+		if slots != nil {
+			slots <- struct{}{}
+		}
+
+		conn, err := listener.Accept()
+		if err != nil {
+			if slots != nil {
+				<-slots
+			}
+
+			if s.shuttingDown() {
+				return ErrWHOISServerClosed
+			}
+
+			// temporary errors (e.g. too many open files) are retried, as done
+			// by http.Server
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+
+				time.Sleep(delay)
+				continue
+			}
+
+			return err
+		}
+
+		delay = 0
+
+		if !s.trackConn(conn, true) {
+			conn.Close()
+			return ErrWHOISServerClosed
+		}
+
+		go func() {
+			defer func() {
+				if slots != nil {
+					<-slots
+				}
+			}()
+
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// Shutdown stops the server gracefully: the listeners are closed and the
+// active connections are answered. If the context expires first the
+// remaining connections are closed and the context error is returned
+func (s *WHOISServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for listener := range s.listeners {
+		listener.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Close stops the server immediately, closing the listeners and the active
+// connections
+func (s *WHOISServer) Close() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.Shutdown(ctx); err != nil && err != context.Canceled {
+		return err
+	}
+	return nil
+}
+
+func (s *WHOISServer) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *WHOISServer) trackListener(listener net.Listener, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !add {
+		delete(s.listeners, listener)
+		return true
+	}
+
+	if s.closed {
+		return false
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[listener] = struct{}{}
+	return true
+}
+
+func (s *WHOISServer) trackConn(conn net.Conn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !add {
+		delete(s.conns, conn)
+		s.active.Done()
+		return true
+	}
+
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+	}
+	s.conns[conn] = struct{}{}
+	s.active.Add(1)
+	return true
+}
+
+func (s *WHOISServer) serveConn(conn net.Conn) {
+	defer s.trackConn(conn, false)
+	defer conn.Close()
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultWHOISTimeout
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return
+	}
+
+	// the query is a single line, so long queries are truncated
+	query, err := bufio.NewReader(io.LimitReader(conn, 1024)).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return
+	}
+
+	response := s.Answer(conn.RemoteAddr().String(), strings.TrimSpace(query))
+
+	// RFC 3912, section 2: the lines end with CRLF
+	io.WriteString(conn, strings.Replace(response, "\n", "\r\n", -1))
+}
+
+// Answer returns the WHOIS response of the query. The client address is used
+// by the backends as the remote address of the request (e.g. RateLimiter).
+// The query type is detected from the query format: AS numbers (with or
+// without the "AS" prefix), IP addresses and networks, domain names and
+// entity handles
+func (s *WHOISServer) Answer(remoteAddr, query string) string {
+	var response []string
+	for _, line := range s.Header {
+		response = append(response, "% "+line)
+	}
+	if len(response) > 0 {
+		response = append(response, "")
+	}
+
+	answer := func(lines ...string) string {
+		return strings.Join(append(response, lines...), "\n") + "\n"
+	}
+
+	if query == "" {
+		return answer("% Empty query")
+	}
+
+	queryType, queryValue := whoisQueryType(query)
+
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		return answer("% Internal error")
+	}
+	r.URL.Path = "/" + string(queryType) + "/" + queryValue
+	r.RemoteAddr = remoteAddr
+
+	object, err := s.Server.query(r)
+	if err == nil {
+		if redirect, ok := object.(*Redirect); ok {
+			return answer(fmt.Sprintf("%% The object is available at %s", redirect.URL))
+		}
+
+		var text string
+		if text, err = s.Template.Render(object); err == nil {
+			return answer(text)
+		}
+	}
+
+	switch e := err.(type) {
+	case protocol.Error:
+		return answer("% Error: " + e.Title)
+	case *protocol.Error:
+		return answer("% Error: " + e.Title)
+	case RateLimitError:
+		return answer("% Error: " + e.Response.Title)
+	}
+
+	switch err {
+	case ErrNotFound:
+		return answer(fmt.Sprintf("%% No match for %q", query))
+	case ErrForbidden:
+		return answer("% Access denied")
+	}
+
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf("whois: error answering %q: %s", query, err)
+	}
+
+	return answer("% Internal error")
+}
+
+// whoisQueryType detects the query type of the WHOIS query, in the same way
+// as Client.Query
+func whoisQueryType(query string) (QueryType, string) {
+	asn := query
+	if len(asn) > 2 && strings.EqualFold(asn[:2], "AS") {
+		asn = asn[2:]
+	}
+
+	if _, err := strconv.ParseUint(asn, 10, 32); err == nil {
+		return QueryTypeAutnum, asn
+	}
+
+	if net.ParseIP(query) != nil {
+		return QueryTypeIP, query
+	}
+
+	if _, _, err := net.ParseCIDR(query); err == nil {
+		return QueryTypeIP, query
+	}
+
+	// entity handles don't have dots, but can look like a TLD (e.g. "FAN")
+	fqdn, err := idna.ToASCII(strings.ToLower(query))
+	if err == nil && strings.Contains(fqdn, ".") && fqdnRX.MatchString(fqdn) {
+		return QueryTypeDomain, query
+	}
+
+	return QueryTypeEntity, query
+}
+
+// whoisBlock stores the fields of a block of the WHOIS response, in order
+type whoisBlock [][2]string
+
+func (b *whoisBlock) add(key string, values ...string) {
+	if key == "" {
+		return
+	}
+
+	for _, value := range values {
+		if value != "" {
+			*b = append(*b, [2]string{key, value})
+		}
+	}
+}
+
+// Render converts the RDAP object to WHOIS text, using the fields of the
+// template. The object can be a protocol.Domain, protocol.IPNetwork,
+// protocol.AS or protocol.Entity, or a pointer to them. The contacts with
+// more information than the handle are rendered in their own blocks after the
+// object, as expected by Parse
+func (t WHOISTemplate) Render(object interface{}) (string, error) {
+	var blocks []whoisBlock
+
+	switch o := object.(type) {
+	case *protocol.Domain:
+		return t.Render(*o)
+	case protocol.Domain:
+		var block whoisBlock
+		block.add(t.DomainKey, o.LDHName)
+		if !strings.EqualFold(o.Handle, o.LDHName) {
+			block.add(t.HandleKey, o.Handle)
+		}
+		t.renderContacts(&block, o.Entities)
+		for _, nameserver := range o.Nameservers {
+			block.add(t.NameserverKey, nameserver.LDHName)
+		}
+		t.renderEvents(&block, o.Events)
+		t.renderStatus(&block, o.Status)
+		blocks = append([]whoisBlock{block}, t.contactBlocks(o.Entities)...)
+
+	case *protocol.IPNetwork:
+		return t.Render(*o)
+	case protocol.IPNetwork:
+		var block whoisBlock
+		block.add(t.renderNetworkKey(o), whoisNetwork(o.Handle, o.StartAddress, o.EndAddress))
+		block.add(t.NetworkNameKey, o.Name)
+		block.add(t.CountryKey, o.Country)
+		t.renderContacts(&block, o.Entities)
+		t.renderEvents(&block, o.Events)
+		t.renderStatus(&block, o.Status)
+		blocks = append([]whoisBlock{block}, t.contactBlocks(o.Entities)...)
+
+	case *protocol.AS:
+		return t.Render(*o)
+	case protocol.AS:
+		asn := fmt.Sprintf("AS%d", o.StartAutnum)
+		if o.EndAutnum > o.StartAutnum {
+			asn += fmt.Sprintf(" - AS%d", o.EndAutnum)
+		}
+
+		var block whoisBlock
+		block.add(t.ASKey, asn)
+		block.add(t.ASNameKey, o.Name)
+		block.add(t.CountryKey, o.Country)
+		t.renderContacts(&block, o.Entities)
+		t.renderEvents(&block, o.Events)
+		t.renderStatus(&block, o.Status)
+		blocks = append([]whoisBlock{block}, t.contactBlocks(o.Entities)...)
+
+	case *protocol.Entity:
+		return t.Render(*o)
+	case protocol.Entity:
+		blocks = []whoisBlock{t.entityBlock(o)}
+
+	default:
+		return "", ErrUnknownWHOISObject
+	}
+
+	// the values are aligned in all blocks, two spaces after the longest key,
+	// as the WHOIS servers usually do
+	width := 0
+	for _, block := range blocks {
+		for _, field := range block {
+			if len(field[0])+3 > width {
+				width = len(field[0]) + 3
+			}
+		}
+	}
+
+	var lines []string
+	for i, block := range blocks {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+
+		for _, field := range block {
+			lines = append(lines, fmt.Sprintf("%-*s%s", width, field[0]+":", field[1]))
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (t WHOISTemplate) entityBlock(entity protocol.Entity) whoisBlock {
+	var block whoisBlock
+	block.add(t.HandleKey, entity.Handle)
+
+	if vcard, err := entity.VCard(); err == nil && vcard != nil {
+		if len(t.NameKeys) > 0 {
+			block.add(t.NameKeys[0], vcard.FN())
+		}
+
+		for _, email := range vcard.Emails() {
+			block.add(t.EmailKey, email.Address)
+		}
+
+		for _, tel := range vcard.Tels() {
+			block.add(t.PhoneKey, tel.Number)
+		}
+	}
+
+	t.renderEvents(&block, entity.Events)
+	return block
+}
+
+// renderNetworkKey returns the network key of the IP version
+func (t WHOISTemplate) renderNetworkKey(ipNetwork protocol.IPNetwork) string {
+	ipv6 := ipNetwork.IPVersion == "v6" || strings.Contains(ipNetwork.StartAddress, ":")
+	if ipv6 && t.NetworkIPv6Key != "" {
+		return t.NetworkIPv6Key
+	}
+
+	return t.NetworkKey
+}
+
+// renderContacts adds the references to the contacts of the object, with the
+// keys of the contact roles. When many keys have the same role, only the
+// first one is used. The contacts without handle are referenced by the name
+// when the template has no contact name key for the role
+func (t WHOISTemplate) renderContacts(block *whoisBlock, entities []protocol.Entity) {
+	namedRoles := make(map[protocol.Role]bool)
+	for _, role := range t.ContactNames {
+		namedRoles[role] = true
+	}
+
+	rendered := make(map[protocol.Role]bool)
+
+	for _, key := range sortedWHOISKeys(t.Contacts) {
+		if rendered[t.Contacts[key]] {
+			continue
+		}
+		rendered[t.Contacts[key]] = true
+
+		for _, entity := range entities {
+			if !entity.HasRole(t.Contacts[key]) {
+				continue
+			}
+
+			value := entity.Handle
+			if value == "" && !namedRoles[t.Contacts[key]] {
+				value = whoisContactName(entity)
+			}
+
+			block.add(key, value)
+		}
+	}
+
+	rendered = make(map[protocol.Role]bool)
+
+	for _, key := range sortedWHOISKeys(t.ContactNames) {
+		if rendered[t.ContactNames[key]] {
+			continue
+		}
+		rendered[t.ContactNames[key]] = true
+
+		for _, entity := range entities {
+			if entity.HasRole(t.ContactNames[key]) {
+				block.add(key, whoisContactName(entity))
+			}
+		}
+	}
+}
+
+// whoisContactName returns the full name of the contact, or an empty string
+// when the contact has no vCard
+func whoisContactName(entity protocol.Entity) string {
+	if vcard, err := entity.VCard(); err == nil && vcard != nil {
+		return vcard.FN()
+	}
+
+	return ""
+}
+
+// contactBlocks returns the blocks of the contacts that have more information
+// than the handle, that is already in the object block
+func (t WHOISTemplate) contactBlocks(entities []protocol.Entity) []whoisBlock {
+	if t.HandleKey == "" {
+		return nil
+	}
+
+	var blocks []whoisBlock
+	rendered := make(map[string]bool)
+
+	for _, entity := range entities {
+		if entity.Handle == "" || rendered[entity.Handle] {
+			continue
+		}
+		rendered[entity.Handle] = true
+
+		if block := t.entityBlock(entity); len(block) > 1 {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
+
+func (t WHOISTemplate) renderEvents(block *whoisBlock, events []protocol.Event) {
+	layout := time.RFC3339
+	if len(t.DateLayouts) > 0 {
+		layout = t.DateLayouts[0]
+	}
+
+	actions := []struct {
+		key    string
+		action protocol.EventAction
+	}{
+		{t.CreatedKey, protocol.EventActionRegistration},
+		{t.ChangedKey, protocol.EventActionLastChanged},
+		{t.ExpiresKey, protocol.EventActionExpiration},
+	}
+
+	for _, item := range actions {
+		for _, event := range events {
+			if event.Action == item.action && !event.Date.IsZero() {
+				block.add(item.key, event.Date.UTC().Format(layout))
+				break
+			}
+		}
+	}
+}
+
+// renderStatus adds the statuses, converted with the template map when
+// possible, or as EPP status codes
+func (t WHOISTemplate) renderStatus(block *whoisBlock, statuses protocol.StatusSet) {
+	whoisStatuses := make([]string, 0, len(t.Statuses))
+	for whoisStatus := range t.Statuses {
+		whoisStatuses = append(whoisStatuses, whoisStatus)
+	}
+	sort.Strings(whoisStatuses)
+
+Statuses:
+	for _, status := range statuses {
+		for _, whoisStatus := range whoisStatuses {
+			if t.Statuses[whoisStatus] == status {
+				block.add(t.StatusKey, whoisStatus)
+				continue Statuses
+			}
+		}
+
+		if code, found := status.EPP(); found {
+			block.add(t.StatusKey, code)
+		} else {
+			block.add(t.StatusKey, string(status))
+		}
+	}
+}
+
+// whoisNetwork returns the network in CIDR notation, or as a range of
+// addresses when the range isn't a CIDR block. The handle is used when it
+// already describes the network (e.g. handles parsed from WHOIS)
+func whoisNetwork(handle, startAddress, endAddress string) string {
+	start, end := net.ParseIP(startAddress), net.ParseIP(endAddress)
+	if start == nil || end == nil {
+		return strings.TrimSpace(startAddress + " - " + endAddress)
+	}
+
+	if handleStart, handleEnd, err := whoisNetworkRange(handle); err == nil &&
+		handleStart.Equal(start) && handleEnd.Equal(end) {
+		return handle
+	}
+
+	if network, ok := rangeCIDR(start, end); ok {
+		return network.String()
+	}
+
+	return start.String() + " - " + end.String()
+}
+
+// rangeCIDR returns the CIDR block of the range of addresses, or false when
+// the range isn't a CIDR block
+func rangeCIDR(start, end net.IP) (*net.IPNet, bool) {
+	bits := 128
+	if start.To4() != nil && end.To4() != nil {
+		start, end, bits = start.To4(), end.To4(), 32
+	}
+
+	for ones := 0; ones <= bits; ones++ {
+		network := &net.IPNet{IP: start.Mask(net.CIDRMask(ones, bits)), Mask: net.CIDRMask(ones, bits)}
+		if !network.IP.Equal(start) {
+			continue
+		}
+
+		last := make(net.IP, len(network.IP))
+		for i := range network.IP {
+			last[i] = network.IP[i] | ^network.Mask[i]
+		}
+
+		if last.Equal(end) {
+			return network, true
+		}
+	}
+
+	return nil, false
+}
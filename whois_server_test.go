@@ -0,0 +1,355 @@
+package rdap
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestWHOISTemplateRender(t *testing.T) {
+	data := []struct {
+		description string
+		template    WHOISTemplate
+		text        string
+	}{
+		{
+			description: "it should render a registro.br domain",
+			template:    WHOISRegistroBRTemplate,
+			text:        whoisRegistroBRDomain,
+		},
+		{
+			description: "it should render a RIPE network",
+			template:    WHOISRIPETemplate,
+			text:        whoisRIPENetwork,
+		},
+		{
+			description: "it should render a RIPE IPv6 network",
+			template:    WHOISRIPETemplate,
+			text:        whoisRIPEIPv6Network,
+		},
+		{
+			description: "it should render a LACNIC AS",
+			template:    WHOISLACNICTemplate,
+			text:        whoisLACNICAS,
+		},
+		{
+			description: "it should render an ICANN domain",
+			template:    WHOISICANNTemplate,
+			text:        whoisICANNDomain,
+		},
+	}
+
+	for i, item := range data {
+		expected, err := item.template.Parse(item.text)
+		if err != nil {
+			t.Fatalf("[%d] %s: unexpected error “%v”", i, item.description, err)
+		}
+
+		text, err := item.template.Render(expected)
+		if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%v”", i, item.description, err)
+			continue
+		}
+
+		// the rendered response must be parsed back to the same object
+		object, err := item.template.Parse(text)
+		if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%v”", i, item.description, err)
+
+		} else if !reflect.DeepEqual(expected, object) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(expected, object))
+		}
+	}
+
+	domain := &protocol.Domain{
+		ObjectClassName: protocol.ObjectClassDomain,
+		LDHName:         "example.br",
+		Nameservers: []protocol.Nameserver{
+			{ObjectClassName: protocol.ObjectClassNameserver, LDHName: "a.dns.br"},
+		},
+		Entities: []protocol.Entity{
+			{
+				ObjectClassName: protocol.ObjectClassEntity,
+				Handle:          "ABC12",
+				Roles:           []string{protocol.RoleRegistrant, protocol.RoleTechnical},
+			},
+		},
+		Events: []protocol.Event{
+			{
+				Action: protocol.EventActionRegistration,
+				Date:   protocol.NewEventDate(time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC)),
+			},
+		},
+		Status: protocol.StatusSet{protocol.StatusActive},
+	}
+
+	expected := strings.Join([]string{
+		"domain:   example.br",
+		"owner-c:  ABC12",
+		"tech-c:   ABC12",
+		"nserver:  a.dns.br",
+		"created:  20170720",
+		"status:   published",
+	}, "\n")
+
+	text, err := WHOISRegistroBRTemplate.Render(domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if text != expected {
+		t.Errorf("Unexpected WHOIS text.\n%v", diff(expected, text))
+	}
+
+	ipv6Network := &protocol.IPNetwork{StartAddress: "2001:db8::", EndAddress: "2001:db8::ffff", IPVersion: "v6"}
+	if text, err := WHOISRIPETemplate.Render(ipv6Network); err != nil || text != "inet6num:  2001:db8::/112" {
+		t.Errorf("Unexpected IPv6 network WHOIS text “%s” (%v)", text, err)
+	}
+
+	if _, err := WHOISRegistroBRTemplate.Render(&protocol.Help{}); err != ErrUnknownWHOISObject {
+		t.Errorf("Unexpected error. Expected “%v” and got “%v”", ErrUnknownWHOISObject, err)
+	}
+}
+
+func TestWHOISServer(t *testing.T) {
+	server := NewServer()
+	server.Handle(QueryTypeDomain, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		switch queryValue {
+		case "example.br":
+			return &protocol.Domain{ObjectClassName: protocol.ObjectClassDomain, LDHName: queryValue}, nil
+		case "forbidden.br":
+			return nil, ErrForbidden
+		case "limited.br":
+			return nil, protocol.Error{ErrorCode: http.StatusTooManyRequests, Title: "too many requests"}
+		}
+		return nil, ErrNotFound
+	}))
+
+	server.Handle(QueryTypeAutnum, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		return &protocol.AS{ObjectClassName: protocol.ObjectClassAutnum, StartAutnum: 22548, EndAutnum: 22548}, nil
+	}))
+
+	server.Handle(QueryTypeIP, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		return &protocol.IPNetwork{
+			ObjectClassName: protocol.ObjectClassIPNetwork,
+			StartAddress:    "200.160.0.0",
+			EndAddress:      "200.160.15.255",
+			IPVersion:       "v4",
+		}, nil
+	}))
+
+	server.Handle(QueryTypeEntity, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		if host, _, _ := net.SplitHostPort(r.RemoteAddr); host != "127.0.0.1" {
+			return nil, ErrForbidden
+		}
+
+		return &protocol.Entity{ObjectClassName: protocol.ObjectClassEntity, Handle: queryValue}, nil
+	}))
+
+	whoisServer := NewWHOISServer(server)
+	whoisServer.Header = []string{"Copyright (c) Example"}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go whoisServer.Serve(listener)
+
+	client := &WHOISClient{Server: listener.Addr().String(), Timeout: time.Second}
+
+	data := []struct {
+		description string
+		query       string
+		expected    string
+	}{
+		{
+			description: "it should answer a domain query",
+			query:       "EXAMPLE.br",
+			expected:    "domain: example.br",
+		},
+		{
+			description: "it should answer an AS query",
+			query:       "AS22548",
+			expected:    "aut-num: AS22548",
+		},
+		{
+			description: "it should answer an IP query",
+			query:       "200.160.2.3",
+			expected:    "inetnum: 200.160.0.0/20",
+		},
+		{
+			description: "it should answer an entity query with the client address",
+			query:       "FAN",
+			expected:    "nic-hdl-br: FAN",
+		},
+		{
+			description: "it should detect an object that doesn't exist",
+			query:       "missing.br",
+			expected:    `% No match for "missing.br"`,
+		},
+		{
+			description: "it should detect a forbidden object",
+			query:       "forbidden.br",
+			expected:    "% Access denied",
+		},
+		{
+			description: "it should answer with the backend protocol error",
+			query:       "limited.br",
+			expected:    "% Error: too many requests",
+		},
+	}
+
+	for i, item := range data {
+		response, err := client.Query(item.query)
+		if err != nil {
+			t.Errorf("[%d] %s: unexpected error “%v”", i, item.description, err)
+			continue
+		}
+
+		text := strings.Replace(response.Text, "\r\n", "\n", -1)
+		if !strings.HasPrefix(text, "% Copyright (c) Example\n\n") {
+			t.Errorf("[%d] %s: missing header in the response “%s”", i, item.description, text)
+		}
+
+		if lines := strings.Split(text, "\n"); strings.Join(strings.Fields(lines[2]), " ") != item.expected {
+			t.Errorf("[%d] %s: expected “%s” and got “%s”", i, item.description, item.expected, lines[2])
+		}
+	}
+}
+
+func TestWHOISServerMaxConnections(t *testing.T) {
+	server := NewServer()
+	server.Handle(QueryTypeDomain, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		return &protocol.Domain{ObjectClassName: protocol.ObjectClassDomain, LDHName: queryValue}, nil
+	}))
+
+	whoisServer := NewWHOISServer(server)
+	whoisServer.MaxConnections = 1
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer whoisServer.Close()
+
+	go whoisServer.Serve(listener)
+
+	first, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+
+	second, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	// the second connection waits in the listener backlog while the first
+	// one is active
+	io.WriteString(second, "example.br\r\n")
+	second.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, err := second.Read(make([]byte, 1)); n > 0 || err == nil {
+		t.Fatalf("unexpected response while the connection limit is reached")
+	}
+
+	io.WriteString(first, "example.br\r\n")
+	if _, err := ioutil.ReadAll(first); err != nil {
+		t.Fatalf("unexpected error “%v”", err)
+	}
+
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	response, err := ioutil.ReadAll(second)
+	if err != nil {
+		t.Fatalf("unexpected error “%v”", err)
+	}
+
+	if !strings.Contains(string(response), "example.br") {
+		t.Errorf("unexpected response “%s”", response)
+	}
+}
+
+func TestWHOISServerShutdown(t *testing.T) {
+	server := NewServer()
+	server.Handle(QueryTypeDomain, ObjectHandlerFunc(func(r *http.Request, queryType QueryType, queryValue string) (interface{}, error) {
+		return &protocol.Domain{ObjectClassName: protocol.ObjectClassDomain, LDHName: queryValue}, nil
+	}))
+
+	whoisServer := NewWHOISServer(server)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- whoisServer.Serve(listener)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// waits until the connection is accepted
+	for i := 0; ; i++ {
+		whoisServer.mu.Lock()
+		accepted := len(whoisServer.conns) > 0
+		whoisServer.mu.Unlock()
+
+		if accepted {
+			break
+		} else if i == 100 {
+			t.Fatal("connection not accepted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- whoisServer.Shutdown(ctx)
+	}()
+
+	if err := <-serveErr; err != ErrWHOISServerClosed {
+		t.Fatalf("expected error “%v” and got “%v”", ErrWHOISServerClosed, err)
+	}
+
+	// the active connection is still answered
+	io.WriteString(conn, "example.br\r\n")
+	response, err := ioutil.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("unexpected error “%v”", err)
+	}
+
+	if !strings.Contains(string(response), "example.br") {
+		t.Errorf("unexpected response “%s”", response)
+	}
+
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("unexpected error “%v”", err)
+	}
+
+	if _, err := net.Dial("tcp", listener.Addr().String()); err == nil {
+		t.Errorf("expected the listener to be closed")
+	}
+
+	if err := whoisServer.Serve(listener); err != ErrWHOISServerClosed {
+		t.Errorf("expected error “%v” and got “%v”", ErrWHOISServerClosed, err)
+	}
+}
@@ -0,0 +1,232 @@
+package rdap
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// whoisServer is a local WHOIS stand-in that answers the queries with the
+// given responses. It returns the server address
+func whoisServer(t *testing.T, responses map[string]string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			query, _ := bufio.NewReader(conn).ReadString('\n')
+			if response, ok := responses[strings.TrimSpace(query)]; ok {
+				conn.Write([]byte(response))
+			} else {
+				conn.Write([]byte("% no match\n"))
+			}
+			conn.Close()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestParseWHOISFields(t *testing.T) {
+	text := `% Copyright (c) Nic.br
+% 2017-07-20 12:09:48 (BRT -03:00)
+
+domain:      example.com.br
+owner:       Example Inc.
+nserver:     a.dns.br
+nserver:     b.dns.br
+remarks:     see http://registro.br
+http://example.com
+country:
+>>> Last update of WHOIS database: 2017-07-20 <<<
+`
+
+	expected := WHOISFields{
+		"domain":  []string{"example.com.br"},
+		"owner":   []string{"Example Inc."},
+		"nserver": []string{"a.dns.br", "b.dns.br"},
+		"remarks": []string{"see http://registro.br"},
+	}
+
+	fields := ParseWHOISFields(text)
+	if !reflect.DeepEqual(expected, fields) {
+		t.Errorf("Unexpected fields.\n%v", diff(expected, fields))
+	}
+
+	if owner := fields.Get("Owner"); owner != "Example Inc." {
+		t.Errorf("Unexpected owner. Expected “Example Inc.” and got “%s”", owner)
+	}
+}
+
+func TestWHOISClientQuery(t *testing.T) {
+	registrar := whoisServer(t, map[string]string{
+		"example.com": "Domain Name: EXAMPLE.COM\nRegistrant Name: Joe User\n",
+	})
+
+	registry := whoisServer(t, map[string]string{
+		"example.com": "Domain Name: EXAMPLE.COM\nRegistrar WHOIS Server: " + registrar + "\n",
+		"example.net": "Domain Name: EXAMPLE.NET\nRegistrar WHOIS Server: 127.0.0.1:1\n",
+	})
+
+	iana := whoisServer(t, map[string]string{
+		"example.com": "refer: " + registry + "\n",
+		"example.net": "refer: " + registry + "\n",
+		"example.org": "whois: whois://" + registry + "/\n",
+	})
+
+	data := []struct {
+		description     string
+		query           string
+		maxReferrals    int
+		expectedServers []string
+		expectedError   bool
+	}{
+		{
+			description:     "it should follow the referrals",
+			query:           "example.com",
+			maxReferrals:    DefaultWHOISReferrals,
+			expectedServers: []string{registrar, registry, iana},
+		},
+		{
+			description:     "it should stop at the maximum number of referrals",
+			query:           "example.com",
+			maxReferrals:    1,
+			expectedServers: []string{registry, iana},
+		},
+		{
+			description:     "it should follow a referral with the whois scheme",
+			query:           "example.org",
+			maxReferrals:    DefaultWHOISReferrals,
+			expectedServers: []string{registry, iana},
+		},
+		{
+			description:     "it should return the last response when a referral fails",
+			query:           "example.net",
+			maxReferrals:    DefaultWHOISReferrals,
+			expectedServers: []string{registry, iana},
+			expectedError:   true,
+		},
+	}
+
+	for i, item := range data {
+		client := WHOISClient{
+			Server:       iana,
+			MaxReferrals: item.maxReferrals,
+		}
+
+		response, err := client.Query(item.query)
+
+		if item.expectedError != (err != nil) {
+			t.Errorf("[%d] %s: unexpected error “%v”", i, item.description, err)
+		}
+
+		var servers []string
+		for r := response; r != nil; r = r.Referrer {
+			servers = append(servers, r.Server)
+		}
+
+		if !reflect.DeepEqual(item.expectedServers, servers) {
+			t.Errorf("[%d] %s: mismatch servers.\n%v", i, item.description, diff(item.expectedServers, servers))
+		}
+	}
+}
+
+func TestClientQueryWithFallback(t *testing.T) {
+	server := whoisServer(t, map[string]string{
+		"example.com": "Domain Name: EXAMPLE.COM\n",
+		"AS65536":     "aut-num: AS65536\n",
+	})
+
+	data := []struct {
+		description    string
+		object         string
+		fetchError     error
+		expectedField  string
+		expectedObject interface{}
+		expectedError  error
+	}{
+		{
+			description:   "it should fall back to WHOIS when RDAP fails",
+			object:        "example.com",
+			fetchError:    fmt.Errorf("no matches for example.com"),
+			expectedField: "EXAMPLE.COM",
+			expectedObject: &protocol.Domain{
+				ObjectClassName: "domain",
+				LDHName:         "example.com",
+				Port43:          protocol.Port43{Port43: "127.0.0.1"},
+			},
+		},
+		{
+			description:   "it should query the AS number with the WHOIS format and report the parse error",
+			object:        "65536",
+			fetchError:    fmt.Errorf("no matches for 65536"),
+			expectedField: "AS65536",
+			expectedError: fmt.Errorf("unknown WHOIS object"),
+		},
+		{
+			description:   "it should not fall back when the object was not found",
+			object:        "example.com",
+			fetchError:    ErrNotFound,
+			expectedError: ErrNotFound,
+		},
+	}
+
+	for i, item := range data {
+		client := Client{
+			Transport: fetcherFunc(func(uris []string, queryType QueryType, queryValue string, header http.Header, queryString url.Values) (*http.Response, error) {
+				return nil, item.fetchError
+			}),
+			WHOIS: &WHOISClient{Server: server},
+		}
+
+		object, _, response, err := client.QueryWithFallback(item.object, nil, nil)
+
+		if fmt.Sprintf("%v", item.expectedError) != fmt.Sprintf("%v", err) {
+			t.Errorf("[%d] %s: expected error “%s”, got “%s”", i, item.description, item.expectedError, err)
+			continue
+		}
+
+		if item.expectedField == "" {
+			if object != nil {
+				t.Errorf("[%d] %s: unexpected RDAP object “%#v”", i, item.description, object)
+			}
+
+			if response != nil {
+				t.Errorf("[%d] %s: unexpected WHOIS response “%#v”", i, item.description, response)
+			}
+			continue
+		}
+
+		if response == nil {
+			t.Errorf("[%d] %s: expected a WHOIS response", i, item.description)
+			continue
+		}
+
+		var field string
+		for _, values := range response.Fields {
+			field = values[0]
+		}
+
+		if field != item.expectedField {
+			t.Errorf("[%d] %s: expected field “%s” and got “%s”", i, item.description, item.expectedField, field)
+		}
+
+		if !reflect.DeepEqual(item.expectedObject, object) {
+			t.Errorf("[%d] “%s”: mismatch results.\n%v", i, item.description, diff(item.expectedObject, object))
+		}
+	}
+}